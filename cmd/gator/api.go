@@ -0,0 +1,567 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+/*
+======================================================
+
+		JSON API
+
+	served by gator serve under /api, records share
+	their field names with --output json
+
+======================================================
+*/
+
+const (
+	defaultAPILimit = 20
+	maxAPILimit     = 100
+
+	// request bodies are a handful of short fields
+	maxAPIBody = 1 << 20
+)
+
+type listResponse[T any] struct {
+	Items []T `json:"items"`
+}
+
+type feedPage struct {
+	Items []feedRecord `json:"items"`
+	// offset of the next page, null on the last one
+	NextOffset *int `json:"next_offset"`
+}
+
+type postPage struct {
+	Items []postRecord `json:"items"`
+	// cursor of the next page, null on the last one
+	NextCursor *string `json:"next_cursor"`
+}
+
+type postDetail struct {
+	postRecord
+	Content string `json:"content"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func registerAPI(s *state, mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/users", apiListUsers(s))
+	mux.HandleFunc("POST /api/users", apiCreateUser(s))
+	mux.HandleFunc("GET /api/users/{name}", apiUser(s, apiGetUser))
+	mux.HandleFunc("GET /api/feeds", apiListFeeds(s))
+	mux.HandleFunc("POST /api/users/{name}/feeds", apiUser(s, apiAddFeed))
+	mux.HandleFunc("GET /api/users/{name}/follows", apiUser(s, apiListFollows))
+	mux.HandleFunc("POST /api/users/{name}/follows", apiUser(s, apiFollow))
+	mux.HandleFunc("PATCH /api/users/{name}/follows", apiUser(s, apiSetFollowTitle))
+	mux.HandleFunc("DELETE /api/users/{name}/follows", apiUser(s, apiUnfollow))
+	mux.HandleFunc("GET /api/users/{name}/posts", apiUser(s, apiBrowsePosts))
+	mux.HandleFunc("GET /api/users/{name}/posts/{id}", apiUser(s, apiGetPost))
+	mux.HandleFunc("PUT /api/users/{name}/posts/{id}/read", apiUser(s, apiMarkRead))
+	mux.HandleFunc("DELETE /api/users/{name}/posts/{id}/read", apiUser(s, apiMarkUnread))
+	mux.HandleFunc("PUT /api/users/{name}/posts/{id}/star", apiUser(s, apiStar))
+	mux.HandleFunc("DELETE /api/users/{name}/posts/{id}/star", apiUser(s, apiUnstar))
+}
+
+/*
+======================================================
+
+		Users
+
+======================================================
+*/
+
+func apiListUsers(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, err := s.db.GetUsers(r.Context())
+		if err != nil {
+			writeAPIError(w, fmt.Errorf("error retrieving users: %v", err))
+			return
+		}
+
+		records := make([]userRecord, 0, len(users))
+		for _, user := range users {
+			records = append(records, newUserRecord(s, user))
+		}
+		writeJSON(w, http.StatusOK, listResponse[userRecord]{Items: records})
+	}
+}
+
+func apiCreateUser(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if !decodeBody(w, r, &body) {
+			return
+		}
+
+		user, err := registerUser(r.Context(), s.db, body.Name)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newUserRecord(s, user))
+	}
+}
+
+func apiGetUser(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, newUserRecord(s, user))
+}
+
+func newUserRecord(s *state, user database.User) userRecord {
+	return userRecord{
+		Name:      user.Name,
+		Current:   user.Name == s.cfg.User_name,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+/*
+======================================================
+
+		Feeds and follows
+
+======================================================
+*/
+
+func apiListFeeds(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := pageParams(r)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		feeds, err := s.db.ListFeeds(r.Context(), database.ListFeedsParams{
+			Limit:  int32(limit),
+			Offset: int32(offset),
+		})
+		if err != nil {
+			writeAPIError(w, fmt.Errorf("error retrieving feeds: %v", err))
+			return
+		}
+
+		page := feedPage{Items: make([]feedRecord, 0, len(feeds))}
+		for _, feed := range feeds {
+			page.Items = append(page.Items, feedRecord{
+				Name:      feed.Name,
+				URL:       feed.Url,
+				CreatedBy: feed.UserName,
+			})
+		}
+		if len(feeds) == limit {
+			next := offset + limit
+			page.NextOffset = &next
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+func apiAddFeed(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	var feed database.Feed
+	err := withTx(r.Context(), s, func(db database.Store) error {
+		var err error
+		feed, err = addFeed(r.Context(), db, user, body.Name, body.URL)
+		return err
+	})
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, feedRecord{
+		Name:      feed.Name,
+		URL:       feed.Url,
+		CreatedBy: user.Name,
+	})
+}
+
+func apiListFollows(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error retrieving feed follows: %v", err))
+		return
+	}
+
+	records := make([]followRecord, 0, len(follows))
+	for _, follow := range follows {
+		records = append(records, followRecord{
+			Name:    follow.FeedName,
+			URL:     follow.FeedUrl,
+			SiteURL: follow.SiteUrl.String,
+			Folder:  follow.Folder.String,
+		})
+	}
+	writeJSON(w, http.StatusOK, listResponse[followRecord]{Items: records})
+}
+
+func apiFollow(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		URL string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.URL == "" {
+		writeAPIError(w, opErrorf(failInvalid, "url is required"))
+		return
+	}
+
+	follow, err := followFeed(r.Context(), s.db, user, body.URL)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, followRecord{
+		Name:   follow.FeedName,
+		URL:    body.URL,
+		Folder: follow.Folder.String,
+	})
+}
+
+// apiSetFollowTitle sets the name a followed feed is shown under, a null or empty title
+// goes back to the feed's own name
+func apiSetFollowTitle(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		URL   string  `json:"url"`
+		Title *string `json:"title"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.URL == "" {
+		writeAPIError(w, opErrorf(failInvalid, "url is required"))
+		return
+	}
+
+	var title sql.NullString
+	if body.Title != nil && *body.Title != "" {
+		title.Scan(*body.Title)
+	}
+
+	if err := setFollowTitle(r.Context(), s.db, user, body.URL, title); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiUnfollow(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	feedURL := r.URL.Query().Get("url")
+	if feedURL == "" {
+		writeAPIError(w, opErrorf(failInvalid, "url query parameter is required"))
+		return
+	}
+
+	if err := unfollowFeed(r.Context(), s.db, user, feedURL); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+======================================================
+
+		Posts
+
+======================================================
+*/
+
+// apiBrowsePosts takes the same filters as gator browse, as query parameters
+func apiBrowsePosts(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	opts := browseOptions{
+		limit:  limit,
+		offset: offset,
+		cursor: query.Get("cursor"),
+		feed:   query.Get("feed"),
+		since:  query.Get("since"),
+		until:  query.Get("until"),
+		sortBy: query.Get("sort"),
+	}
+	if opts.ascending, err = boolParam(query.Get("asc")); err != nil {
+		writeAPIError(w, opErrorf(failInvalid, "asc: %v", err))
+		return
+	}
+	if opts.unread, err = boolParam(query.Get("unread")); err != nil {
+		writeAPIError(w, opErrorf(failInvalid, "unread: %v", err))
+		return
+	}
+
+	posts, next, err := browsePosts(r.Context(), s.db, user, opts)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	page := postPage{Items: make([]postRecord, 0, len(posts))}
+	for _, post := range posts {
+		page.Items = append(page.Items, newPostRecord(post))
+	}
+	if next != "" {
+		page.NextCursor = &next
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func apiGetPost(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, ok := postIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := getPost(r.Context(), s.db, user, postID)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	detail := postDetail{
+		postRecord: postRecord{
+			ID:          post.ID.String(),
+			Title:       post.Title.String,
+			URL:         post.Url,
+			Feed:        post.FeedName,
+			FeedURL:     post.FeedUrl,
+			Author:      post.Author.String,
+			FetchedAt:   post.CreatedAt,
+			Read:        post.ReadAt.Valid,
+			Description: post.Description.String,
+		},
+		Content: post.Content.String,
+	}
+	if post.PublishedAt.Valid {
+		detail.PublishedAt = &post.PublishedAt.Time
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func apiMarkRead(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, ok := postIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := markPostRead(r.Context(), s.db, user, postID); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiMarkUnread(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, ok := postIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// unread posts are left as they are, clients retry these freely
+	if _, err := markPostUnread(r.Context(), s.db, user, postID); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiStar(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, ok := postIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := starPost(r.Context(), s.db, user, postID); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiUnstar(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, ok := postIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := unstarPost(r.Context(), s.db, user, postID); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+======================================================
+
+		Helpers
+
+======================================================
+*/
+
+// apiUser resolves the {name} in the path, the api equivalent of middlewareLoggedIn.
+// Users with a password have to send the token of one of their logins as well
+func apiUser(s *state, handler func(*state, http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getUser(r.Context(), s.db, r.PathValue("name"))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		if err := apiSession(r, s.db, user); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		handler(s, w, r, user)
+	}
+}
+
+// apiSession checks the "Authorization: Bearer" header against user's logins, the
+// token is the one gator login saved in the config file
+func apiSession(r *http.Request, db database.UserRepository, user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return opErrorf(failUnauthorized, "%v has a password, send a token from gator login as a bearer token", user.Name)
+	}
+
+	ok, err := validSession(r.Context(), db, user, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return opErrorf(failUnauthorized, "the token isn't a current login of %v", user.Name)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Printf("\twarning: couldn't write response: %v\n", err)
+	}
+}
+
+// writeAPIError picks the status from the kind of failure, database errors are logged
+// rather than sent to the client
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch failureOf(err) {
+	case failInvalid:
+		status = http.StatusBadRequest
+	case failNotFound:
+		status = http.StatusNotFound
+	case failConflict:
+		status = http.StatusConflict
+	case failUnauthorized:
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+	default:
+		fmt.Printf("\twarning: %v\n", err)
+		err = errors.New("internal server error")
+	}
+
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// decodeBody reads a json request body into dst, answering the request itself and
+// returning false when the body isn't acceptable
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: "request body is too large"})
+		case errors.Is(err, io.EOF):
+			writeAPIError(w, opErrorf(failInvalid, "request body is empty"))
+		default:
+			writeAPIError(w, opErrorf(failInvalid, "malformed request body: %v", err))
+		}
+		return false
+	}
+
+	if decoder.More() {
+		writeAPIError(w, opErrorf(failInvalid, "request body must hold a single json object"))
+		return false
+	}
+	return true
+}
+
+func pageParams(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+
+	limit := defaultAPILimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxAPILimit {
+			return 0, 0, opErrorf(failInvalid, "limit must be between 1 and %v", maxAPILimit)
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, opErrorf(failInvalid, "offset must be a positive number")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+func boolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func postIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	postID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, opErrorf(failInvalid, "invalid post id: %v", r.PathValue("id")))
+		return uuid.UUID{}, false
+	}
+	return postID, true
+}
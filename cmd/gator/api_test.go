@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIRequiresSessionForPasswordUsers(t *testing.T) {
+	s := newTestState(t)
+
+	withInput(t, "secret", "secret")
+	mustRun(t, s, "register", "--password", "carol")
+	carolToken := s.cfg.Session.Token
+	mustRun(t, s, "register", "dave")
+
+	server := httptest.NewServer(newServeMux(s))
+	t.Cleanup(server.Close)
+
+	get := func(path, token string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("building request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %v: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := get("/api/users/dave/follows", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("users without a password need no token, got %v", resp.StatusCode)
+	}
+	if resp := get("/api/users/carol/follows", ""); resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected carol's follows to need a token, got %v", resp.StatusCode)
+	}
+	if resp := get("/api/users/carol", "not-a-token"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown token to be refused, got %v", resp.StatusCode)
+	}
+	if resp := get("/api/users/carol/posts", carolToken); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected carol's token to work, got %v", resp.StatusCode)
+	}
+
+	// a token of someone else's doesn't work for carol
+	withInput(t, "other", "other")
+	mustRun(t, s, "register", "--password", "erin")
+	if resp := get("/api/users/carol/follows", s.cfg.Session.Token); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected erin's token to be refused for carol, got %v", resp.StatusCode)
+	}
+
+	carol := mustGetUser(t, s, "carol")
+	if _, err := s.db.DeleteSessionsForUser(context.Background(), carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if resp := get("/api/users/carol/follows", carolToken); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a token logged out everywhere to be refused, got %v", resp.StatusCode)
+	}
+
+	// expired logins don't count either
+	session, err := newSession(context.Background(), s.db, carol, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if resp := get("/api/users/carol/follows", session.Token); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an expired token to be refused, got %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/config"
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// how long logging in as a user with a password lasts unless login is given --for
+const defaultSessionLifetime = 30 * 24 * time.Hour
+
+// readPassword prompts for a password without echoing it, when stdin isn't a terminal
+// the password is read as a line so scripts can pipe it in
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("couldn't read password: %v", err)
+		}
+		return string(password), nil
+	}
+
+	password, err := stdin.ReadString('\n')
+	if err != nil && password == "" {
+		return "", fmt.Errorf("couldn't read password: %v", err)
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+// readNewPassword asks for a password twice and hashes it
+func readNewPassword() (sql.NullString, error) {
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if password == "" {
+		return sql.NullString{}, fmt.Errorf("password can't be empty")
+	}
+
+	repeated, err := readPassword("Repeat password: ")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if repeated != password {
+		return sql.NullString{}, fmt.Errorf("passwords don't match")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("couldn't hash password: %v", err)
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// checkPassword prompts for a user's password, it's an error if it doesn't match
+func checkPassword(user database.User) error {
+	password, err := readPassword(fmt.Sprintf("Password for %v: ", user.Name))
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return fmt.Errorf("wrong password for %v", user.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't check password: %v", err)
+	}
+	return nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSession records a login for user, the token returned goes in the config
+func newSession(ctx context.Context, db database.UserRepository, user database.User, lifetime time.Duration) (*config.Session, error) {
+	token, err := newFeedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// nobody is going to use these again, this is as good a time as any to clear them out
+	if _, err := db.DeleteExpiredSessions(ctx, time.Now()); err != nil {
+		return nil, fmt.Errorf("error removing expired sessions: %v", err)
+	}
+
+	session := &config.Session{
+		Token:     token,
+		ExpiresAt: time.Now().Add(lifetime).UTC(),
+	}
+	err = db.CreateSession(ctx, database.CreateSessionParams{
+		TokenHash: hashSessionToken(token),
+		CreatedAt: time.Now(),
+		ExpiresAt: session.ExpiresAt,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %v", err)
+	}
+	return session, nil
+}
+
+// hasSession reports whether the config holds a login for user that hasn't expired
+func hasSession(s *state, user database.User) (bool, error) {
+	if s.cfg.Session == nil {
+		return false, nil
+	}
+	return validSession(context.Background(), s.db, user, s.cfg.Session.Token)
+}
+
+// validSession reports whether token is one of user's logins and hasn't expired
+func validSession(ctx context.Context, db database.UserRepository, user database.User, token string) (bool, error) {
+	session, err := db.GetSession(ctx, database.GetSessionParams{
+		TokenHash: hashSessionToken(token),
+		Now:       time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error retrieving session: %v", err)
+	}
+	return session.UserID == user.ID, nil
+}
+
+// checkSession is used before acting as the current user, users without a password
+// don't need a session at all
+func checkSession(s *state, user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+
+	ok, err := hasSession(s, user)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%v's login has expired, log in again with gator login %v", user.Name, user.Name)
+	}
+	return nil
+}
+
+// authorize is used before changing a user other commands could act on, it asks for
+// the user's password unless you're logged in as them
+func authorize(s *state, user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+
+	ok, err := hasSession(s, user)
+	if err != nil || ok {
+		return err
+	}
+	return checkPassword(user)
+}
+
+// authorizeEveryone is authorize for every user with a password, it's used before
+// changes that reach into everyone's feeds and posts
+func authorizeEveryone(s *state) error {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return fmt.Errorf("error retrieving users: %v", err)
+	}
+
+	for _, user := range users {
+		if err := authorize(s, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handlerLogin(s *state, cmd command) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	lifetime := flags.Duration("for", defaultSessionLifetime, "how long the login lasts for users with a password")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("login command requires a username")
+	}
+	if *lifetime <= 0 {
+		return fmt.Errorf("--for must be a positive duration")
+	}
+
+	user, err := s.db.GetUser(context.Background(), flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if !user.PasswordHash.Valid {
+		if err := s.cfg.SetSession(user.Name, nil); err != nil {
+			return fmt.Errorf("received an error when trying to update username: %v", err)
+		}
+		fmt.Println("User has been successfuly set")
+		return nil
+	}
+
+	if err := checkPassword(user); err != nil {
+		return err
+	}
+
+	session, err := newSession(context.Background(), s.db, user, *lifetime)
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.SetSession(user.Name, session); err != nil {
+		return fmt.Errorf("received an error when trying to update username: %v", err)
+	}
+
+	fmt.Printf("Logged in as %v until %v\n", user.Name, formatTime(&session.ExpiresAt))
+	return nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("command takes no arguments")
+	}
+
+	if s.cfg.Session != nil {
+		err := s.db.DeleteSession(context.Background(), hashSessionToken(s.cfg.Session.Token))
+		if err != nil {
+			return fmt.Errorf("error removing session: %v", err)
+		}
+	}
+
+	if err := s.cfg.SetSession("", nil); err != nil {
+		return fmt.Errorf("received an error when trying to update username: %v", err)
+	}
+
+	fmt.Println("Logged out")
+	return nil
+}
+
+// handlerPassword sets or removes the current user's password, either way they're
+// logged out everywhere else
+func handlerPassword(s *state, cmd command, user database.User) error {
+	flags := flag.NewFlagSet("password", flag.ContinueOnError)
+	remove := flags.Bool("remove", false, "remove the password, anyone can log in as you again")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 0 {
+		return fmt.Errorf("command takes no arguments")
+	}
+	if *remove && !user.PasswordHash.Valid {
+		return fmt.Errorf("%v has no password", user.Name)
+	}
+
+	if user.PasswordHash.Valid {
+		if err := checkPassword(user); err != nil {
+			return err
+		}
+	}
+
+	var hash sql.NullString
+	if !*remove {
+		var err error
+		hash, err = readNewPassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	var session *config.Session
+	err := withTx(context.Background(), s, func(db database.Store) error {
+		err := db.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hash,
+			UpdatedAt:    sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("error updating password: %v", err)
+		}
+
+		if _, err := db.DeleteSessionsForUser(context.Background(), user.ID); err != nil {
+			return fmt.Errorf("error removing sessions: %v", err)
+		}
+
+		if hash.Valid {
+			session, err = newSession(context.Background(), db, user, defaultSessionLifetime)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.cfg.SetSession(user.Name, session); err != nil {
+		return fmt.Errorf("received an error when trying to update username: %v", err)
+	}
+
+	if *remove {
+		fmt.Printf("Password removed, anyone can log in as %v\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Password set, you're logged in as %v until %v\n", user.Name, formatTime(&session.ExpiresAt))
+	return nil
+}
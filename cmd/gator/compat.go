@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+/*
+======================================================
+
+		Reader app compatibility
+
+	shared by the fever and google reader apis, both
+	log in with the user's name and their api password
+
+======================================================
+*/
+
+func handlerAPIPassword(s *state, cmd command, user database.User) error {
+	flags := flag.NewFlagSet("apipassword", flag.ContinueOnError)
+	rotate := flags.Bool("rotate", false, "replace the current password, apps using the old one are logged out")
+	revoke := flags.Bool("revoke", false, "remove the password, disabling the fever and google reader apis for you")
+	host := flags.String("host", "http://localhost:8080", "address gator serve is reachable at, used to print the api urls")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+
+	params := database.SetUserApiPasswordParams{
+		ID:          user.ID,
+		ApiPassword: user.ApiPassword,
+	}
+	params.UpdatedAt.Scan(time.Now())
+
+	switch {
+	case *revoke:
+		params.ApiPassword.Valid = false
+	case *rotate || !user.ApiPassword.Valid:
+		password, err := newFeedToken()
+		if err != nil {
+			return err
+		}
+		params.ApiPassword.Scan(password)
+	}
+
+	if params.ApiPassword != user.ApiPassword {
+		err := s.db.SetUserApiPassword(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("error updating api password: %v", err)
+		}
+	}
+
+	if !params.ApiPassword.Valid {
+		fmt.Println("API password revoked, reader apps can no longer log in as you")
+		return nil
+	}
+
+	fmt.Println("Reader apps can log in through gator serve with:")
+	fmt.Printf("\tusername: %v\n", user.Name)
+	fmt.Printf("\tpassword: %v\n", params.ApiPassword.String)
+	fmt.Printf("\tfever:          %v/fever/\n", *host)
+	fmt.Printf("\tgoogle reader:  %v/greader\n", *host)
+	return nil
+}
+
+// feverAPIKey is what fever clients send instead of the password itself, md5 is
+// mandated by the fever api
+func feverAPIKey(user database.User) string {
+	sum := md5.Sum([]byte(user.Name + ":" + user.ApiPassword.String))
+	return hex.EncodeToString(sum[:])
+}
+
+// greaderAuthToken is handed out by ClientLogin, it's derived from the password so
+// rotating the password logs every client out
+func greaderAuthToken(user database.User) string {
+	sum := sha256.Sum256([]byte("greader:" + user.Name + ":" + user.ApiPassword.String))
+	return user.Name + "/" + hex.EncodeToString(sum[:])
+}
+
+func validAPIPassword(user database.User, password string) bool {
+	if !user.ApiPassword.Valid || password == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(user.ApiPassword.String), []byte(password)) == 1
+}
+
+// both apis identify feeds and folders by number, gator doesn't so they're derived
+// from the feed's url and the folder's name, which keeps them stable across requests
+func compatNumber(key string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int64(h.Sum32() & 0x7fffffff)
+}
+
+func feedNumber(feedURL string) int64 {
+	return compatNumber("feed:" + feedURL)
+}
+
+func folderNumber(folder string) int64 {
+	return compatNumber("folder:" + folder)
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// parseSeqs reads a list of comma separated post numbers
+func parseSeqs(values ...string) ([]int64, error) {
+	seqs := make([]int64, 0, len(values))
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			seq, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item id %v", part)
+			}
+			seqs = append(seqs, seq)
+		}
+	}
+	return seqs, nil
+}
+
+func joinSeqs(seqs []int64) string {
+	parts := make([]string, 0, len(seqs))
+	for _, seq := range seqs {
+		parts = append(parts, strconv.FormatInt(seq, 10))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+const (
+	compatFeedURL  = "https://example.com/feed.xml"
+	compatOtherURL = "https://other.example.com/feed.xml"
+)
+
+// compatServer is gator serve for alice, who follows one feed with three posts in
+// her news folder and has an api password for reader apps
+type compatServer struct {
+	s      *state
+	server *httptest.Server
+	alice  database.User
+	posts  []database.Post
+	header http.Header
+}
+
+func newCompatServer(t *testing.T) *compatServer {
+	t.Helper()
+	s := newTestState(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", compatFeedURL)
+	mustRun(t, s, "apipassword")
+	posts := addPosts(t, s, mustGetFeed(t, s, compatFeedURL), 3)
+
+	alice := mustGetUser(t, s, "alice")
+	_, err := s.db.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+		Folder:    nullString("news"),
+		UpdatedAt: time.Now(),
+		UserID:    alice.ID,
+		Url:       compatFeedURL,
+	})
+	if err != nil {
+		t.Fatalf("moving feed: %v", err)
+	}
+
+	server := httptest.NewServer(newServeMux(s))
+	t.Cleanup(server.Close)
+	return &compatServer{s: s, server: server, alice: alice, posts: posts, header: http.Header{}}
+}
+
+// do sends form as the query of a GET and as the body of anything else, the way
+// reader apps do
+func (c *compatServer) do(t *testing.T, method, target string, form url.Values) (*http.Response, string) {
+	t.Helper()
+
+	var body io.Reader
+	if method == http.MethodGet {
+		if len(form) > 0 {
+			separator := "?"
+			if strings.Contains(target, "?") {
+				separator = "&"
+			}
+			target += separator + form.Encode()
+		}
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, c.server.URL+target, body)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%v %v: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	return resp, string(data)
+}
+
+func (c *compatServer) seq(i int) int64 {
+	return c.posts[i].Seq
+}
+
+// refs lists the seqs of alice's posts matching params, newest first
+func (c *compatServer) refs(t *testing.T, params database.ListSyncPostRefsParams) []int64 {
+	t.Helper()
+	params.UserID = c.alice.ID
+	params.ItemLimit = 100
+	refs, err := c.s.db.ListSyncPostRefs(context.Background(), params)
+	if err != nil {
+		t.Fatalf("listing posts: %v", err)
+	}
+	seqs := make([]int64, 0, len(refs))
+	for _, ref := range refs {
+		seqs = append(seqs, ref.Seq)
+	}
+	return seqs
+}
+
+func decodeResponse(t *testing.T, body string, dst any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(body), dst); err != nil {
+		t.Fatalf("invalid json %q: %v", body, err)
+	}
+}
+
+func sortedSeqs(ids string) []int64 {
+	seqs, _ := parseSeqs(ids)
+	slices.Sort(seqs)
+	return seqs
+}
+
+type feverResponse struct {
+	APIVersion  int               `json:"api_version"`
+	Auth        int               `json:"auth"`
+	Groups      []feverGroup      `json:"groups"`
+	FeedsGroups []feverFeedsGroup `json:"feeds_groups"`
+	Feeds       []feverFeed       `json:"feeds"`
+	Items       []feverItem       `json:"items"`
+	TotalItems  int64             `json:"total_items"`
+	UnreadIDs   string            `json:"unread_item_ids"`
+	SavedIDs    string            `json:"saved_item_ids"`
+	Error       string            `json:"error"`
+}
+
+// TestFeverReplay replays what Reeder sends while syncing a fever account
+func TestFeverReplay(t *testing.T) {
+	c := newCompatServer(t)
+	apiKey := feverAPIKey(c.alice)
+	feedID := feedNumber(compatFeedURL)
+
+	steps := []struct {
+		name   string
+		target string
+		form   url.Values
+		status int
+		check  func(t *testing.T, r feverResponse)
+	}{
+		{"wrong key", "/fever/?api", url.Values{"api_key": {"0123456789abcdef"}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if r.Auth != 0 || r.APIVersion != feverVersion {
+				t.Fatalf("expected a failed login, got %+v", r)
+			}
+		}},
+		{"missing key", "/fever/?api", nil, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if r.Auth != 0 {
+				t.Fatalf("expected a failed login, got %+v", r)
+			}
+		}},
+		{"login", "/fever/?api", url.Values{"api_key": {apiKey}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if r.Auth != 1 || r.APIVersion != feverVersion {
+				t.Fatalf("expected to be logged in, got %+v", r)
+			}
+		}},
+		{"groups", "/fever/?api&groups", url.Values{"api_key": {apiKey}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if len(r.Groups) != 1 || r.Groups[0].Title != "news" || r.Groups[0].ID != folderNumber("news") {
+				t.Fatalf("unexpected groups %+v", r.Groups)
+			}
+			if len(r.FeedsGroups) != 1 || r.FeedsGroups[0].FeedIDs != strconv.FormatInt(feedID, 10) {
+				t.Fatalf("unexpected feeds_groups %+v", r.FeedsGroups)
+			}
+		}},
+		{"feeds", "/fever/?api&feeds", url.Values{"api_key": {apiKey}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if len(r.Feeds) != 1 || r.Feeds[0].ID != feedID || r.Feeds[0].Title != "Example" || r.Feeds[0].URL != compatFeedURL {
+				t.Fatalf("unexpected feeds %+v", r.Feeds)
+			}
+		}},
+		{"unread ids", "/fever/?api&unread_item_ids", url.Values{"api_key": {apiKey}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if got := sortedSeqs(r.UnreadIDs); !slices.Equal(got, []int64{c.seq(0), c.seq(1), c.seq(2)}) {
+				t.Fatalf("expected every post unread, got %q", r.UnreadIDs)
+			}
+		}},
+		{"first items", "/fever/?api&items", url.Values{"api_key": {apiKey}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if len(r.Items) != 3 || r.Items[0].ID != c.seq(0) || r.TotalItems != 3 {
+				t.Fatalf("expected every item oldest first, got %+v", r.Items)
+			}
+			item := r.Items[0]
+			if item.FeedID != feedID || item.Title != "post 0" || item.URL != c.posts[0].Url || item.IsRead != 0 ||
+				item.CreatedOnTime != c.posts[0].PublishedAt.Time.Unix() {
+				t.Fatalf("unexpected item %+v", item)
+			}
+		}},
+		{"items since", "/fever/?api&items", url.Values{"api_key": {apiKey}, "since_id": {fmt.Sprint(c.seq(0))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if len(r.Items) != 2 || r.Items[0].ID != c.seq(1) || r.Items[1].ID != c.seq(2) {
+				t.Fatalf("expected the items after the first, got %+v", r.Items)
+			}
+		}},
+		{"items before", "/fever/?api&items", url.Values{"api_key": {apiKey}, "max_id": {fmt.Sprint(c.seq(2))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if len(r.Items) != 2 || r.Items[0].ID != c.seq(1) || r.Items[1].ID != c.seq(0) {
+				t.Fatalf("expected the items before the last newest first, got %+v", r.Items)
+			}
+		}},
+		{"items by id", "/fever/?api&items", url.Values{"api_key": {apiKey}, "with_ids": {fmt.Sprintf("%v,%v", c.seq(0), c.seq(2))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if len(r.Items) != 2 {
+				t.Fatalf("expected 2 items, got %+v", r.Items)
+			}
+		}},
+		{"mark read", "/fever/?api&unread_item_ids", url.Values{"api_key": {apiKey}, "mark": {"item"}, "as": {"read"}, "id": {fmt.Sprint(c.seq(1))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if got := sortedSeqs(r.UnreadIDs); !slices.Equal(got, []int64{c.seq(0), c.seq(2)}) {
+				t.Fatalf("the response should reflect the mark, unread %q", r.UnreadIDs)
+			}
+			if read := c.refs(t, database.ListSyncPostRefsParams{ReadOnly: true}); !slices.Equal(read, []int64{c.seq(1)}) {
+				t.Fatalf("expected only the marked post read, got %v", read)
+			}
+		}},
+		{"mark saved", "/fever/?api&saved_item_ids", url.Values{"api_key": {apiKey}, "mark": {"item"}, "as": {"saved"}, "id": {fmt.Sprint(c.seq(2))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if r.SavedIDs != fmt.Sprint(c.seq(2)) {
+				t.Fatalf("expected the post saved, got %q", r.SavedIDs)
+			}
+		}},
+		{"mark unread", "/fever/?api", url.Values{"api_key": {apiKey}, "mark": {"item"}, "as": {"unread"}, "id": {fmt.Sprint(c.seq(1))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if read := c.refs(t, database.ListSyncPostRefsParams{ReadOnly: true}); len(read) != 0 {
+				t.Fatalf("expected nothing read, got %v", read)
+			}
+		}},
+		{"mark feed read before", "/fever/?api", url.Values{
+			"api_key": {apiKey},
+			"mark":    {"feed"},
+			"as":      {"read"},
+			"id":      {fmt.Sprint(feedID)},
+			"before":  {fmt.Sprint(c.posts[2].CreatedAt.Unix())},
+		}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if read := c.refs(t, database.ListSyncPostRefsParams{ReadOnly: true}); !slices.Equal(read, []int64{c.seq(1), c.seq(0)}) {
+				t.Fatalf("expected the posts before the last read, got %v", read)
+			}
+		}},
+		{"mark group read", "/fever/?api", url.Values{"api_key": {apiKey}, "mark": {"group"}, "as": {"read"}, "id": {fmt.Sprint(folderNumber("news"))}}, http.StatusOK, func(t *testing.T, r feverResponse) {
+			if unread := c.refs(t, database.ListSyncPostRefsParams{UnreadOnly: true}); len(unread) != 0 {
+				t.Fatalf("expected the whole group read, %v unread", unread)
+			}
+		}},
+		{"unknown group", "/fever/?api", url.Values{"api_key": {apiKey}, "mark": {"group"}, "as": {"read"}, "id": {"12345"}}, http.StatusNotFound, nil},
+		{"bad mark", "/fever/?api", url.Values{"api_key": {apiKey}, "mark": {"item"}, "as": {"sideways"}, "id": {"1"}}, http.StatusBadRequest, nil},
+	}
+
+	for _, step := range steps {
+		resp, body := c.do(t, http.MethodPost, step.target, step.form)
+		if resp.StatusCode != step.status {
+			t.Fatalf("%v: expected status %v, got %v: %v", step.name, step.status, resp.StatusCode, body)
+		}
+
+		var r feverResponse
+		decodeResponse(t, body, &r)
+		if step.check != nil {
+			t.Run(step.name, func(t *testing.T) { step.check(t, r) })
+		}
+	}
+}
+
+type greaderResponse struct {
+	ID           string           `json:"id"`
+	Items        []greaderItem    `json:"items"`
+	ItemRefs     []greaderItemRef `json:"itemRefs"`
+	Continuation string           `json:"continuation"`
+}
+
+// TestGReaderReplay replays what NetNewsWire sends while syncing a FreshRSS style account
+func TestGReaderReplay(t *testing.T) {
+	c := newCompatServer(t)
+	const api = greaderPrefix + "/reader/api/0"
+	itemID := func(i int) string { return fmt.Sprintf("%v%016x", greaderItemPrefix, c.seq(i)) }
+
+	resp, body := c.do(t, http.MethodPost, greaderPrefix+"/accounts/ClientLogin", url.Values{"Email": {"alice"}, "Passwd": {"wrong"}})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("a wrong password should be refused, got %v: %v", resp.StatusCode, body)
+	}
+	resp, _ = c.do(t, http.MethodGet, api+"/token", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("requests without a login should be refused, got %v", resp.StatusCode)
+	}
+
+	resp, body = c.do(t, http.MethodPost, greaderPrefix+"/accounts/ClientLogin", url.Values{
+		"Email":  {"alice"},
+		"Passwd": {c.alice.ApiPassword.String},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logging in failed with %v: %v", resp.StatusCode, body)
+	}
+	var auth string
+	for _, line := range strings.Split(body, "\n") {
+		if value, found := strings.CutPrefix(line, "Auth="); found {
+			auth = value
+		}
+	}
+	if auth != greaderAuthToken(c.alice) || !strings.Contains(body, "SID="+auth) {
+		t.Fatalf("unexpected ClientLogin response %q", body)
+	}
+	c.header.Set("Authorization", "GoogleLogin auth="+auth)
+
+	_, token := c.do(t, http.MethodGet, api+"/token", nil)
+	if token != greaderActionToken(c.alice) {
+		t.Fatalf("unexpected token %q", token)
+	}
+
+	steps := []struct {
+		name   string
+		method string
+		target string
+		form   url.Values
+		status int
+		check  func(t *testing.T, resp *http.Response, body string)
+	}{
+		{"reading list", http.MethodGet, api + "/stream/contents/user/-/state/com.google/reading-list", url.Values{"n": {"2"}, "output": {"json"}}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			var r greaderResponse
+			decodeResponse(t, body, &r)
+			if len(r.Items) != 2 || r.Items[0].ID != itemID(2) || r.Items[1].ID != itemID(1) {
+				t.Fatalf("expected the newest two items, got %+v", r.Items)
+			}
+			if r.Continuation != fmt.Sprint(c.seq(1)) {
+				t.Fatalf("expected a continuation after the second item, got %q", r.Continuation)
+			}
+			item := r.Items[0]
+			if item.Origin.StreamID != "feed/"+compatFeedURL || item.Origin.Title != "Example" ||
+				!slices.Contains(item.Categories, "user/-/label/news") || slices.Contains(item.Categories, stateRead) {
+				t.Fatalf("unexpected item %+v", item)
+			}
+		}},
+		{"continued reading list", http.MethodGet, api + "/stream/contents/user/-/state/com.google/reading-list", url.Values{"n": {"2"}, "c": {fmt.Sprint(c.seq(1))}}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			var r greaderResponse
+			decodeResponse(t, body, &r)
+			if len(r.Items) != 1 || r.Items[0].ID != itemID(0) || r.Continuation != "" {
+				t.Fatalf("expected only the oldest item, got %+v (continuation %q)", r.Items, r.Continuation)
+			}
+		}},
+		{"unread ids", http.MethodGet, api + "/stream/items/ids", url.Values{
+			"s":  {stateReadingList},
+			"xt": {stateRead},
+			"n":  {"1000"},
+			"r":  {"o"},
+		}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			var r greaderResponse
+			decodeResponse(t, body, &r)
+			if len(r.ItemRefs) != 3 || r.ItemRefs[0].ID != fmt.Sprint(c.seq(0)) {
+				t.Fatalf("expected every item oldest first, got %+v", r.ItemRefs)
+			}
+		}},
+		{"edit-tag without token", http.MethodPost, api + "/edit-tag", url.Values{"i": {itemID(0)}, "a": {stateRead}}, http.StatusUnauthorized, func(t *testing.T, resp *http.Response, body string) {
+			if resp.Header.Get("X-Reader-Google-Bad-Token") != "true" {
+				t.Fatal("a missing token should be flagged so clients fetch a new one")
+			}
+			if read := c.refs(t, database.ListSyncPostRefsParams{ReadOnly: true}); len(read) != 0 {
+				t.Fatalf("nothing should be marked read, got %v", read)
+			}
+		}},
+		{"mark read", http.MethodPost, api + "/edit-tag", url.Values{"i": {itemID(0), fmt.Sprint(c.seq(1))}, "a": {stateRead}, "T": {token}}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			if body != "OK" {
+				t.Fatalf("expected OK, got %q", body)
+			}
+			if read := c.refs(t, database.ListSyncPostRefsParams{ReadOnly: true}); !slices.Equal(read, []int64{c.seq(1), c.seq(0)}) {
+				t.Fatalf("expected both items read, got %v", read)
+			}
+		}},
+		{"mark unread and starred", http.MethodPost, api + "/edit-tag", url.Values{
+			"i": {itemID(1)},
+			"r": {"user/1005921515/state/com.google/read"},
+			"a": {stateStarred},
+			"T": {token},
+		}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			if read := c.refs(t, database.ListSyncPostRefsParams{ReadOnly: true}); !slices.Equal(read, []int64{c.seq(0)}) {
+				t.Fatalf("expected only the first item read, got %v", read)
+			}
+			if starred := c.refs(t, database.ListSyncPostRefsParams{StarredOnly: true}); !slices.Equal(starred, []int64{c.seq(1)}) {
+				t.Fatalf("expected the second item starred, got %v", starred)
+			}
+		}},
+		{"starred stream", http.MethodGet, api + "/stream/contents/" + stateStarred, nil, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			var r greaderResponse
+			decodeResponse(t, body, &r)
+			if len(r.Items) != 1 || r.Items[0].ID != itemID(1) || !slices.Contains(r.Items[0].Categories, stateStarred) {
+				t.Fatalf("expected the starred item, got %+v", r.Items)
+			}
+		}},
+		{"bad item id", http.MethodPost, api + "/edit-tag", url.Values{"i": {"not-an-id"}, "a": {stateRead}, "T": {token}}, http.StatusBadRequest, nil},
+		{"rename and move", http.MethodPost, api + "/subscription/edit", url.Values{
+			"ac": {"edit"},
+			"s":  {"feed/" + compatFeedURL},
+			"t":  {"Renamed"},
+			"r":  {"user/-/label/news"},
+			"a":  {"user/-/label/Reading"},
+			"T":  {token},
+		}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			sub := findSubscription(t, c, compatFeedURL)
+			if sub == nil || sub.FeedName != "Renamed" || sub.Folder.String != "Reading" {
+				t.Fatalf("expected the feed renamed and moved, got %+v", sub)
+			}
+		}},
+		{"subscribe", http.MethodPost, api + "/subscription/edit", url.Values{
+			"ac": {"subscribe"},
+			"s":  {"feed/" + compatOtherURL},
+			"t":  {"Other"},
+			"a":  {"user/-/label/Reading"},
+			"T":  {token},
+		}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			if feed := mustGetFeed(t, c.s, compatOtherURL); feed.Name != "Other" || feed.UserID != c.alice.ID {
+				t.Fatalf("expected alice to add the feed, got %+v", feed)
+			}
+			if sub := findSubscription(t, c, compatOtherURL); sub == nil || sub.Folder.String != "Reading" {
+				t.Fatalf("expected the new feed in Reading, got %+v", sub)
+			}
+		}},
+		{"unsubscribe", http.MethodPost, api + "/subscription/edit", url.Values{"ac": {"unsubscribe"}, "s": {"feed/" + compatOtherURL}, "T": {token}}, http.StatusOK, func(t *testing.T, resp *http.Response, body string) {
+			if sub := findSubscription(t, c, compatOtherURL); sub != nil {
+				t.Fatalf("expected the feed unfollowed, got %+v", sub)
+			}
+		}},
+		{"unknown action", http.MethodPost, api + "/subscription/edit", url.Values{"ac": {"merge"}, "s": {"feed/" + compatFeedURL}, "T": {token}}, http.StatusBadRequest, nil},
+	}
+
+	for _, step := range steps {
+		resp, body := c.do(t, step.method, step.target, step.form)
+		if resp.StatusCode != step.status {
+			t.Fatalf("%v: expected status %v, got %v: %v", step.name, step.status, resp.StatusCode, body)
+		}
+		if step.check != nil {
+			t.Run(step.name, func(t *testing.T) { step.check(t, resp, body) })
+		}
+	}
+}
+
+func findSubscription(t *testing.T, c *compatServer, feedURL string) *database.GetSubscriptionsForUserRow {
+	t.Helper()
+	subscriptions, err := c.s.db.GetSubscriptionsForUser(context.Background(), c.alice.ID)
+	if err != nil {
+		t.Fatalf("retrieving subscriptions: %v", err)
+	}
+	for _, sub := range subscriptions {
+		if sub.Url == feedURL {
+			return &sub
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Andrew-The-Cat/gator/internal/config"
+)
+
+func handlerConfig(s *state, cmd command) error {
+	if len(cmd.args) < 2 || cmd.args[0] != "profiles" {
+		return fmt.Errorf("command requires profiles followed by one of list, use or add")
+	}
+
+	switch cmd.args[1] {
+	case "list":
+		return listProfiles(s, cmd.args[2:])
+	case "use":
+		return useProfile(cmd.args[2:])
+	case "add":
+		return addProfile(cmd.args[2:])
+	default:
+		return fmt.Errorf("unknown config profiles command %v, expected list, use or add", cmd.args[1])
+	}
+}
+
+func listProfiles(s *state, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("command takes no arguments")
+	}
+
+	current, profiles, err := config.Profiles()
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	records := make([]profileRecord, 0, len(profiles))
+	for _, name := range config.ProfileNames(profiles) {
+		profile := profiles[name]
+		records = append(records, profileRecord{
+			Name:    name,
+			DBURL:   redactDBURL(profile.Conn_str),
+			User:    profile.User_name,
+			Current: name == current,
+			InUse:   name == s.cfg.Profile,
+		})
+	}
+
+	return printList(s, records, []column[profileRecord]{
+		{"name", func(r profileRecord) string { return r.Name }},
+		{"db_url", func(r profileRecord) string { return r.DBURL }},
+		{"user", func(r profileRecord) string { return r.User }},
+		{"current", func(r profileRecord) string { return strconv.FormatBool(r.Current) }},
+		{"in_use", func(r profileRecord) string { return strconv.FormatBool(r.InUse) }},
+	})
+}
+
+// redactDBURL hides the password in a postgres url so listings can be shared
+func redactDBURL(dbURL string) string {
+	parsed, err := url.Parse(dbURL)
+	if err != nil || parsed.User == nil {
+		return dbURL
+	}
+	return parsed.Redacted()
+}
+
+func useProfile(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("command requires the name of a profile")
+	}
+
+	if err := config.UseProfile(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Now using profile %v\n", args[0])
+	return nil
+}
+
+// addProfile creates a profile, --from copies another profile's settings apart from
+// who's logged in
+func addProfile(args []string) error {
+	flags := flag.NewFlagSet("config profiles add", flag.ContinueOnError)
+	dbURL := flags.String("db-url", "", "database the profile connects to")
+	from := flags.String("from", "", "profile whose db_url, smtp, retention and fetch settings are copied")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires the name of the new profile")
+	}
+
+	name := flags.Arg(0)
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("profile names can't be empty or contain spaces")
+	}
+
+	var settings config.Config
+	if *from != "" {
+		var err error
+		settings, err = config.Read(*from)
+		if err != nil {
+			return err
+		}
+		settings.User_name = ""
+		settings.Session = nil
+	}
+	if *dbURL != "" {
+		settings.Conn_str = *dbURL
+	}
+	if settings.Conn_str == "" {
+		return fmt.Errorf("command requires --db-url or --from")
+	}
+
+	if err := config.AddProfile(name, settings); err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile %v added, switch to it with gator config profiles use %v or --profile %v\n", name, name, name)
+	return nil
+}
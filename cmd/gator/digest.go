@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/digest"
+	"github.com/Andrew-The-Cat/gator/internal/render"
+)
+
+const (
+	// digests stop at this many posts and point to browse for the rest
+	digestPostLimit = 100
+	digestWidth     = 72
+	// summaries are cut to roughly this many characters
+	digestSummaryLength = 500
+)
+
+func handlerDigest(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("command requires one of email or send")
+	}
+
+	switch cmd.args[0] {
+	case "email":
+		return digestEmail(s, cmd.args[1:], user)
+	case "send":
+		return digestSend(s, cmd.args[1:], user)
+	default:
+		return fmt.Errorf("unknown digest command %v, expected email or send", cmd.args[0])
+	}
+}
+
+func digestEmail(s *state, args []string, user database.User) error {
+	if len(args) == 0 {
+		if user.Email.Valid {
+			fmt.Printf("Digests are sent to %v\n", user.Email.String)
+		} else {
+			fmt.Println("No email set, digests aren't sent")
+		}
+		return nil
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("command takes a single address, or off to stop digests")
+	}
+
+	email := sql.NullString{}
+	if args[0] != "off" {
+		address, err := mail.ParseAddress(args[0])
+		if err != nil {
+			return fmt.Errorf("%v is not a valid email address", args[0])
+		}
+		email = nullString(address.Address)
+	}
+
+	err := s.db.SetUserEmail(context.Background(), database.SetUserEmailParams{
+		ID:        user.ID,
+		Email:     email,
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating email: %v", err)
+	}
+
+	if email.Valid {
+		fmt.Printf("Digests will be sent to %v\n", email.String)
+	} else {
+		fmt.Println("Digests have been turned off")
+	}
+	return nil
+}
+
+func digestSend(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("digest send", flag.ContinueOnError)
+	all := flags.Bool("all", false, "send a digest to every user with an email")
+	dryRun := flags.Bool("dry-run", false, "print the digests instead of sending them")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 0 {
+		return fmt.Errorf("command takes no arguments besides its flags")
+	}
+
+	if s.cfg.SMTP == nil && !*dryRun {
+		return fmt.Errorf("no smtp server configured, add an smtp section to the config file")
+	}
+
+	users := []database.User{user}
+	if *all {
+		var err error
+		users, err = s.db.GetUsers(context.Background())
+		if err != nil {
+			return fmt.Errorf("error retrieving users: %v", err)
+		}
+	}
+
+	failed := 0
+	for _, recipient := range users {
+		if !recipient.Email.Valid {
+			if !*all {
+				return fmt.Errorf("no email set, use digest email <address> first")
+			}
+			continue
+		}
+
+		if err := sendDigest(s, recipient, *dryRun); err != nil {
+			fmt.Printf("\twarning: couldn't send digest to %v: %v\n", recipient.Name, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v digest(s) couldn't be sent", failed)
+	}
+	return nil
+}
+
+// sendDigest mails the unread posts that arrived since the user's last digest. The
+// cutoff is only recorded once the server accepted the mail, so a failed send is
+// picked up again by the next run
+func sendDigest(s *state, user database.User, dryRun bool) error {
+	cutoff := time.Now()
+
+	posts, err := s.db.GetDigestPostsForUser(context.Background(), database.GetDigestPostsForUserParams{
+		UserID:    user.ID,
+		Since:     user.LastDigestAt,
+		SinceSeq:  user.LastDigestSeq,
+		Until:     cutoff,
+		PostLimit: digestPostLimit + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error retrieving posts: %v", err)
+	}
+	if len(posts) == 0 {
+		fmt.Printf("Nothing new for %v\n", user.Name)
+		return nil
+	}
+
+	from := "gator <gator@localhost>"
+	if s.cfg.SMTP != nil && s.cfg.SMTP.From != "" {
+		from = s.cfg.SMTP.From
+	}
+
+	message := digest.Digest{
+		From: from,
+		To:   user.Email.String,
+		User: user.Name,
+		Date: cutoff,
+	}
+	var cutoffSeq sql.NullInt64
+	posts, cutoff, cutoffSeq, message.Truncated = digestBatch(posts, cutoff)
+	for _, post := range posts {
+		message.Posts = append(message.Posts, digestPost(post))
+	}
+
+	body, err := message.Message()
+	if err != nil {
+		return fmt.Errorf("error building digest: %v", err)
+	}
+
+	if dryRun {
+		fmt.Printf("%s\n", body)
+		return nil
+	}
+
+	err = digest.Send(digest.Server{
+		Host:     s.cfg.SMTP.Host,
+		Port:     s.cfg.SMTP.Port,
+		Username: s.cfg.SMTP.Username,
+		Password: s.cfg.SMTP.Password,
+		TLS:      s.cfg.SMTP.TLS,
+	}, from, user.Email.String, body)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SetUserLastDigest(context.Background(), database.SetUserLastDigestParams{
+		ID:            user.ID,
+		LastDigestAt:  sql.NullTime{Time: cutoff, Valid: true},
+		LastDigestSeq: cutoffSeq,
+	})
+	if err != nil {
+		return fmt.Errorf("digest was sent but recording it failed, the next one will repeat it: %v", err)
+	}
+
+	fmt.Printf("Sent %v post(s) to %v\n", len(posts), user.Email.String)
+	return nil
+}
+
+// digestBatch picks what a digest holds out of posts fetched oldest first, one past the
+// limit. When there are more than fit, the next digest starts right after the last post
+// sent, going by its fetch time and then its seq, so posts fetched in the same instant
+// can be split between digests without any being sent twice or skipped. The batch comes
+// back grouped by feed, newest first
+func digestBatch(posts []database.GetDigestPostsForUserRow, until time.Time) ([]database.GetDigestPostsForUserRow, time.Time, sql.NullInt64, bool) {
+	var untilSeq sql.NullInt64
+	truncated := len(posts) > digestPostLimit
+	if truncated {
+		posts = posts[:digestPostLimit]
+		last := posts[len(posts)-1]
+		until, untilSeq = last.CreatedAt, sql.NullInt64{Int64: last.Seq, Valid: true}
+	}
+
+	slices.SortStableFunc(posts, func(a, b database.GetDigestPostsForUserRow) int {
+		if byFeed := strings.Compare(a.FeedName, b.FeedName); byFeed != 0 {
+			return byFeed
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return posts, until, untilSeq, truncated
+}
+
+func digestPost(post database.GetDigestPostsForUserRow) digest.Post {
+	out := digest.Post{
+		Title:   post.Title.String,
+		URL:     post.Url,
+		Feed:    post.FeedName,
+		Author:  post.Author.String,
+		Summary: render.HTMLToText(post.Description.String, digestWidth),
+	}
+	if out.Title == "" {
+		out.Title = post.Url
+	}
+	if post.PublishedAt.Valid {
+		out.Published = post.PublishedAt.Time
+	}
+
+	if summary := []rune(out.Summary); len(summary) > digestSummaryLength {
+		cut := string(summary[:digestSummaryLength])
+		if space := strings.LastIndex(cut, " "); space > 0 {
+			cut = cut[:space]
+		}
+		out.Summary = strings.TrimSpace(cut) + "…"
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestDigestPostCutsOnRunes(t *testing.T) {
+	summary := strings.Repeat("é", digestSummaryLength+20)
+	out := digestPost(database.GetDigestPostsForUserRow{
+		Url:         "https://example.com/post",
+		Description: sql.NullString{String: summary, Valid: true},
+	})
+
+	if !utf8.ValidString(out.Summary) {
+		t.Fatalf("summary was cut inside a character: %q", out.Summary)
+	}
+	if want := strings.Repeat("é", digestSummaryLength) + "…"; out.Summary != want {
+		t.Fatalf("expected %v characters and an ellipsis, got %v", digestSummaryLength, utf8.RuneCountInString(out.Summary))
+	}
+
+	words := strings.Repeat("ünïcödé ", digestSummaryLength/4)
+	out = digestPost(database.GetDigestPostsForUserRow{Description: sql.NullString{String: words, Valid: true}})
+	if !utf8.ValidString(out.Summary) || !strings.HasSuffix(out.Summary, "ünïcödé…") {
+		t.Fatalf("expected the summary cut at a space, got %q", out.Summary)
+	}
+}
+
+func digestRows(feeds []string, created []time.Time) []database.GetDigestPostsForUserRow {
+	rows := make([]database.GetDigestPostsForUserRow, 0, len(created))
+	for i, at := range created {
+		rows = append(rows, database.GetDigestPostsForUserRow{
+			Url:       fmt.Sprintf("https://example.com/%v", i),
+			CreatedAt: at,
+			FeedName:  feeds[i%len(feeds)],
+		})
+	}
+	return rows
+}
+
+func TestDigestBatch(t *testing.T) {
+	until := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	apart := func(n int) []time.Time {
+		times := make([]time.Time, n)
+		for i := range times {
+			times[i] = start.Add(time.Duration(i) * time.Minute)
+		}
+		return times
+	}
+
+	// everything fits, the cutoff stays put and posts are grouped newest first
+	posts, cutoff, seq, truncated := digestBatch(digestRows([]string{"B", "A"}, apart(4)), until)
+	if truncated || !cutoff.Equal(until) || seq.Valid || len(posts) != 4 {
+		t.Fatalf("expected every post and the original cutoff, got %v posts until %v (%v)", len(posts), cutoff, seq)
+	}
+	if posts[0].FeedName != "A" || posts[0].Url != "https://example.com/3" || posts[2].FeedName != "B" || posts[2].Url != "https://example.com/2" {
+		t.Fatalf("expected posts grouped by feed newest first, got %+v", posts)
+	}
+
+	// one too many, the next digest starts right after the last post sent
+	rows := digestRows([]string{"A"}, apart(digestPostLimit+1))
+	for i := range rows {
+		rows[i].Seq = int64(i + 1)
+	}
+	last := rows[digestPostLimit-1]
+	posts, cutoff, seq, truncated = digestBatch(rows, until)
+	if !truncated || len(posts) != digestPostLimit || !cutoff.Equal(last.CreatedAt) || seq.Int64 != last.Seq {
+		t.Fatalf("expected %v posts until the last one sent, got %v until %v (%v)", digestPostLimit, len(posts), cutoff, seq)
+	}
+}
+
+// TestDigestsLoseNothing pages through more posts than fit in a digest, all fetched in the
+// same instant, the way successive digests do
+func TestDigestsLoseNothing(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", "https://example.com/feed.xml")
+	feed := mustGetFeed(t, s, "https://example.com/feed.xml")
+
+	fetched := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	total := digestPostLimit*2 + 10
+	for i := 0; i < total; i++ {
+		_, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+			ID:        uuid.New(),
+			CreatedAt: fetched,
+			UpdatedAt: fetched,
+			Url:       fmt.Sprintf("https://example.com/posts/%v", i),
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			t.Fatalf("creating post: %v", err)
+		}
+	}
+
+	seen := map[string]int{}
+	for digests := 0; ; digests++ {
+		if digests > 5 {
+			t.Fatalf("digests never caught up, saw %v of %v posts", len(seen), total)
+		}
+
+		user := mustGetUser(t, s, "alice")
+		until := fetched.Add(time.Hour)
+		rows, err := s.db.GetDigestPostsForUser(context.Background(), database.GetDigestPostsForUserParams{
+			UserID:    user.ID,
+			Since:     user.LastDigestAt,
+			SinceSeq:  user.LastDigestSeq,
+			Until:     until,
+			PostLimit: digestPostLimit + 1,
+		})
+		if err != nil {
+			t.Fatalf("retrieving digest posts: %v", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		posts, cutoff, cutoffSeq, _ := digestBatch(rows, until)
+		for _, post := range posts {
+			seen[post.Url]++
+		}
+		err = s.db.SetUserLastDigest(context.Background(), database.SetUserLastDigestParams{
+			ID:            user.ID,
+			LastDigestAt:  sql.NullTime{Time: cutoff, Valid: true},
+			LastDigestSeq: cutoffSeq,
+		})
+		if err != nil {
+			t.Fatalf("recording digest: %v", err)
+		}
+	}
+
+	if len(seen) != total {
+		t.Fatalf("expected all %v posts across the digests, got %v", total, len(seen))
+	}
+	for url, times := range seen {
+		if times != 1 {
+			t.Fatalf("%v was sent %v times", url, times)
+		}
+	}
+}
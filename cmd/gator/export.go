@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/export"
+	"github.com/google/uuid"
+)
+
+// posts are pulled from the database in batches of this size while exporting
+const exportBatchSize = 500
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("command requires what to export: opml or posts")
+	}
+
+	switch cmd.args[0] {
+	case "opml":
+		return exportOPML(s, cmd.args[1:], user)
+	case "posts":
+		return exportPosts(s, cmd.args[1:], user)
+	default:
+		return fmt.Errorf("unknown export %v, expected opml or posts", cmd.args[0])
+	}
+}
+
+func exportPosts(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("export posts", flag.ContinueOnError)
+	format := flags.String("format", "json", "output format, one of "+strings.Join(export.Formats, ", "))
+	feed := flags.String("feed", "", "only export posts from the feed with this url or name")
+	since := flags.String("since", "", "only export posts from this date onwards (YYYY-MM-DD or RFC3339)")
+	until := flags.String("until", "", "only export posts from before this date (YYYY-MM-DD or RFC3339)")
+	starred := flags.Bool("starred", false, "only export starred posts")
+	path := flags.String("file", "", "write the export to this file instead of stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	params := database.ExportPostsForUserParams{
+		UserID:      user.ID,
+		StarredOnly: *starred,
+		BatchSize:   exportBatchSize,
+	}
+	if *feed != "" {
+		params.Feed.Scan(*feed)
+	}
+
+	var err error
+	if params.Since, err = parseDateFlag(*since); err != nil {
+		return fmt.Errorf("error when parsing --since: %v", err)
+	}
+	if params.Until, err = parseDateFlag(*until); err != nil {
+		return fmt.Errorf("error when parsing --until: %v", err)
+	}
+
+	out, err := exportDestination(*path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	buffered := bufio.NewWriter(out)
+	writer, err := export.NewWriter(*format, buffered)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for {
+		batch, err := s.db.ExportPostsForUser(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("error retrieving posts: %v", err)
+		}
+
+		for _, row := range batch {
+			if err := writer.Write(exportedPost(row)); err != nil {
+				return fmt.Errorf("error writing export: %v", err)
+			}
+		}
+		total += len(batch)
+
+		if len(batch) < int(params.BatchSize) {
+			break
+		}
+
+		last := batch[len(batch)-1]
+		params.AfterCreatedAt.Scan(last.CreatedAt)
+		params.AfterID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+
+	if *path != "" {
+		fmt.Printf("Exported %v posts to %v\n", total, *path)
+	}
+	return out.Close()
+}
+
+func exportedPost(row database.ExportPostsForUserRow) export.Post {
+	post := export.Post{
+		ID:          row.ID.String(),
+		Title:       row.Title.String,
+		URL:         row.Url,
+		Feed:        row.FeedName,
+		FeedURL:     row.FeedUrl,
+		Author:      row.Author.String,
+		FetchedAt:   row.CreatedAt,
+		Read:        row.ReadAt.Valid,
+		Starred:     row.StarredAt.Valid,
+		Description: row.Description.String,
+		Content:     row.Content.String,
+	}
+	if row.PublishedAt.Valid {
+		post.PublishedAt = &row.PublishedAt.Time
+	}
+	return post
+}
+
+// exportDestination opens the file exports are written to, stdout when no path is given
+func exportDestination(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %v: %v", path, err)
+	}
+	return file, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
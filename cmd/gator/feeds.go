@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+func handlerFeeds(s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		return listFeeds(s)
+	}
+
+	switch cmd.args[0] {
+	case "list":
+		return listFeeds(s)
+	case "remove":
+		return middlewareLoggedIn(removeFeed)(s, command{name: cmd.name, args: cmd.args[1:]})
+	case "rename":
+		return middlewareLoggedIn(renameFeed)(s, command{name: cmd.name, args: cmd.args[1:]})
+	case "set-url":
+		return middlewareLoggedIn(setFeedURL)(s, command{name: cmd.name, args: cmd.args[1:]})
+	default:
+		return fmt.Errorf("unknown feeds command %v, expected list, remove, rename or set-url", cmd.args[0])
+	}
+}
+
+func listFeeds(s *state) error {
+	data, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("error retrieving feeds: %v", err)
+	}
+
+	records := make([]feedRecord, 0, len(data))
+	for _, row := range data {
+		records = append(records, feedRecord{
+			Name:      row.Name,
+			URL:       row.Url,
+			CreatedBy: row.UserName,
+		})
+	}
+
+	return printList(s, records, []column[feedRecord]{
+		{"name", func(r feedRecord) string { return r.Name }},
+		{"url", func(r feedRecord) string { return r.URL }},
+		{"created_by", func(r feedRecord) string { return r.CreatedBy }},
+	})
+}
+
+// getOwnedFeed looks up a feed that's about to be changed, only the user who added a
+// feed may change it since everyone following it sees the change
+func getOwnedFeed(db database.FeedRepository, user database.User, feedURL, action string) (database.Feed, error) {
+	feed, err := db.GetFeedByUrl(context.Background(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, fmt.Errorf("no feed found at %v", feedURL)
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("error retrieving feed: %v", err)
+	}
+	if feed.UserID != user.ID {
+		return database.Feed{}, fmt.Errorf("only the user who added %v can %v it", feedURL, action)
+	}
+	return feed, nil
+}
+
+func removeFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("command requires the url of the feed to remove")
+	}
+
+	var removed database.Feed
+	err := withTx(context.Background(), s, func(db database.Store) error {
+		feed, err := getOwnedFeed(db, user, cmd.args[0], "remove")
+		if err != nil {
+			return err
+		}
+
+		followers, err := db.CountOtherFeedFollowers(context.Background(), database.CountOtherFeedFollowersParams{
+			FeedID: feed.ID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("error counting followers: %v", err)
+		}
+		if followers > 0 {
+			return fmt.Errorf("%v other user(s) still follow %v, it can only be removed once nobody else does", followers, feed.Url)
+		}
+
+		// posts, follows and everything hanging off them go with the feed
+		if _, err := db.DeleteFeed(context.Background(), feed.ID); err != nil {
+			return fmt.Errorf("error removing feed: %v", err)
+		}
+		removed = feed
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Feed %v (%v) has been removed\n", removed.Name, removed.Url)
+	return nil
+}
+
+func renameFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("command requires the url of the feed and its new name")
+	}
+
+	name := strings.TrimSpace(cmd.args[1])
+	if name == "" {
+		return fmt.Errorf("feed name can't be empty")
+	}
+
+	feed, err := getOwnedFeed(s.db, user, cmd.args[0], "rename")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.RenameFeed(context.Background(), database.RenameFeedParams{
+		ID:        feed.ID,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error renaming feed: %v", err)
+	}
+
+	fmt.Printf("Feed at %v has been renamed from %v to %v\n", feed.Url, feed.Name, name)
+	return nil
+}
+
+func setFeedURL(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("command requires the current and the new url of the feed")
+	}
+
+	newURL := cmd.args[1]
+	if err := validFeedURL(newURL); err != nil {
+		return err
+	}
+
+	feed, err := getOwnedFeed(s.db, user, cmd.args[0], "change the url of")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.SetFeedUrl(context.Background(), database.SetFeedUrlParams{
+		ID:        feed.ID,
+		Url:       newURL,
+		UpdatedAt: time.Now(),
+	})
+	if isUniqueViolation(err) {
+		return fmt.Errorf("a feed at %v already exists", newURL)
+	}
+	if err != nil {
+		return fmt.Errorf("error updating feed: %v", err)
+	}
+
+	fmt.Printf("Feed %v now points at %v\n", feed.Name, newURL)
+	return nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+/*
+======================================================
+
+		Fever API
+
+	https://feedafever.com/api, every request is a
+	POST to /fever/?api carrying api_key in its form
+
+======================================================
+*/
+
+const (
+	feverVersion    = 3
+	feverItemsLimit = 50
+)
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+func serveFever(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "malformed request", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]any{
+			"api_version": feverVersion,
+			"auth":        0,
+		}
+
+		user, ok := feverUser(s, r, r.Form.Get("api_key"))
+		if !ok {
+			writeJSON(w, http.StatusOK, response)
+			return
+		}
+		response["auth"] = 1
+
+		has := func(key string) bool {
+			_, ok := r.Form[key]
+			return ok
+		}
+
+		// writes come first so the lists below already reflect them
+		if has("mark") {
+			if err := feverMark(s, r, user); err != nil {
+				writeAPIError(w, err)
+				return
+			}
+		}
+
+		subscriptions, err := s.db.GetSubscriptionsForUser(r.Context(), user.ID)
+		if err != nil {
+			writeAPIError(w, fmt.Errorf("error retrieving subscriptions: %v", err))
+			return
+		}
+		response["last_refreshed_on_time"] = lastRefreshed(subscriptions)
+
+		if has("groups") {
+			response["groups"] = feverGroups(subscriptions)
+			response["feeds_groups"] = feverFeedsGroups(subscriptions)
+		}
+
+		if has("feeds") {
+			feeds := make([]feverFeed, 0, len(subscriptions))
+			for _, sub := range subscriptions {
+				feed := feverFeed{
+					ID:      feedNumber(sub.Url),
+					Title:   sub.FeedName,
+					URL:     sub.Url,
+					SiteURL: sub.SiteUrl.String,
+				}
+				if sub.LastFetchedAt.Valid {
+					feed.LastUpdatedOnTime = sub.LastFetchedAt.Time.Unix()
+				}
+				feeds = append(feeds, feed)
+			}
+			response["feeds"] = feeds
+			response["feeds_groups"] = feverFeedsGroups(subscriptions)
+		}
+
+		if has("favicons") {
+			response["favicons"] = []any{}
+		}
+
+		if has("links") {
+			response["links"] = []any{}
+		}
+
+		if has("items") {
+			items, total, err := feverItems(s, r, user)
+			if err != nil {
+				writeAPIError(w, err)
+				return
+			}
+			response["items"] = items
+			response["total_items"] = total
+		}
+
+		if has("unread_item_ids") {
+			ids, err := feverItemIDs(s, r, database.ListSyncPostRefsParams{UserID: user.ID, UnreadOnly: true})
+			if err != nil {
+				writeAPIError(w, err)
+				return
+			}
+			response["unread_item_ids"] = ids
+		}
+
+		if has("saved_item_ids") {
+			ids, err := feverItemIDs(s, r, database.ListSyncPostRefsParams{UserID: user.ID, StarredOnly: true})
+			if err != nil {
+				writeAPIError(w, err)
+				return
+			}
+			response["saved_item_ids"] = ids
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// feverUser finds whose api key this is, fever keys don't carry the user's name
+func feverUser(s *state, r *http.Request, key string) (database.User, bool) {
+	if key == "" {
+		return database.User{}, false
+	}
+
+	users, err := s.db.GetUsers(r.Context())
+	if err != nil {
+		fmt.Printf("\twarning: error retrieving users: %v\n", err)
+		return database.User{}, false
+	}
+
+	for _, user := range users {
+		if !user.ApiPassword.Valid {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(feverAPIKey(user)), []byte(key)) == 1 {
+			return user, true
+		}
+	}
+	return database.User{}, false
+}
+
+func feverMark(s *state, r *http.Request, user database.User) error {
+	id, err := strconv.ParseInt(r.Form.Get("id"), 10, 64)
+	if err != nil {
+		return opErrorf(failInvalid, "invalid id %v", r.Form.Get("id"))
+	}
+	as := r.Form.Get("as")
+
+	switch r.Form.Get("mark") {
+	case "item":
+		seqs := []int64{id}
+		switch as {
+		case "read":
+			_, err = s.db.MarkPostsReadBySeq(r.Context(), database.MarkPostsReadBySeqParams{ReadAt: time.Now(), UserID: user.ID, Seqs: seqs})
+		case "unread":
+			_, err = s.db.MarkPostsUnreadBySeq(r.Context(), database.MarkPostsUnreadBySeqParams{UserID: user.ID, Seqs: seqs})
+		case "saved":
+			_, err = s.db.StarPostsBySeq(r.Context(), database.StarPostsBySeqParams{StarredAt: time.Now(), UserID: user.ID, Seqs: seqs})
+		case "unsaved":
+			_, err = s.db.UnstarPostsBySeq(r.Context(), database.UnstarPostsBySeqParams{UserID: user.ID, Seqs: seqs})
+		default:
+			return opErrorf(failInvalid, "items can't be marked as %v", as)
+		}
+		if err != nil {
+			return fmt.Errorf("error marking item: %v", err)
+		}
+		return nil
+
+	case "feed", "group":
+		if as != "read" {
+			return opErrorf(failInvalid, "%v can only be marked as read", r.Form.Get("mark"))
+		}
+
+		params := database.MarkPostsReadBeforeParams{
+			ReadAt: time.Now(),
+			UserID: user.ID,
+			Before: time.Now(),
+		}
+		if before, err := strconv.ParseInt(r.Form.Get("before"), 10, 64); err == nil && before > 0 {
+			params.Before = time.Unix(before, 0).UTC()
+		}
+
+		subscriptions, err := s.db.GetSubscriptionsForUser(r.Context(), user.ID)
+		if err != nil {
+			return fmt.Errorf("error retrieving subscriptions: %v", err)
+		}
+
+		// group 0 is fever's "all items"
+		found := r.Form.Get("mark") == "group" && id == 0
+		for _, sub := range subscriptions {
+			if r.Form.Get("mark") == "feed" && feedNumber(sub.Url) == id {
+				params.FeedUrl = nullString(sub.Url)
+				found = true
+			}
+			if r.Form.Get("mark") == "group" && sub.Folder.Valid && folderNumber(sub.Folder.String) == id {
+				params.Folder = sub.Folder
+				found = true
+			}
+		}
+		if !found {
+			return opErrorf(failNotFound, "no %v %v", r.Form.Get("mark"), id)
+		}
+
+		if _, err := s.db.MarkPostsReadBefore(r.Context(), params); err != nil {
+			return fmt.Errorf("error marking posts as read: %v", err)
+		}
+		return nil
+
+	default:
+		return opErrorf(failInvalid, "unknown mark %v", r.Form.Get("mark"))
+	}
+}
+
+func feverItems(s *state, r *http.Request, user database.User) ([]feverItem, int64, error) {
+	params := database.ListSyncPostsParams{
+		UserID:    user.ID,
+		ItemLimit: feverItemsLimit,
+	}
+
+	switch {
+	case r.Form.Get("with_ids") != "":
+		seqs, err := parseSeqs(r.Form.Get("with_ids"))
+		if err != nil {
+			return nil, 0, opErrorf(failInvalid, "%v", err)
+		}
+		params.Seqs = seqs
+	case r.Form.Get("since_id") != "":
+		since, err := strconv.ParseInt(r.Form.Get("since_id"), 10, 64)
+		if err != nil {
+			return nil, 0, opErrorf(failInvalid, "invalid since_id")
+		}
+		params.AfterSeq = sql.NullInt64{Int64: since, Valid: true}
+		params.Ascending = true
+	case r.Form.Get("max_id") != "":
+		max, err := strconv.ParseInt(r.Form.Get("max_id"), 10, 64)
+		if err != nil {
+			return nil, 0, opErrorf(failInvalid, "invalid max_id")
+		}
+		params.BeforeSeq = sql.NullInt64{Int64: max, Valid: true}
+	default:
+		// without a starting point fever clients expect the oldest items first
+		params.Ascending = true
+	}
+
+	posts, err := s.db.ListSyncPosts(r.Context(), params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error retrieving posts: %v", err)
+	}
+
+	total, err := s.db.CountPostsForUser(r.Context(), user.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting posts: %v", err)
+	}
+
+	items := make([]feverItem, 0, len(posts))
+	for _, post := range posts {
+		item := feverItem{
+			ID:            post.Seq,
+			FeedID:        feedNumber(post.FeedUrl),
+			Title:         post.Title.String,
+			Author:        post.Author.String,
+			HTML:          post.Description.String,
+			URL:           post.Url,
+			CreatedOnTime: post.CreatedAt.Unix(),
+		}
+		if post.Content.Valid && post.Content.String != "" {
+			item.HTML = post.Content.String
+		}
+		if post.PublishedAt.Valid {
+			item.CreatedOnTime = post.PublishedAt.Time.Unix()
+		}
+		if post.ReadAt.Valid {
+			item.IsRead = 1
+		}
+		if post.StarredAt.Valid {
+			item.IsSaved = 1
+		}
+		items = append(items, item)
+	}
+
+	return items, total, nil
+}
+
+func feverItemIDs(s *state, r *http.Request, params database.ListSyncPostRefsParams) (string, error) {
+	params.ItemLimit = math.MaxInt32
+	refs, err := s.db.ListSyncPostRefs(r.Context(), params)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving item ids: %v", err)
+	}
+
+	seqs := make([]int64, 0, len(refs))
+	for _, ref := range refs {
+		seqs = append(seqs, ref.Seq)
+	}
+	return joinSeqs(seqs), nil
+}
+
+func feverGroups(subscriptions []database.GetSubscriptionsForUserRow) []feverGroup {
+	seen := make(map[string]bool)
+	groups := make([]feverGroup, 0)
+	for _, sub := range subscriptions {
+		if !sub.Folder.Valid || seen[sub.Folder.String] {
+			continue
+		}
+		seen[sub.Folder.String] = true
+		groups = append(groups, feverGroup{ID: folderNumber(sub.Folder.String), Title: sub.Folder.String})
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Title < groups[j].Title })
+	return groups
+}
+
+func feverFeedsGroups(subscriptions []database.GetSubscriptionsForUserRow) []feverFeedsGroup {
+	members := make(map[string][]int64)
+	for _, sub := range subscriptions {
+		if sub.Folder.Valid {
+			members[sub.Folder.String] = append(members[sub.Folder.String], feedNumber(sub.Url))
+		}
+	}
+
+	groups := make([]feverFeedsGroup, 0, len(members))
+	for _, group := range feverGroups(subscriptions) {
+		groups = append(groups, feverFeedsGroup{GroupID: group.ID, FeedIDs: joinSeqs(members[group.Title])})
+	}
+	return groups
+}
+
+func lastRefreshed(subscriptions []database.GetSubscriptionsForUserRow) int64 {
+	var last int64
+	for _, sub := range subscriptions {
+		if sub.LastFetchedAt.Valid && sub.LastFetchedAt.Time.Unix() > last {
+			last = sub.LastFetchedAt.Time.Unix()
+		}
+	}
+	return last
+}
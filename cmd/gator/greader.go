@@ -0,0 +1,794 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+/*
+======================================================
+
+		Google Reader API
+
+	the dialect spoken by FreshRSS and Miniflux, clients
+	are pointed at /greader and log in with ClientLogin
+
+======================================================
+*/
+
+const (
+	greaderPrefix = "/greader"
+
+	greaderItemPrefix = "tag:google.com,2005:reader/item/"
+
+	stateReadingList = "user/-/state/com.google/reading-list"
+	stateRead        = "user/-/state/com.google/read"
+	stateStarred     = "user/-/state/com.google/starred"
+	stateKeptUnread  = "user/-/state/com.google/kept-unread"
+
+	defaultGreaderLimit = 20
+	maxGreaderLimit     = 10000
+)
+
+// user ids in stream ids are either - or whatever user-info returned
+var greaderUserStream = regexp.MustCompile(`^user/[^/]+/(state/com\.google|label)/(.+)$`)
+
+type greaderStream struct {
+	id      string
+	feedURL sql.NullString
+	folder  sql.NullString
+	read    bool
+	starred bool
+}
+
+type greaderCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+type greaderSubscription struct {
+	ID            string            `json:"id"`
+	Title         string            `json:"title"`
+	Categories    []greaderCategory `json:"categories"`
+	URL           string            `json:"url"`
+	HTMLURL       string            `json:"htmlUrl"`
+	IconURL       string            `json:"iconUrl"`
+	FirstItemMsec string            `json:"firstitemmsec"`
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type greaderOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+type greaderItem struct {
+	ID            string         `json:"id"`
+	CrawlTimeMsec string         `json:"crawlTimeMsec"`
+	TimestampUsec string         `json:"timestampUsec"`
+	Published     int64          `json:"published"`
+	Updated       int64          `json:"updated"`
+	Title         string         `json:"title"`
+	Canonical     []greaderLink  `json:"canonical"`
+	Alternate     []greaderLink  `json:"alternate"`
+	Summary       greaderContent `json:"summary"`
+	Author        string         `json:"author,omitempty"`
+	Categories    []string       `json:"categories"`
+	Origin        greaderOrigin  `json:"origin"`
+}
+
+type greaderItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+type greaderUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int64  `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+func registerGReader(s *state, mux *http.ServeMux) {
+	api := greaderPrefix + "/reader/api/0"
+
+	mux.HandleFunc(greaderPrefix+"/accounts/ClientLogin", greaderClientLogin(s))
+	mux.HandleFunc("GET "+api+"/token", greaderAuth(s, greaderToken))
+	mux.HandleFunc("GET "+api+"/user-info", greaderAuth(s, greaderUserInfo))
+	mux.HandleFunc("GET "+api+"/subscription/list", greaderAuth(s, greaderSubscriptionList))
+	mux.HandleFunc("POST "+api+"/subscription/edit", greaderAuth(s, greaderWrite(greaderSubscriptionEdit)))
+	mux.HandleFunc("POST "+api+"/subscription/quickadd", greaderAuth(s, greaderWrite(greaderQuickAdd)))
+	mux.HandleFunc("GET "+api+"/tag/list", greaderAuth(s, greaderTagList))
+	mux.HandleFunc("GET "+api+"/unread-count", greaderAuth(s, greaderUnreadCounts))
+	mux.HandleFunc("GET "+api+"/stream/items/ids", greaderAuth(s, greaderItemIDs))
+	mux.HandleFunc(api+"/stream/items/contents", greaderAuth(s, greaderItemContents))
+	mux.HandleFunc("GET "+api+"/stream/contents", greaderAuth(s, greaderStreamContents))
+	mux.HandleFunc("GET "+api+"/stream/contents/{stream...}", greaderAuth(s, greaderStreamContents))
+	mux.HandleFunc("POST "+api+"/edit-tag", greaderAuth(s, greaderWrite(greaderEditTag)))
+	mux.HandleFunc("POST "+api+"/mark-all-as-read", greaderAuth(s, greaderWrite(greaderMarkAllRead)))
+}
+
+/*
+======================================================
+
+		Authentication
+
+======================================================
+*/
+
+func greaderClientLogin(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error=BadAuthentication", http.StatusBadRequest)
+			return
+		}
+
+		user, err := s.db.GetUser(r.Context(), r.Form.Get("Email"))
+		if err != nil || !validAPIPassword(user, r.Form.Get("Passwd")) {
+			http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+			return
+		}
+
+		token := greaderAuthToken(user)
+		if r.Form.Get("output") == "json" {
+			writeJSON(w, http.StatusOK, map[string]string{"SID": token, "LSID": token, "Auth": token})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "SID=%v\nLSID=%v\nAuth=%v\n", token, token, token)
+	}
+}
+
+// greaderAuth checks the "Authorization: GoogleLogin auth=" header ClientLogin's token is sent back in
+func greaderAuth(s *state, handler func(*state, http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		name, _, _ := strings.Cut(token, "/")
+		if !found || name == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := s.db.GetUser(r.Context(), name)
+		if err != nil || !user.ApiPassword.Valid ||
+			subtle.ConstantTimeCompare([]byte(greaderAuthToken(user)), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "malformed request", http.StatusBadRequest)
+			return
+		}
+
+		handler(s, w, r, user)
+	}
+}
+
+// greaderWrite requires the token from /token on requests that change anything
+func greaderWrite(handler func(*state, http.ResponseWriter, *http.Request, database.User)) func(*state, http.ResponseWriter, *http.Request, database.User) {
+	return func(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+		if subtle.ConstantTimeCompare([]byte(greaderActionToken(user)), []byte(r.Form.Get("T"))) != 1 {
+			w.Header().Set("X-Reader-Google-Bad-Token", "true")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler(s, w, r, user)
+	}
+}
+
+func greaderActionToken(user database.User) string {
+	sum := sha256.Sum256([]byte("action:" + greaderAuthToken(user)))
+	return hex.EncodeToString(sum[:])[:57]
+}
+
+func greaderToken(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	greaderText(w, greaderActionToken(user))
+}
+
+func greaderUserInfo(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+/*
+======================================================
+
+		Subscriptions and tags
+
+======================================================
+*/
+
+func greaderSubscriptionList(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	subscriptions, err := s.db.GetSubscriptionsForUser(r.Context(), user.ID)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error retrieving subscriptions: %v", err))
+		return
+	}
+
+	list := make([]greaderSubscription, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		entry := greaderSubscription{
+			ID:            "feed/" + sub.Url,
+			Title:         sub.FeedName,
+			Categories:    make([]greaderCategory, 0, 1),
+			URL:           sub.Url,
+			HTMLURL:       sub.SiteUrl.String,
+			FirstItemMsec: strconv.FormatInt(sub.CreatedAt.UnixMilli(), 10),
+		}
+		if sub.Folder.Valid {
+			entry.Categories = append(entry.Categories, greaderCategory{ID: "user/-/label/" + sub.Folder.String, Label: sub.Folder.String})
+		}
+		list = append(list, entry)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"subscriptions": list})
+}
+
+// greaderSubscriptionEdit handles subscribe, unsubscribe and edit, t sets the title
+// while a and r add and remove the feed from a folder
+func greaderSubscriptionEdit(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	action := r.Form.Get("ac")
+	streams := r.Form["s"]
+	if len(streams) == 0 {
+		writeAPIError(w, opErrorf(failInvalid, "s is required"))
+		return
+	}
+
+	for _, stream := range streams {
+		feedURL, found := strings.CutPrefix(stream, "feed/")
+		if !found {
+			writeAPIError(w, opErrorf(failInvalid, "%v is not a feed", stream))
+			return
+		}
+
+		var err error
+		switch action {
+		case "subscribe":
+			_, err = greaderSubscribe(r.Context(), s, user, feedURL, r.Form.Get("t"))
+			if err == nil {
+				err = withTx(r.Context(), s, func(db database.Store) error {
+					return greaderEditSubscription(r, db, user, feedURL, false)
+				})
+			}
+		case "edit":
+			err = withTx(r.Context(), s, func(db database.Store) error {
+				return greaderEditSubscription(r, db, user, feedURL, true)
+			})
+		case "unsubscribe":
+			err = unfollowFeed(r.Context(), s.db, user, feedURL)
+		default:
+			err = opErrorf(failInvalid, "unknown action %v", action)
+		}
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+	}
+
+	greaderText(w, "OK")
+}
+
+func greaderEditSubscription(r *http.Request, db database.FollowRepository, user database.User, feedURL string, retitle bool) error {
+	if title := r.Form.Get("t"); retitle && title != "" {
+		if err := setFollowTitle(r.Context(), db, user, feedURL, nullString(title)); err != nil {
+			return err
+		}
+	}
+
+	var folder sql.NullString
+	changed := false
+	if label, found := strings.CutPrefix(r.Form.Get("r"), "user/-/label/"); found && label != "" {
+		changed = true
+	}
+	if label, found := strings.CutPrefix(r.Form.Get("a"), "user/-/label/"); found && label != "" {
+		folder = nullString(label)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	affected, err := db.SetFeedFollowFolder(r.Context(), database.SetFeedFollowFolderParams{
+		Folder:    folder,
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Url:       feedURL,
+	})
+	if err != nil {
+		return fmt.Errorf("error moving feed: %v", err)
+	}
+	if affected == 0 {
+		return opErrorf(failNotFound, "you are not following a feed at %v", feedURL)
+	}
+	return nil
+}
+
+// greaderSubscribe follows a feed gator already knows about and adds it otherwise,
+// reader apps don't make that distinction
+func greaderSubscribe(ctx context.Context, s *state, user database.User, feedURL, title string) (string, error) {
+	// following happens outside of a transaction, postgres won't commit one that hit
+	// the conflict of an existing follow
+	follow, err := followFeed(ctx, s.db, user, feedURL)
+	if err == nil {
+		return follow.FeedName, nil
+	}
+	if failureOf(err) == failConflict {
+		return feedURL, nil
+	}
+	if failureOf(err) != failNotFound {
+		return "", err
+	}
+
+	if title == "" {
+		title = feedURL
+	}
+	var feed database.Feed
+	err = withTx(ctx, s, func(db database.Store) error {
+		var err error
+		feed, err = addFeed(ctx, db, user, title, feedURL)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return feed.Name, nil
+}
+
+func greaderQuickAdd(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	feedURL := strings.TrimPrefix(r.Form.Get("quickadd"), "feed/")
+	if feedURL == "" {
+		writeAPIError(w, opErrorf(failInvalid, "quickadd is required"))
+		return
+	}
+
+	name, err := greaderSubscribe(r.Context(), s, user, feedURL, "")
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"numResults": 1,
+		"query":      feedURL,
+		"streamId":   "feed/" + feedURL,
+		"streamName": name,
+	})
+}
+
+func greaderTagList(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	subscriptions, err := s.db.GetSubscriptionsForUser(r.Context(), user.ID)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error retrieving subscriptions: %v", err))
+		return
+	}
+
+	tags := []greaderCategory{{ID: stateStarred}}
+	for _, group := range feverGroups(subscriptions) {
+		tags = append(tags, greaderCategory{ID: "user/-/label/" + group.Title, Type: "folder"})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func greaderUnreadCounts(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := s.db.CountUnreadPostsByFeed(r.Context(), user.ID)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error counting unread posts: %v", err))
+		return
+	}
+
+	type tally struct {
+		count  int64
+		newest time.Time
+	}
+
+	var total tally
+	folders := make(map[string]*tally)
+	var order []string
+	list := make([]greaderUnreadCount, 0, len(counts)+1)
+	for _, count := range counts {
+		list = append(list, greaderUnreadCount{
+			ID:                      "feed/" + count.FeedUrl,
+			Count:                   count.Unread,
+			NewestItemTimestampUsec: usec(count.Newest),
+		})
+
+		tallies := []*tally{&total}
+		if count.Folder.Valid {
+			folder, ok := folders[count.Folder.String]
+			if !ok {
+				folder = &tally{}
+				folders[count.Folder.String] = folder
+				order = append(order, count.Folder.String)
+			}
+			tallies = append(tallies, folder)
+		}
+		for _, t := range tallies {
+			t.count += count.Unread
+			if count.Newest.After(t.newest) {
+				t.newest = count.Newest
+			}
+		}
+	}
+	for _, name := range order {
+		list = append(list, greaderUnreadCount{
+			ID:                      "user/-/label/" + name,
+			Count:                   folders[name].count,
+			NewestItemTimestampUsec: usec(folders[name].newest),
+		})
+	}
+	list = append(list, greaderUnreadCount{ID: stateReadingList, Count: total.count, NewestItemTimestampUsec: usec(total.newest)})
+
+	writeJSON(w, http.StatusOK, map[string]any{"max": total.count, "unreadcounts": list})
+}
+
+/*
+======================================================
+
+		Streams and items
+
+======================================================
+*/
+
+func greaderItemIDs(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	params, err := greaderStreamParams(r, user)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	refs, err := s.db.ListSyncPostRefs(r.Context(), database.ListSyncPostRefsParams{
+		UserID:      params.UserID,
+		FeedUrl:     params.FeedUrl,
+		Folder:      params.Folder,
+		UnreadOnly:  params.UnreadOnly,
+		ReadOnly:    params.ReadOnly,
+		StarredOnly: params.StarredOnly,
+		Since:       params.Since,
+		Until:       params.Until,
+		AfterSeq:    params.AfterSeq,
+		BeforeSeq:   params.BeforeSeq,
+		Ascending:   params.Ascending,
+		ItemLimit:   params.ItemLimit,
+	})
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error retrieving item ids: %v", err))
+		return
+	}
+
+	itemRefs := make([]greaderItemRef, 0, len(refs))
+	for _, ref := range refs {
+		itemRefs = append(itemRefs, greaderItemRef{
+			ID:              strconv.FormatInt(ref.Seq, 10),
+			DirectStreamIDs: []string{"feed/" + ref.FeedUrl},
+			TimestampUsec:   usec(ref.CreatedAt),
+		})
+	}
+
+	response := map[string]any{"itemRefs": itemRefs}
+	if len(refs) > 0 && len(refs) == int(params.ItemLimit) {
+		response["continuation"] = strconv.FormatInt(refs[len(refs)-1].Seq, 10)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func greaderItemContents(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	seqs, err := greaderItemSeqs(r.Form["i"])
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if len(seqs) == 0 {
+		writeAPIError(w, opErrorf(failInvalid, "i is required"))
+		return
+	}
+
+	posts, err := s.db.ListSyncPosts(r.Context(), database.ListSyncPostsParams{
+		UserID:    user.ID,
+		Seqs:      seqs,
+		ItemLimit: int32(len(seqs)),
+	})
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error retrieving items: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, greaderStreamResponse(stateReadingList, posts, ""))
+}
+
+func greaderStreamContents(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	if r.PathValue("stream") != "" {
+		r.Form.Set("s", r.PathValue("stream"))
+	}
+
+	params, err := greaderStreamParams(r, user)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	posts, err := s.db.ListSyncPosts(r.Context(), params)
+	if err != nil {
+		writeAPIError(w, fmt.Errorf("error retrieving items: %v", err))
+		return
+	}
+
+	var continuation string
+	if len(posts) > 0 && len(posts) == int(params.ItemLimit) {
+		continuation = strconv.FormatInt(posts[len(posts)-1].Seq, 10)
+	}
+	writeJSON(w, http.StatusOK, greaderStreamResponse(r.Form.Get("s"), posts, continuation))
+}
+
+// greaderEditTag marks items read, unread, starred or unstarred, labels on single
+// items aren't supported since gator only groups whole feeds
+func greaderEditTag(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	seqs, err := greaderItemSeqs(r.Form["i"])
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	apply := func(tag string, add bool) error {
+		tag = greaderNormaliseStream(tag)
+		var err error
+		switch {
+		case tag == stateRead && add, tag == stateKeptUnread && !add:
+			_, err = s.db.MarkPostsReadBySeq(r.Context(), database.MarkPostsReadBySeqParams{ReadAt: time.Now(), UserID: user.ID, Seqs: seqs})
+		case tag == stateRead, tag == stateKeptUnread:
+			_, err = s.db.MarkPostsUnreadBySeq(r.Context(), database.MarkPostsUnreadBySeqParams{UserID: user.ID, Seqs: seqs})
+		case tag == stateStarred && add:
+			_, err = s.db.StarPostsBySeq(r.Context(), database.StarPostsBySeqParams{StarredAt: time.Now(), UserID: user.ID, Seqs: seqs})
+		case tag == stateStarred:
+			_, err = s.db.UnstarPostsBySeq(r.Context(), database.UnstarPostsBySeqParams{UserID: user.ID, Seqs: seqs})
+		}
+		return err
+	}
+
+	for _, tag := range r.Form["a"] {
+		if err := apply(tag, true); err != nil {
+			writeAPIError(w, fmt.Errorf("error tagging items: %v", err))
+			return
+		}
+	}
+	for _, tag := range r.Form["r"] {
+		if err := apply(tag, false); err != nil {
+			writeAPIError(w, fmt.Errorf("error untagging items: %v", err))
+			return
+		}
+	}
+
+	greaderText(w, "OK")
+}
+
+func greaderMarkAllRead(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	stream, err := parseGReaderStream(r.Form.Get("s"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	params := database.MarkPostsReadBeforeParams{
+		ReadAt:  time.Now(),
+		UserID:  user.ID,
+		FeedUrl: stream.feedURL,
+		Folder:  stream.folder,
+		Before:  time.Now(),
+	}
+	if ts, err := strconv.ParseInt(r.Form.Get("ts"), 10, 64); err == nil && ts > 0 {
+		params.Before = time.UnixMicro(ts).UTC()
+	}
+
+	if _, err := s.db.MarkPostsReadBefore(r.Context(), params); err != nil {
+		writeAPIError(w, fmt.Errorf("error marking posts as read: %v", err))
+		return
+	}
+
+	greaderText(w, "OK")
+}
+
+// greaderStreamParams reads the stream and paging parameters shared by the
+// stream/contents and stream/items/ids endpoints
+func greaderStreamParams(r *http.Request, user database.User) (database.ListSyncPostsParams, error) {
+	stream, err := parseGReaderStream(r.Form.Get("s"))
+	if err != nil {
+		return database.ListSyncPostsParams{}, err
+	}
+
+	params := database.ListSyncPostsParams{
+		UserID:      user.ID,
+		FeedUrl:     stream.feedURL,
+		Folder:      stream.folder,
+		ReadOnly:    stream.read,
+		StarredOnly: stream.starred,
+		Ascending:   r.Form.Get("r") == "o",
+		ItemLimit:   defaultGreaderLimit,
+	}
+
+	if n := r.Form.Get("n"); n != "" {
+		limit, err := strconv.Atoi(n)
+		if err != nil || limit < 1 {
+			return database.ListSyncPostsParams{}, opErrorf(failInvalid, "invalid n %v", n)
+		}
+		params.ItemLimit = int32(min(limit, maxGreaderLimit))
+	}
+
+	for _, excluded := range r.Form["xt"] {
+		if greaderNormaliseStream(excluded) == stateRead {
+			params.UnreadOnly = true
+		}
+	}
+	if greaderNormaliseStream(r.Form.Get("it")) == stateStarred {
+		params.StarredOnly = true
+	}
+
+	if ot, err := strconv.ParseInt(r.Form.Get("ot"), 10, 64); err == nil {
+		params.Since = sql.NullTime{Time: time.Unix(ot, 0).UTC(), Valid: true}
+	}
+	if nt, err := strconv.ParseInt(r.Form.Get("nt"), 10, 64); err == nil {
+		params.Until = sql.NullTime{Time: time.Unix(nt, 0).UTC(), Valid: true}
+	}
+
+	if c := r.Form.Get("c"); c != "" {
+		seq, err := strconv.ParseInt(c, 10, 64)
+		if err != nil {
+			return database.ListSyncPostsParams{}, opErrorf(failInvalid, "invalid continuation %v", c)
+		}
+		if params.Ascending {
+			params.AfterSeq = sql.NullInt64{Int64: seq, Valid: true}
+		} else {
+			params.BeforeSeq = sql.NullInt64{Int64: seq, Valid: true}
+		}
+	}
+
+	return params, nil
+}
+
+func parseGReaderStream(id string) (greaderStream, error) {
+	id = greaderNormaliseStream(id)
+	stream := greaderStream{id: id}
+
+	switch {
+	case id == "" || id == stateReadingList:
+	case id == stateRead:
+		stream.read = true
+	case id == stateStarred:
+		stream.starred = true
+	case strings.HasPrefix(id, "feed/"):
+		stream.feedURL = nullString(strings.TrimPrefix(id, "feed/"))
+	case strings.HasPrefix(id, "user/-/label/"):
+		stream.folder = nullString(strings.TrimPrefix(id, "user/-/label/"))
+	default:
+		return greaderStream{}, opErrorf(failInvalid, "unsupported stream %v", id)
+	}
+
+	return stream, nil
+}
+
+// greaderNormaliseStream replaces the user id in user streams with -
+func greaderNormaliseStream(id string) string {
+	if match := greaderUserStream.FindStringSubmatch(id); match != nil {
+		return "user/-/" + match[1] + "/" + match[2]
+	}
+	return id
+}
+
+// item ids come either as the long hex form or as the decimal numbers from itemRefs
+func greaderItemSeqs(ids []string) ([]int64, error) {
+	seqs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		var seq int64
+		var err error
+		if hexID, found := strings.CutPrefix(id, greaderItemPrefix); found {
+			var unsigned uint64
+			unsigned, err = strconv.ParseUint(hexID, 16, 64)
+			seq = int64(unsigned)
+		} else {
+			seq, err = strconv.ParseInt(id, 10, 64)
+		}
+		if err != nil {
+			return nil, opErrorf(failInvalid, "invalid item id %v", id)
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs, nil
+}
+
+func greaderStreamResponse(streamID string, posts []database.ListSyncPostsRow, continuation string) map[string]any {
+	items := make([]greaderItem, 0, len(posts))
+	var updated int64
+	for _, post := range posts {
+		published := post.CreatedAt
+		if post.PublishedAt.Valid {
+			published = post.PublishedAt.Time
+		}
+		updated = max(updated, post.UpdatedAt.Unix())
+
+		body := post.Description.String
+		if post.Content.Valid && post.Content.String != "" {
+			body = post.Content.String
+		}
+
+		categories := []string{stateReadingList, "user/-/state/com.google/fresh"}
+		if post.ReadAt.Valid {
+			categories = append(categories, stateRead)
+		}
+		if post.StarredAt.Valid {
+			categories = append(categories, stateStarred)
+		}
+		if post.Folder.Valid {
+			categories = append(categories, "user/-/label/"+post.Folder.String)
+		}
+
+		items = append(items, greaderItem{
+			ID:            fmt.Sprintf("%v%016x", greaderItemPrefix, post.Seq),
+			CrawlTimeMsec: strconv.FormatInt(post.CreatedAt.UnixMilli(), 10),
+			TimestampUsec: usec(post.CreatedAt),
+			Published:     published.Unix(),
+			Updated:       post.UpdatedAt.Unix(),
+			Title:         post.Title.String,
+			Canonical:     []greaderLink{{Href: post.Url}},
+			Alternate:     []greaderLink{{Href: post.Url, Type: "text/html"}},
+			Summary:       greaderContent{Direction: "ltr", Content: body},
+			Author:        post.Author.String,
+			Categories:    categories,
+			Origin: greaderOrigin{
+				StreamID: "feed/" + post.FeedUrl,
+				Title:    post.FeedName,
+				HTMLURL:  post.FeedSiteUrl.String,
+			},
+		})
+	}
+
+	response := map[string]any{
+		"direction": "ltr",
+		"id":        streamID,
+		"updated":   updated,
+		"items":     items,
+	}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+	return response
+}
+
+func greaderText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, text)
+}
+
+func usec(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
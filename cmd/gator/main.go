@@ -3,16 +3,19 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"github.com/Andrew-The-Cat/gator/internal/config"
 	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/readability"
+	"github.com/Andrew-The-Cat/gator/internal/render"
+	"github.com/Andrew-The-Cat/gator/internal/rss"
+	"github.com/google/uuid"
 	"os"
+	"os/exec"
 	"strconv"
 	"strings"
 	"time"
-	"github.com/Andrew-The-Cat/gator/internal/rss"
-	"github.com/google/uuid"
-	_ "github.com/lib/pq"
 )
 
 /*
@@ -24,8 +27,13 @@ import (
 */
 
 type state struct {
-	cfg *config.Config
-	db  *database.Queries
+	cfg  *config.Config
+	db   database.Store
+	conn *sql.DB
+	// the kind of database db_url points to
+	backend backend
+	// format listings are printed in, one of outputFormats
+	output string
 }
 
 type command struct {
@@ -38,13 +46,58 @@ type commands struct {
 }
 
 func (c *commands) run(s *state, cmd command) error {
-	return c.callback[cmd.name](s, cmd)
+	handler, ok := c.callback[cmd.name]
+	if !ok {
+		return fmt.Errorf("unknown command %v", cmd.name)
+	}
+	return handler(s, cmd)
 }
 
 func (c *commands) register(name string, f func(*state, command) error) {
 	c.callback[name] = f
 }
 
+// newCommands registers every command gator knows
+func newCommands() commands {
+	cmds := commands{
+		make(map[string]func(*state, command) error),
+	}
+
+	cmds.register("login", handlerLogin)
+	cmds.register("logout", handlerLogout)
+	cmds.register("password", middlewareLoggedIn(handlerPassword))
+	cmds.register("register", handlerRegister)
+	cmds.register("reset", handlerReset)
+	cmds.register("users", handlerUsers)
+	cmds.register("agg", handlerAgg)
+	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
+	cmds.register("feeds", handlerFeeds)
+	cmds.register("fullcontent", middlewareLoggedIn(handlerFullContent))
+	cmds.register("follow", middlewareLoggedIn(handlerFollow))
+	cmds.register("following", middlewareLoggedIn(handlerFollowing))
+	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	cmds.register("title", middlewareLoggedIn(handlerTitle))
+	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("read", middlewareLoggedIn(handlerRead))
+	cmds.register("star", middlewareLoggedIn(handlerStar))
+	cmds.register("unstar", middlewareLoggedIn(handlerUnstar))
+	cmds.register("tag", middlewareLoggedIn(handlerTag))
+	cmds.register("untag", middlewareLoggedIn(handlerUntag))
+	cmds.register("import", middlewareLoggedIn(handlerImport))
+	cmds.register("export", middlewareLoggedIn(handlerExport))
+	cmds.register("feedtoken", middlewareLoggedIn(handlerFeedToken))
+	cmds.register("webhooks", middlewareLoggedIn(handlerWebhooks))
+	cmds.register("apipassword", middlewareLoggedIn(handlerAPIPassword))
+	cmds.register("digest", middlewareLoggedIn(handlerDigest))
+	cmds.register("rules", middlewareLoggedIn(handlerRules))
+	cmds.register("serve", handlerServe)
+	cmds.register("migrate", handlerMigrate)
+	cmds.register("retention", handlerRetention)
+	cmds.register("prune", handlerPrune)
+	cmds.register("config", handlerConfig)
+	return cmds
+}
+
 /*
 ======================================================
 
@@ -53,135 +106,112 @@ func (c *commands) register(name string, f func(*state, command) error) {
 ======================================================
 */
 
-func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("login command requires a username")
-	}
-
-	_, err := s.db.GetUser(context.Background(), cmd.args[0])
+func handlerRegister(s *state, cmd command) error {
+	flags := flag.NewFlagSet("register", flag.ContinueOnError)
+	withPassword := flags.Bool("password", false, "protect the user with a password, asked for when logging in")
 
-	if err != nil {
-		return fmt.Errorf("user not found")
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires a username")
 	}
 
-	err = s.cfg.SetUser(cmd.args[0])
-	if err != nil {
-		return fmt.Errorf("received an error when trying to update username: %v", err)
+	var hash sql.NullString
+	if *withPassword {
+		var err error
+		hash, err = readNewPassword()
+		if err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("User has been successfuly set")
-	return nil
-}
+	var user database.User
+	var session *config.Session
+	err := withTx(context.Background(), s, func(db database.Store) error {
+		var err error
+		user, err = registerUser(context.Background(), db, flags.Arg(0))
+		if err != nil || !hash.Valid {
+			return err
+		}
 
-func handlerRegister(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("command requires a username")
-	}
+		err = db.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hash,
+			UpdatedAt:    user.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("error setting password: %v", err)
+		}
 
-	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		Name: cmd.args[0],
+		session, err = newSession(context.Background(), db, user, defaultSessionLifetime)
+		return err
 	})
-
 	if err != nil {
-		return fmt.Errorf("an unexpected error occured when creating user: %v", err)
+		return err
 	}
 
-	s.cfg.SetUser(cmd.args[0])
+	s.cfg.SetSession(user.Name, session)
 	fmt.Println("Successfuly created user:")
 	fmt.Printf("\tID: %v | created_at: %v | updated_at: %v | name: %v\n", user.ID, user.CreatedAt, user.UpdatedAt.Time, user.Name)
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	err := s.db.UsersReset(context.Background())
-	if err != nil {
-		return fmt.Errorf("couldn't delete users: %v", err)
+// handlerAgg fetches feeds until it's stopped, the profile's fetch settings fill in
+// the interval when it isn't given
+func handlerAgg(s *state, cmd command) error {
+	fetch := config.Fetch{}
+	if s.cfg.Fetch != nil {
+		fetch = *s.cfg.Fetch
 	}
-
-	err = s.db.FeedsReset(context.Background())
-	if err != nil {
-		return fmt.Errorf("couldn't delete feeds: %v", err)
+	if len(cmd.args) == 1 {
+		fetch.Interval = cmd.args[0]
+	}
+	if len(cmd.args) > 1 || fetch.Interval == "" {
+		return fmt.Errorf("command requires a time between requests given in the format (1-9)[s|m|h], or fetch.interval in the config")
 	}
 
-	err = s.db.FeedFollowsReset(context.Background())
+	dur, err := time.ParseDuration(fetch.Interval)
 	if err != nil {
-		return fmt.Errorf("couldn't delete feed followdL: %v", err)
+		return fmt.Errorf("error occured when trying to parse duration: %v", err)
 	}
-
-	return nil
-}
-
-func handlerAgg(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("command requires a time between requests given in the format (1-9)[s|m|h]")
+	if dur <= 0 {
+		return fmt.Errorf("the time between requests must be positive")
 	}
 
-	dur, err := time.ParseDuration(cmd.args[0])
-	if err != nil {
-		return fmt.Errorf("error occured when trying to parse duration: %v", err)
+	var timeout time.Duration
+	if fetch.Timeout != "" {
+		timeout, err = time.ParseDuration(fetch.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("fetch.timeout in the config must be a positive duration like 30s")
+		}
 	}
 
 	fmt.Printf("Attempting to collect feeds every %v\n", dur)
 
 	ticker := time.NewTicker(dur)
 	for ; ; <-ticker.C {
-		err := scrapeFeeds(s)
+		retryWebhooks(s)
+		err := scrapeFeeds(s, timeout)
 		if err != nil {
 			fmt.Printf("\twarning: %v\n", err)
 		}
 	}
 }
 
-func handlerUsers(s *state, cmd command) error {
-	users, err := s.db.GetUsers(context.Background())
-	if err != nil {
-		return err
-	}
-
-	for _, user := range users {
-		fmt.Printf("* %v", user.Name)
-		if user.Name == s.cfg.User_name {
-			fmt.Printf(" (current)")
-		}
-
-		fmt.Print("\n")
-	}
-
-	return nil
-}
-
 func handlerAddFeed(s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 2 {
 		return fmt.Errorf("command requires a name and a url")
 	}
 
-	res, err := s.db.AddFeed(context.Background(), database.AddFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      cmd.args[0],
-		Url:       cmd.args[1],
-		UserID:    user.ID,
-	})
-	if err != nil {
-		return fmt.Errorf("error adding the feed to the database: %v", err)
-	}
-
-	_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user.ID,
-		FeedID:    res.ID,
+	var res database.Feed
+	err := withTx(context.Background(), s, func(db database.Store) error {
+		var err error
+		res, err = addFeed(context.Background(), db, user, cmd.args[0], cmd.args[1])
+		return err
 	})
 	if err != nil {
-		return fmt.Errorf("error creating feed follow: %v", err)
+		return err
 	}
 
 	fmt.Println("Successfuly added feed:")
@@ -189,16 +219,29 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFeeds(s *state, cmd command) error {
-	data, err := s.db.GetFeeds(context.Background())
+func handlerFullContent(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 || (cmd.args[1] != "on" && cmd.args[1] != "off") {
+		return fmt.Errorf("command requires the url of a feed followed by on or off")
+	}
+
+	feed, err := getOwnedFeed(s.db, user, cmd.args[0], "change full content fetching for")
 	if err != nil {
-		return fmt.Errorf("error retrieving feeds: %v", err)
+		return err
 	}
 
-	for _, row := range data {
-		fmt.Printf(" * %v - %v: %v\n", row.UserName, row.Name, row.Url)
+	affected, err := s.db.SetFeedFullContent(context.Background(), database.SetFeedFullContentParams{
+		Url:              feed.Url,
+		FetchFullContent: cmd.args[1] == "on",
+		UpdatedAt:        time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("no feed found at %v", cmd.args[0])
 	}
 
+	fmt.Printf("Full content fetching for %v is now %v\n", cmd.args[0], cmd.args[1])
 	return nil
 }
 
@@ -207,21 +250,9 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("command requires the url of the feed you want to follow")
 	}
 
-	targetFeed, err := s.db.GetFeedByUrl(context.Background(), cmd.args[0])
+	response, err := followFeed(context.Background(), s.db, user, cmd.args[0])
 	if err != nil {
-		return fmt.Errorf("error retrieving requested feed: %v", err)
-	}
-
-	response, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user.ID,
-		FeedID:    targetFeed.ID,
-	})
-
-	if err != nil {
-		return fmt.Errorf("error creating feed follow: %v", err)
+		return err
 	}
 
 	fmt.Printf("Successfuly followed feed for user %v:\n", s.cfg.User_name)
@@ -235,10 +266,21 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("error retrieving feed follows: %v", err)
 	}
 
+	records := make([]followRecord, 0, len(data))
 	for _, row := range data {
-		fmt.Printf(" * %v: %v\n", row.FeedName, row.FeedUrl)
-	}
-	return nil
+		records = append(records, followRecord{
+			Name:    row.FeedName,
+			URL:     row.FeedUrl,
+			SiteURL: row.SiteUrl.String,
+			Folder:  row.Folder.String,
+		})
+	}
+
+	return printList(s, records, []column[followRecord]{
+		{"name", func(r followRecord) string { return r.Name }},
+		{"url", func(r followRecord) string { return r.URL }},
+		{"folder", func(r followRecord) string { return r.Folder }},
+	})
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
@@ -246,43 +288,272 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("command requires the url of the feed you want to unfollow")
 	}
 
-	err := s.db.DeleteFeedFollowForUser(context.Background(), database.DeleteFeedFollowForUserParams{
-		UserID: user.ID,
-		Url:    cmd.args[0],
-	})
+	err := unfollowFeed(context.Background(), s.db, user, cmd.args[0])
 	if err != nil {
-		return fmt.Errorf("error deleting feed follow: %v", err)
+		return err
 	}
 
 	fmt.Println("Successfully unfollowed feed")
 	return nil
 }
 
+func handlerTitle(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 || len(cmd.args) > 2 {
+		return fmt.Errorf("command requires the url of a followed feed and optionally a new title")
+	}
+
+	var title sql.NullString
+	if len(cmd.args) == 2 {
+		title.Scan(cmd.args[1])
+	}
+
+	err := setFollowTitle(context.Background(), s.db, user, cmd.args[0], title)
+	if err != nil {
+		return err
+	}
+
+	if title.Valid {
+		fmt.Printf("Feed at %v will now be shown as %v\n", cmd.args[0], title.String)
+	} else {
+		fmt.Printf("Feed at %v will now be shown under its original name\n", cmd.args[0])
+	}
+	return nil
+}
+
 func handlerBrowse(s *state, cmd command, user database.User) error {
-	params := database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  2,
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	offset := flags.Int("offset", 0, "number of posts to skip")
+	cursor := flags.String("cursor", "", "continue from the cursor printed by a previous browse")
+	feed := flags.String("feed", "", "only show posts from the feed with this url or name")
+	since := flags.String("since", "", "only show posts from this date onwards (YYYY-MM-DD or RFC3339)")
+	until := flags.String("until", "", "only show posts from before this date (YYYY-MM-DD or RFC3339)")
+	sortBy := flags.String("sort", "fetched", "order posts by their [published|fetched] time")
+	ascending := flags.Bool("asc", false, "show the oldest posts first")
+	unread := flags.Bool("unread", false, "only show posts you haven't read yet")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
 	}
-	if len(cmd.args) == 1 {
-		to_int, err := strconv.Atoi(cmd.args[0])
+
+	opts := browseOptions{
+		limit:     2,
+		offset:    *offset,
+		cursor:    *cursor,
+		feed:      *feed,
+		since:     *since,
+		until:     *until,
+		sortBy:    *sortBy,
+		ascending: *ascending,
+		unread:    *unread,
+	}
+
+	if len(flags.Args()) > 1 {
+		return fmt.Errorf("command takes at most one limit after its flags")
+	}
+	if len(flags.Args()) == 1 {
+		to_int, err := strconv.Atoi(flags.Arg(0))
 		if err != nil {
 			return fmt.Errorf("error when parsing limit: %v", err)
 		}
 
-		params.Limit = int32(to_int)
+		opts.limit = to_int
 	}
 
-	res, err := s.db.GetPostsForUser(context.Background(), params)
+	res, next, err := browsePosts(context.Background(), s.db, user, opts)
 	if err != nil {
-		return fmt.Errorf("error when retrieving posts: %v", err)
+		return err
+	}
+
+	var cursorHint string
+	if next != "" {
+		cursorHint = fmt.Sprintf("More posts available, continue with --cursor %v", next)
 	}
 
+	if s.output != outputTable {
+		records := make([]postRecord, 0, len(res))
+		for _, item := range res {
+			records = append(records, newPostRecord(item))
+		}
+
+		err = printList(s, records, []column[postRecord]{
+			{"id", func(r postRecord) string { return r.ID }},
+			{"feed", func(r postRecord) string { return r.Feed }},
+			{"title", func(r postRecord) string { return r.Title }},
+			{"published_at", func(r postRecord) string { return formatTime(r.PublishedAt) }},
+			{"url", func(r postRecord) string { return r.URL }},
+		})
+		if err != nil {
+			return err
+		}
+
+		// keep stdout parseable, the hint is only for whoever is running the script
+		if cursorHint != "" {
+			fmt.Fprintln(os.Stderr, cursorHint)
+		}
+		return nil
+	}
+
+	// descriptions are indented by two tabs, which terminals render as 16 columns
+	width := render.TerminalWidth() - 16
 	for _, item := range res {
-		fmt.Printf("\t*\t%v (%v) - \n\t\t%v\n\n", item.Title.String, item.PublishedAt.Time, item.Description.String)
+		fmt.Printf("\t*\t[%v] %v (%v) - \n", item.FeedName, item.Title.String, item.PublishedAt.Time)
+		fmt.Printf("\t\tid: %v\n", item.ID)
+		fmt.Printf("%v\n\n", render.Indent(render.HTMLToText(item.Description.String, width), "\t\t"))
+	}
+
+	if cursorHint != "" {
+		fmt.Println(cursorHint)
 	}
 	return nil
 }
 
+func handlerRead(s *state, cmd command, user database.User) error {
+	flags := flag.NewFlagSet("read", flag.ContinueOnError)
+	pager := flags.Bool("pager", false, "show the post through $PAGER")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires the id of the post you want to read")
+	}
+
+	postID, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid post id: %v", err)
+	}
+
+	post, err := getPost(context.Background(), s.db, user, postID)
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%v\n\n", post.Title.String)
+	fmt.Fprintf(&out, "Feed:      %v (%v)\n", post.FeedName, post.FeedUrl)
+	if post.Author.Valid && post.Author.String != "" {
+		fmt.Fprintf(&out, "Author:    %v\n", post.Author.String)
+	}
+	if post.PublishedAt.Valid {
+		fmt.Fprintf(&out, "Published: %v\n", post.PublishedAt.Time)
+	}
+	fmt.Fprintf(&out, "Fetched:   %v\n", post.CreatedAt)
+	fmt.Fprintf(&out, "URL:       %v\n\n", post.Url)
+
+	body := post.Description.String
+	if post.Content.Valid && post.Content.String != "" {
+		body = post.Content.String
+	}
+	fmt.Fprintf(&out, "%v\n", render.HTMLToText(body, render.TerminalWidth()))
+
+	if *pager {
+		err = showInPager(out.String())
+		if err != nil {
+			return fmt.Errorf("error running pager: %v", err)
+		}
+	} else {
+		fmt.Print(out.String())
+	}
+
+	return markPostRead(context.Background(), s.db, user, post.ID)
+}
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("command requires the id of the post you want to star")
+	}
+
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %v", err)
+	}
+
+	err = starPost(context.Background(), s.db, user, postID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Post has been starred")
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("command requires the id of the post you want to unstar")
+	}
+
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %v", err)
+	}
+
+	starred, err := unstarPost(context.Background(), s.db, user, postID)
+	if err != nil {
+		return err
+	}
+	if !starred {
+		return fmt.Errorf("post %v isn't starred", postID)
+	}
+
+	fmt.Println("Post has been unstarred")
+	return nil
+}
+
+func handlerTag(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("command requires the id of a post and the tag to give it")
+	}
+
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %v", err)
+	}
+
+	_, err = getPost(context.Background(), s.db, user, postID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.TagPost(context.Background(), database.TagPostParams{
+		UserID:    user.ID,
+		PostID:    postID,
+		Tag:       cmd.args[1],
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error tagging post: %v", err)
+	}
+
+	fmt.Printf("Post has been tagged %v\n", cmd.args[1])
+	return nil
+}
+
+func handlerUntag(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("command requires the id of a post and the tag to remove")
+	}
+
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %v", err)
+	}
+
+	affected, err := s.db.UntagPost(context.Background(), database.UntagPostParams{
+		UserID: user.ID,
+		PostID: postID,
+		Tag:    cmd.args[1],
+	})
+	if err != nil {
+		return fmt.Errorf("error removing tag: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("post %v isn't tagged %v", postID, cmd.args[1])
+	}
+
+	fmt.Printf("Tag %v has been removed\n", cmd.args[1])
+	return nil
+}
+
 /*
 ======================================================
 
@@ -297,12 +568,75 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 		if err != nil {
 			return fmt.Errorf("error retrieving current user: %v", err)
 		}
+		if err := checkSession(s, user); err != nil {
+			return err
+		}
 
 		return handler(s, cmd, user)
 	}
 }
 
-func scrapeFeeds(s *state) error {
+func showInPager(text string) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+
+	// run through the shell so PAGER can carry its own arguments, e.g. "less -R"
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func parseDateFlag(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return sql.NullTime{Time: parsed.UTC(), Valid: true}, nil
+		}
+	}
+
+	return sql.NullTime{}, fmt.Errorf("expected a date in the format YYYY-MM-DD or RFC3339, got %v", value)
+}
+
+// cursors are the sort key and id of the last post shown, which is enough to resume
+// a listing even if new posts have been fetched in the meantime
+func encodeCursor(key time.Time, id uuid.UUID) string {
+	return fmt.Sprintf("%d_%v", key.UnixMicro(), id)
+}
+
+func decodeCursor(cursor string) (sql.NullTime, uuid.NullUUID, error) {
+	if cursor == "" {
+		return sql.NullTime{}, uuid.NullUUID{}, nil
+	}
+
+	key, id, found := strings.Cut(cursor, "_")
+	if !found {
+		return sql.NullTime{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor %v", cursor)
+	}
+
+	micros, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor %v", cursor)
+	}
+
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor %v", cursor)
+	}
+
+	return sql.NullTime{Time: time.UnixMicro(micros).UTC(), Valid: true}, uuid.NullUUID{UUID: parsedID, Valid: true}, nil
+}
+
+// scrapeFeeds fetches the feed that's waited longest, a timeout of 0 waits as long
+// as the feed takes
+func scrapeFeeds(s *state, timeout time.Duration) error {
 	feed, err := s.db.GetNextFeedToFetch(context.Background())
 	if err != nil {
 		return err
@@ -321,12 +655,31 @@ func scrapeFeeds(s *state) error {
 
 	fmt.Printf("Attempting to fetch feed at %v\n", res.Url)
 
-	fetched_items, err := rss.FetchFeed(context.Background(), res.Url)
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	fetched_items, err := rss.FetchFeed(ctx, res.Url)
 	if err != nil {
 		return err
 	}
 
+	if link := fetched_items.Channel.Link; link != "" && link != feed.SiteUrl.String {
+		err = s.db.SetFeedSiteUrl(context.Background(), database.SetFeedSiteUrlParams{
+			ID:      feed.ID,
+			SiteUrl: sql.NullString{String: link, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	fetched_items.PrintFeed()
+	var created []database.Post
+	categories := map[uuid.UUID][]string{}
 	for _, item := range fetched_items.Channel.Item {
 		params := database.CreatePostParams{
 			ID:        uuid.New(),
@@ -338,18 +691,104 @@ func scrapeFeeds(s *state) error {
 
 		params.Title.Scan(item.Title)
 		params.Description.Scan(item.Description)
-		params.PublishedAt.Scan(item.PubDate)
+		if author := item.AuthorName(); author != "" {
+			params.Author.Scan(author)
+		}
+		if item.Content != "" {
+			params.Content.Scan(item.Content)
+		}
+		if published, err := rss.ParseDate(item.PubDate); err == nil {
+			params.PublishedAt.Scan(published)
+		}
 
-		_, err := s.db.CreatePost(context.Background(), params)
+		pruned, err := s.db.PostWasPruned(context.Background(), item.Link)
+		if err != nil {
+			return err
+		}
+		if pruned {
+			continue
+		}
 
-		if err != nil && !strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		// a post and its categories are stored together, otherwise a post whose categories
+		// failed would be skipped as a duplicate on the next fetch and never get them
+		var post database.Post
+		err = withTx(context.Background(), s, func(db database.Store) error {
+			var err error
+			post, err = db.CreatePost(context.Background(), params)
+			if err != nil {
+				return err
+			}
+
+			for _, category := range item.Categories {
+				if category == "" {
+					continue
+				}
+				err = db.CreatePostCategory(context.Background(), database.CreatePostCategoryParams{
+					PostID:   post.ID,
+					Category: category,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil && !isUniqueViolation(err) {
 			return err
 		}
+
+		if err == nil {
+			if feed.FetchFullContent {
+				fetchFullContent(s, &post)
+			}
+			for _, category := range item.Categories {
+				if category != "" {
+					categories[post.ID] = append(categories[post.ID], category)
+				}
+			}
+			created = append(created, post)
+		}
 	}
 
+	hidden := applyRulesToNewPosts(s, feed, created, categories)
+	notifyWebhooks(s, feed, created, hidden)
+
+	removed, err := pruneFeed(context.Background(), s, feed, false)
+	if err != nil {
+		fmt.Printf("\twarning: couldn't prune old posts: %v\n", err)
+	} else if removed > 0 {
+		fmt.Printf("Pruned %v old post(s)\n", removed)
+	}
 	return nil
 }
 
+// fetchFullContent replaces a post's content with the article found at its link, keeping
+// whatever the feed provided when the page can't be fetched or doesn't look like an article
+func fetchFullContent(s *state, post *database.Post) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	article, err := readability.FetchArticle(ctx, post.Url)
+	if err != nil {
+		fmt.Printf("\twarning: couldn't fetch full content of %v: %v\n", post.Url, err)
+		return
+	}
+
+	params := database.SetPostContentParams{
+		ID:        post.ID,
+		UpdatedAt: time.Now(),
+	}
+	params.Content.Scan(article)
+
+	err = s.db.SetPostContent(context.Background(), params)
+	if err != nil {
+		fmt.Printf("\twarning: couldn't store full content of %v: %v\n", post.Url, err)
+		return
+	}
+	post.Content = params.Content
+}
+
 /*
 ======================================================
 
@@ -363,55 +802,65 @@ func main() {
 	var configs config.Config
 	var err error
 
+	//		global flags
+	global := flag.NewFlagSet("gator", flag.ContinueOnError)
+	output := global.String("output", outputTable, "how listings are printed: "+strings.Join(outputFormats, ", "))
+	profile := global.String("profile", "", "config profile to use, overrides GATOR_PROFILE")
+
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(1)
+	}
+
+	if !validOutput(*output) {
+		fmt.Printf("Unknown output format %v, expected one of %v\n", *output, strings.Join(outputFormats, ", "))
+		os.Exit(1)
+	}
+
+	if *profile == "" {
+		*profile = os.Getenv("GATOR_PROFILE")
+	}
+
 	//		db connection
 	{
-		configs, err = config.Read()
+		configs, err = config.Read(*profile)
 		if err != nil {
 			fmt.Printf("Unexpected error occured when reading config file: %v\n", err)
+			// carrying on with another profile's database would be worse than stopping
+			if *profile != "" {
+				os.Exit(1)
+			}
 		}
 
 		running_state = state{
-			cfg: &configs,
+			cfg:    &configs,
+			output: *output,
 		}
 
-		db, err := sql.Open("postgres", running_state.cfg.Conn_str)
+		db, store, backend, err := openDatabase(running_state.cfg.Conn_str)
 		if err != nil {
 			fmt.Printf("Unexpected error occured when connecting to db: %v\n", err)
 		}
 
-		dbQueries := database.New(db)
-		running_state.db = dbQueries
+		running_state.db = store
+		running_state.conn = db
+		running_state.backend = backend
 	}
 
 	//		input handling
 	{
-		cmds := commands{
-			make(map[string]func(*state, command) error),
-		}
+		cmds := newCommands()
 
-		cmds.register("login", handlerLogin)
-		cmds.register("register", handlerRegister)
-		cmds.register("reset", handlerReset)
-		cmds.register("users", handlerUsers)
-		cmds.register("agg", handlerAgg)
-		cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-		cmds.register("feeds", handlerFeeds)
-		cmds.register("follow", middlewareLoggedIn(handlerFollow))
-		cmds.register("following", middlewareLoggedIn(handlerFollowing))
-		cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-		cmds.register("browse", middlewareLoggedIn(handlerBrowse))
-
-		args := os.Args
-		if len(args) < 2 {
+		args := global.Args()
+		if len(args) < 1 {
 			fmt.Println("Too few arguments were given")
 			os.Exit(1)
 		}
 
-		cmd_name := args[1]
+		cmd_name := args[0]
 		var cmd_args []string = make([]string, 0)
 
-		if len(args) > 2 {
-			cmd_args = args[2:]
+		if len(args) > 1 {
+			cmd_args = args[1:]
 		}
 
 		cmd := command{
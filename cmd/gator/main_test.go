@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/config"
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/sqlite"
+	"github.com/google/uuid"
+)
+
+// newTestState runs gator against a fresh in-memory database, with HOME pointed at
+// a temporary directory so the config file handlers write doesn't touch the real one
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GATOR_PROFILE", "")
+
+	conn, db, err := sqlite.OpenMemory(context.Background())
+	if err != nil {
+		t.Fatalf("opening in-memory store: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	cfg := config.Config{Profile: config.DefaultProfile}
+	return &state{
+		cfg:     &cfg,
+		db:      db,
+		conn:    conn,
+		backend: sqliteBackend,
+		output:  outputTable,
+	}
+}
+
+// runOutput executes a command the way main does and returns what it printed.
+// Handlers save changes to the config file rather than to s.cfg, so it's read back
+// afterwards
+func runOutput(t *testing.T, s *state, args ...string) (string, string, error) {
+	t.Helper()
+
+	var err error
+	stdout, stderr := capture(t, func() {
+		cmds := newCommands()
+		err = cmds.run(s, command{name: args[0], args: args[1:]})
+	})
+
+	if cfg, readErr := config.Read(s.cfg.Profile); readErr == nil {
+		*s.cfg = cfg
+	}
+	return stdout, stderr, err
+}
+
+// run executes a command, its output only shows up in the log of a failed test
+func run(t *testing.T, s *state, args ...string) error {
+	t.Helper()
+	stdout, stderr, err := runOutput(t, s, args...)
+	t.Logf("gator %v\n%v%v", strings.Join(args, " "), stdout, stderr)
+	return err
+}
+
+func mustRun(t *testing.T, s *state, args ...string) {
+	t.Helper()
+	if err := run(t, s, args...); err != nil {
+		t.Fatalf("%v: %v", strings.Join(args, " "), err)
+	}
+}
+
+// withInput feeds prompts the given lines instead of the terminal
+func withInput(t *testing.T, lines ...string) {
+	t.Helper()
+	previous := stdin
+	stdin = bufio.NewReader(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	t.Cleanup(func() { stdin = previous })
+}
+
+// capture returns what fn printed to stdout and stderr
+func capture(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+
+	read := func(target **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("creating pipe: %v", err)
+		}
+		previous := *target
+		*target = w
+
+		done := make(chan string)
+		go func() {
+			var buf bytes.Buffer
+			io.Copy(&buf, r)
+			done <- buf.String()
+		}()
+
+		return func() string {
+			w.Close()
+			*target = previous
+			return <-done
+		}
+	}
+
+	stdout := read(&os.Stdout)
+	stderr := read(&os.Stderr)
+	fn()
+	return stdout(), stderr()
+}
+
+func mustGetUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	user, err := s.db.GetUser(context.Background(), name)
+	if err != nil {
+		t.Fatalf("retrieving user %v: %v", name, err)
+	}
+	return user
+}
+
+func mustGetFeed(t *testing.T, s *state, feedURL string) database.Feed {
+	t.Helper()
+	feed, err := s.db.GetFeedByUrl(context.Background(), feedURL)
+	if err != nil {
+		t.Fatalf("retrieving feed %v: %v", feedURL, err)
+	}
+	return feed
+}
+
+// addPosts stores n posts in a feed, each fetched an hour after the one before
+func addPosts(t *testing.T, s *state, feed database.Feed, n int) []database.Post {
+	t.Helper()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	posts := make([]database.Post, 0, n)
+	for i := 0; i < n; i++ {
+		created := start.Add(time.Duration(i) * time.Hour)
+		post, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+			ID:          uuid.New(),
+			CreatedAt:   created,
+			UpdatedAt:   created,
+			Title:       sql.NullString{String: fmt.Sprintf("post %v", i), Valid: true},
+			Url:         fmt.Sprintf("%v/posts/%v", feed.Url, i),
+			Description: sql.NullString{String: "description", Valid: true},
+			PublishedAt: sql.NullTime{Time: created, Valid: true},
+			FeedID:      feed.ID,
+		})
+		if err != nil {
+			t.Fatalf("creating post: %v", err)
+		}
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+func followedURLs(t *testing.T, s *state, user database.User) []string {
+	t.Helper()
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("retrieving follows: %v", err)
+	}
+	urls := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		urls = append(urls, follow.FeedUrl)
+	}
+	return urls
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestState(t)
+
+	mustRun(t, s, "register", "alice")
+	mustGetUser(t, s, "alice")
+	if s.cfg.User_name != "alice" {
+		t.Fatalf("registering should log in as alice, logged in as %q", s.cfg.User_name)
+	}
+
+	if err := run(t, s, "register", "alice"); err == nil {
+		t.Fatal("registering alice twice should fail")
+	}
+	if err := run(t, s, "register"); err == nil {
+		t.Fatal("registering without a name should fail")
+	}
+
+	mustRun(t, s, "register", "bob")
+	if s.cfg.User_name != "bob" {
+		t.Fatalf("expected to be logged in as bob, got %q", s.cfg.User_name)
+	}
+
+	mustRun(t, s, "login", "alice")
+	if s.cfg.User_name != "alice" {
+		t.Fatalf("expected to be logged in as alice, got %q", s.cfg.User_name)
+	}
+
+	if err := run(t, s, "login", "nobody"); err == nil {
+		t.Fatal("logging in as an unknown user should fail")
+	}
+	if s.cfg.User_name != "alice" {
+		t.Fatalf("a failed login shouldn't change the user, got %q", s.cfg.User_name)
+	}
+
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		t.Fatalf("retrieving users: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %v", len(users))
+	}
+}
+
+func TestPasswordLogin(t *testing.T) {
+	s := newTestState(t)
+
+	withInput(t, "secret", "secret")
+	mustRun(t, s, "register", "--password", "carol")
+
+	carol := mustGetUser(t, s, "carol")
+	if !carol.PasswordHash.Valid || carol.PasswordHash.String == "secret" {
+		t.Fatal("the password should be stored as a hash")
+	}
+	if s.cfg.Session == nil {
+		t.Fatal("registering with a password should start a session")
+	}
+
+	mustRun(t, s, "register", "dave")
+	if err := run(t, s, "following"); err != nil {
+		t.Fatalf("dave has no password and needs no session: %v", err)
+	}
+
+	withInput(t, "wrong")
+	if err := run(t, s, "login", "carol"); err == nil {
+		t.Fatal("logging in with the wrong password should fail")
+	}
+	if s.cfg.User_name != "dave" {
+		t.Fatalf("a failed login shouldn't change the user, got %q", s.cfg.User_name)
+	}
+
+	withInput(t, "secret")
+	mustRun(t, s, "login", "carol")
+	if err := run(t, s, "following"); err != nil {
+		t.Fatalf("carol should be logged in: %v", err)
+	}
+
+	mustRun(t, s, "logout")
+	mustRun(t, s, "login", "dave")
+	// pretend to be carol without her password
+	s.cfg.User_name = "carol"
+	if err := run(t, s, "following"); err == nil {
+		t.Fatal("acting as carol without a session should fail")
+	}
+}
+
+func TestAddFeedAndFollow(t *testing.T) {
+	s := newTestState(t)
+	const feedURL = "https://example.com/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", feedURL)
+
+	feed := mustGetFeed(t, s, feedURL)
+	alice := mustGetUser(t, s, "alice")
+	if feed.Name != "Example" || feed.UserID != alice.ID {
+		t.Fatalf("unexpected feed %+v", feed)
+	}
+	if urls := followedURLs(t, s, alice); len(urls) != 1 || urls[0] != feedURL {
+		t.Fatalf("adding a feed should follow it, alice follows %v", urls)
+	}
+
+	if err := run(t, s, "addfeed", "Again", feedURL); err == nil {
+		t.Fatal("adding the same url twice should fail")
+	}
+	if err := run(t, s, "addfeed", "Bad", "ftp://example.com/feed.xml"); err == nil {
+		t.Fatal("adding a non http url should fail")
+	}
+
+	mustRun(t, s, "register", "bob")
+	bob := mustGetUser(t, s, "bob")
+	mustRun(t, s, "follow", feedURL)
+	if urls := followedURLs(t, s, bob); len(urls) != 1 || urls[0] != feedURL {
+		t.Fatalf("bob should follow %v, follows %v", feedURL, urls)
+	}
+
+	if err := run(t, s, "follow", feedURL); err == nil {
+		t.Fatal("following the same feed twice should fail")
+	}
+	if err := run(t, s, "follow", "https://example.com/missing.xml"); err == nil {
+		t.Fatal("following an unknown feed should fail")
+	}
+
+	mustRun(t, s, "unfollow", feedURL)
+	if urls := followedURLs(t, s, bob); len(urls) != 0 {
+		t.Fatalf("bob should follow nothing, follows %v", urls)
+	}
+	if urls := followedURLs(t, s, alice); len(urls) != 1 {
+		t.Fatalf("bob unfollowing shouldn't affect alice, she follows %v", urls)
+	}
+}
+
+func TestFullContentOnlyForOwner(t *testing.T) {
+	s := newTestState(t)
+	const feedURL = "https://example.com/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", feedURL)
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", feedURL)
+
+	if err := run(t, s, "fullcontent", feedURL, "on"); err == nil {
+		t.Fatal("bob shouldn't be able to change a feed alice added")
+	}
+	if mustGetFeed(t, s, feedURL).FetchFullContent {
+		t.Fatal("full content fetching was turned on by someone who doesn't own the feed")
+	}
+
+	mustRun(t, s, "login", "alice")
+	mustRun(t, s, "fullcontent", feedURL, "on")
+	if !mustGetFeed(t, s, feedURL).FetchFullContent {
+		t.Fatal("expected alice to turn full content fetching on")
+	}
+	if err := run(t, s, "fullcontent", "https://example.com/missing.xml", "on"); err == nil {
+		t.Fatal("changing an unknown feed should fail")
+	}
+}
+
+func TestRetentionOnlyForOwner(t *testing.T) {
+	s := newTestState(t)
+	const feedURL = "https://example.com/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", feedURL)
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", feedURL)
+
+	if err := run(t, s, "retention", "set", "--max-posts", "1", feedURL); err == nil {
+		t.Fatal("bob shouldn't be able to change the retention of a feed alice added")
+	}
+	if mustGetFeed(t, s, feedURL).RetentionMaxPosts.Valid {
+		t.Fatal("retention was changed by someone who doesn't own the feed")
+	}
+
+	mustRun(t, s, "login", "alice")
+	mustRun(t, s, "retention", "set", "--max-posts", "1", feedURL)
+	if limit := mustGetFeed(t, s, feedURL).RetentionMaxPosts; !limit.Valid || limit.Int32 != 1 {
+		t.Fatalf("expected alice to limit the feed to 1 post, got %+v", limit)
+	}
+	if err := run(t, s, "retention", "set", "--max-posts", "1", "https://example.com/missing.xml"); err == nil {
+		t.Fatal("changing an unknown feed should fail")
+	}
+
+	mustRun(t, s, "logout")
+	if err := run(t, s, "retention", "set", "--max-posts", "2", feedURL); err == nil {
+		t.Fatal("changing retention should require being logged in")
+	}
+}
+
+func TestBrowsePaging(t *testing.T) {
+	s := newTestState(t)
+	s.output = outputJSON
+	const feedURL = "https://example.com/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", feedURL)
+	posts := addPosts(t, s, mustGetFeed(t, s, feedURL), 5)
+
+	browse := func(args ...string) ([]postRecord, string) {
+		t.Helper()
+		stdout, stderr, err := runOutput(t, s, append([]string{"browse"}, args...)...)
+		if err != nil {
+			t.Fatalf("browse %v: %v", args, err)
+		}
+
+		var records []postRecord
+		if err := json.Unmarshal([]byte(stdout), &records); err != nil {
+			t.Fatalf("browse printed invalid json %q: %v", stdout, err)
+		}
+		cursor := ""
+		if _, after, found := strings.Cut(stderr, "--cursor "); found {
+			cursor = strings.TrimSpace(after)
+		}
+		return records, cursor
+	}
+
+	var seen []string
+	cursor := ""
+	for page := 0; ; page++ {
+		args := []string{"2"}
+		if cursor != "" {
+			args = []string{"--cursor", cursor, "2"}
+		}
+		records, next := browse(args...)
+		for _, record := range records {
+			seen = append(seen, record.ID)
+		}
+		if next == "" {
+			break
+		}
+		if page > 5 {
+			t.Fatal("paging never ended")
+		}
+		cursor = next
+	}
+
+	// newest first, every post exactly once
+	if len(seen) != len(posts) {
+		t.Fatalf("expected %v posts over every page, got %v", len(posts), seen)
+	}
+	for i, id := range seen {
+		if want := posts[len(posts)-1-i].ID.String(); id != want {
+			t.Fatalf("post %v: expected %v, got %v", i, want, id)
+		}
+	}
+
+	records, _ := browse("--offset", "3", "--asc", "10")
+	if len(records) != 2 || records[0].ID != posts[3].ID.String() {
+		t.Fatalf("unexpected posts with an offset: %+v", records)
+	}
+
+	alice := mustGetUser(t, s, "alice")
+	for _, post := range posts[:4] {
+		if err := markPostRead(context.Background(), s.db, alice, post.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, next := browse("--unread", "10")
+	if len(records) != 1 || records[0].ID != posts[4].ID.String() || next != "" {
+		t.Fatalf("expected only the unread post, got %+v (cursor %q)", records, next)
+	}
+
+	if err := run(t, s, "browse", "--cursor", "nonsense"); err == nil {
+		t.Fatal("an invalid cursor should be rejected")
+	}
+}
+
+func TestAggSettings(t *testing.T) {
+	s := newTestState(t)
+
+	if err := run(t, s, "agg"); err == nil {
+		t.Fatal("agg without an interval on the command line or in the config should fail")
+	}
+	if err := run(t, s, "agg", "0s"); err == nil {
+		t.Fatal("agg with an interval of 0 should fail")
+	}
+
+	s.cfg.Fetch = &config.Fetch{Interval: "1m", Timeout: "soon"}
+	if err := run(t, s, "agg"); err == nil || !strings.Contains(err.Error(), "fetch.timeout") {
+		t.Fatalf("expected the config's timeout to be rejected, got %v", err)
+	}
+	s.cfg.Fetch = &config.Fetch{Interval: "often"}
+	if err := run(t, s, "agg"); err == nil {
+		t.Fatal("expected the config's interval to be rejected")
+	}
+}
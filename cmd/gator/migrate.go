@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/Andrew-The-Cat/gator/internal/migrate"
+)
+
+func handlerMigrate(s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("command requires one of up, down, status or version")
+	}
+
+	migrations, err := migrate.Load(s.backend.migrations)
+	if err != nil {
+		return fmt.Errorf("error reading the bundled migrations: %v", err)
+	}
+
+	switch cmd.args[0] {
+	case "up":
+		return migrateUp(s, cmd.args[1:], migrations)
+	case "down":
+		return migrateDown(s, cmd.args[1:], migrations)
+	case "status":
+		return migrateStatus(s, cmd.args[1:], migrations)
+	case "version":
+		return migrateVersion(s, cmd.args[1:], migrations)
+	default:
+		return fmt.Errorf("unknown migrate command %v, expected up, down, status or version", cmd.args[0])
+	}
+}
+
+func migrateUp(s *state, args []string, migrations []migrate.Migration) error {
+	flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	to := flags.Int64("to", 0, "stop after this version instead of applying everything")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 0 {
+		return fmt.Errorf("command takes no arguments besides its flags")
+	}
+
+	ran, err := migrate.Up(context.Background(), s.conn, s.backend.dialect, migrations, *to)
+	for _, migration := range ran {
+		fmt.Printf("\tapplied %03d_%v\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(ran) == 0 {
+		fmt.Println("Database is already up to date")
+		return nil
+	}
+	fmt.Printf("Applied %v migration(s)\n", len(ran))
+	return nil
+}
+
+func migrateDown(s *state, args []string, migrations []migrate.Migration) error {
+	flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	to := flags.Int64("to", -1, "roll back every migration after this version instead of just the latest, 0 rolls back everything")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 0 {
+		return fmt.Errorf("command takes no arguments besides its flags")
+	}
+
+	ran, err := migrate.Down(context.Background(), s.conn, s.backend.dialect, migrations, *to)
+	for _, migration := range ran {
+		fmt.Printf("\trolled back %03d_%v\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(ran) == 0 {
+		fmt.Println("Nothing to roll back")
+		return nil
+	}
+	fmt.Printf("Rolled back %v migration(s)\n", len(ran))
+	return nil
+}
+
+func migrateStatus(s *state, args []string, migrations []migrate.Migration) error {
+	if len(args) != 0 {
+		return fmt.Errorf("command takes no arguments")
+	}
+
+	statuses, err := migrate.Statuses(context.Background(), s.conn, s.backend.dialect, migrations)
+	if err != nil {
+		return err
+	}
+
+	records := make([]migrationRecord, 0, len(statuses))
+	for _, status := range statuses {
+		record := migrationRecord{
+			Version: status.Version,
+			Name:    status.Name,
+			Applied: status.Applied,
+		}
+		if status.Applied {
+			record.AppliedAt = &status.AppliedAt
+		}
+		records = append(records, record)
+	}
+
+	return printList(s, records, []column[migrationRecord]{
+		{"version", func(r migrationRecord) string { return fmt.Sprintf("%03d", r.Version) }},
+		{"name", func(r migrationRecord) string { return r.Name }},
+		{"applied", func(r migrationRecord) string { return strconv.FormatBool(r.Applied) }},
+		{"applied_at", func(r migrationRecord) string { return formatTime(r.AppliedAt) }},
+	})
+}
+
+func migrateVersion(s *state, args []string, migrations []migrate.Migration) error {
+	if len(args) != 0 {
+		return fmt.Errorf("command takes no arguments")
+	}
+
+	version, err := migrate.Version(context.Background(), s.conn, s.backend.dialect)
+	if err != nil {
+		return err
+	}
+
+	latest := int64(0)
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	fmt.Printf("Database is at version %v, this gator ships up to version %v\n", version, latest)
+	if version < latest {
+		fmt.Println("Run gator migrate up to upgrade it")
+	}
+	return nil
+}
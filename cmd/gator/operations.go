@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+/*
+======================================================
+
+		Operations
+
+	business logic shared by the cli handlers and the
+	json api, handlers only parse input and print results
+
+======================================================
+*/
+
+type failure int
+
+const (
+	failInternal failure = iota
+	failInvalid
+	failNotFound
+	failConflict
+	failUnauthorized
+)
+
+// opError lets callers tell bad input and missing records apart from database errors
+// without matching on messages
+type opError struct {
+	kind failure
+	msg  string
+}
+
+func (e *opError) Error() string {
+	return e.msg
+}
+
+func opErrorf(kind failure, format string, args ...any) error {
+	return &opError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+func failureOf(err error) failure {
+	var op *opError
+	if errors.As(err, &op) {
+		return op.kind
+	}
+	return failInternal
+}
+
+// withTx runs fn against a store bound to a single transaction, committing when fn
+// succeeds and rolling back everything it did when it fails. fn must only use the
+// store it's given, sqlite has a single connection that the transaction holds on to
+func withTx(ctx context.Context, s *state, fn func(db database.Store) error) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.db.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	// postgres and sqlite word it differently
+	return strings.Contains(err.Error(), "duplicate key value violates unique constraint") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func validFeedURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return opErrorf(failInvalid, "%v is not an http(s) url", raw)
+	}
+	return nil
+}
+
+func registerUser(ctx context.Context, db database.UserRepository, name string) (database.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return database.User{}, opErrorf(failInvalid, "username can't be empty")
+	}
+
+	user, err := db.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		Name: name,
+	})
+	if isUniqueViolation(err) {
+		return database.User{}, opErrorf(failConflict, "user %v already exists", name)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("an unexpected error occured when creating user: %v", err)
+	}
+
+	return user, nil
+}
+
+func getUser(ctx context.Context, db database.UserRepository, name string) (database.User, error) {
+	user, err := db.GetUser(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, opErrorf(failNotFound, "user %v not found", name)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("error retrieving user: %v", err)
+	}
+	return user, nil
+}
+
+// addFeed creates a feed and has its creator follow it straight away, callers run it
+// in a transaction so a failed follow doesn't leave a feed nobody follows behind
+func addFeed(ctx context.Context, db database.SubscriptionRepository, user database.User, name, feedURL string) (database.Feed, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return database.Feed{}, opErrorf(failInvalid, "feed name can't be empty")
+	}
+	if err := validFeedURL(feedURL); err != nil {
+		return database.Feed{}, err
+	}
+
+	feed, err := db.AddFeed(ctx, database.AddFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+		Url:       feedURL,
+		UserID:    user.ID,
+	})
+	if isUniqueViolation(err) {
+		return database.Feed{}, opErrorf(failConflict, "a feed at %v already exists", feedURL)
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("error adding the feed to the database: %v", err)
+	}
+
+	_, err = db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("error creating feed follow: %v", err)
+	}
+
+	return feed, nil
+}
+
+func followFeed(ctx context.Context, db database.SubscriptionRepository, user database.User, feedURL string) (database.CreateFeedFollowRow, error) {
+	feed, err := db.GetFeedByUrl(ctx, feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.CreateFeedFollowRow{}, opErrorf(failNotFound, "no feed found at %v", feedURL)
+	}
+	if err != nil {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("error retrieving requested feed: %v", err)
+	}
+
+	follow, err := db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if isUniqueViolation(err) {
+		return database.CreateFeedFollowRow{}, opErrorf(failConflict, "%v is already following %v", user.Name, feedURL)
+	}
+	if err != nil {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("error creating feed follow: %v", err)
+	}
+
+	return follow, nil
+}
+
+func unfollowFeed(ctx context.Context, db database.FollowRepository, user database.User, feedURL string) error {
+	affected, err := db.DeleteFeedFollowForUser(ctx, database.DeleteFeedFollowForUserParams{
+		UserID: user.ID,
+		Url:    feedURL,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting feed follow: %v", err)
+	}
+	if affected == 0 {
+		return opErrorf(failNotFound, "you are not following a feed at %v", feedURL)
+	}
+	return nil
+}
+
+// setFollowTitle renames a followed feed for one user, an invalid title restores the feed's own name
+func setFollowTitle(ctx context.Context, db database.FollowRepository, user database.User, feedURL string, title sql.NullString) error {
+	affected, err := db.SetFeedFollowName(ctx, database.SetFeedFollowNameParams{
+		CustomName: title,
+		UpdatedAt:  time.Now(),
+		UserID:     user.ID,
+		Url:        feedURL,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed title: %v", err)
+	}
+	if affected == 0 {
+		return opErrorf(failNotFound, "you are not following a feed at %v", feedURL)
+	}
+	return nil
+}
+
+type browseOptions struct {
+	limit     int
+	offset    int
+	cursor    string
+	feed      string
+	since     string
+	until     string
+	sortBy    string
+	ascending bool
+	unread    bool
+}
+
+// browsePosts returns a page of posts from the user's followed feeds along with the
+// cursor of the next page, which is empty once there's nothing more to show
+func browsePosts(ctx context.Context, db database.PostRepository, user database.User, opts browseOptions) ([]database.BrowsePostsForUserRow, string, error) {
+	if opts.limit < 1 {
+		return nil, "", opErrorf(failInvalid, "limit must be at least 1")
+	}
+	if opts.offset < 0 {
+		return nil, "", opErrorf(failInvalid, "offset can't be negative")
+	}
+
+	params := database.BrowsePostsForUserParams{
+		UserID:     user.ID,
+		UnreadOnly: opts.unread,
+		Ascending:  opts.ascending,
+		PageLimit:  int32(opts.limit),
+		PageOffset: int32(opts.offset),
+	}
+
+	switch opts.sortBy {
+	case "published":
+		params.ByPublished = true
+	case "fetched", "":
+		params.ByPublished = false
+	default:
+		return nil, "", opErrorf(failInvalid, "unknown sort order %v, expected published or fetched", opts.sortBy)
+	}
+
+	if opts.feed != "" {
+		params.Feed.Scan(opts.feed)
+	}
+
+	var err error
+	if params.Since, err = parseDateFlag(opts.since); err != nil {
+		return nil, "", opErrorf(failInvalid, "invalid since: %v", err)
+	}
+	if params.Until, err = parseDateFlag(opts.until); err != nil {
+		return nil, "", opErrorf(failInvalid, "invalid until: %v", err)
+	}
+	if params.CursorKey, params.CursorID, err = decodeCursor(opts.cursor); err != nil {
+		return nil, "", opErrorf(failInvalid, "invalid cursor: %v", err)
+	}
+
+	posts, err := db.BrowsePostsForUser(ctx, params)
+	if err != nil {
+		return nil, "", fmt.Errorf("error when retrieving posts: %v", err)
+	}
+
+	var next string
+	if len(posts) > 0 && len(posts) == opts.limit {
+		last := posts[len(posts)-1]
+		next = encodeCursor(last.SortKey, last.ID)
+	}
+
+	return posts, next, nil
+}
+
+// getPost only finds posts from feeds the user follows
+func getPost(ctx context.Context, db database.PostRepository, user database.User, postID uuid.UUID) (database.GetPostForUserRow, error) {
+	post, err := db.GetPostForUser(ctx, database.GetPostForUserParams{
+		ID:     postID,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.GetPostForUserRow{}, opErrorf(failNotFound, "no post %v in your followed feeds", postID)
+	}
+	if err != nil {
+		return database.GetPostForUserRow{}, fmt.Errorf("error retrieving post from a followed feed: %v", err)
+	}
+	return post, nil
+}
+
+func markPostRead(ctx context.Context, db database.PostRepository, user database.User, postID uuid.UUID) error {
+	if _, err := getPost(ctx, db, user, postID); err != nil {
+		return err
+	}
+
+	err := db.MarkPostRead(ctx, database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: postID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error marking post as read: %v", err)
+	}
+	return nil
+}
+
+// markPostUnread reports whether the post had been read
+func markPostUnread(ctx context.Context, db database.PostRepository, user database.User, postID uuid.UUID) (bool, error) {
+	affected, err := db.MarkPostUnread(ctx, database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("error marking post as unread: %v", err)
+	}
+	return affected > 0, nil
+}
+
+func starPost(ctx context.Context, db database.PostRepository, user database.User, postID uuid.UUID) error {
+	if _, err := getPost(ctx, db, user, postID); err != nil {
+		return err
+	}
+
+	err := db.StarPost(ctx, database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    postID,
+		StarredAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error starring post: %v", err)
+	}
+	return nil
+}
+
+// unstarPost reports whether the post had been starred
+func unstarPost(ctx context.Context, db database.PostRepository, user database.User, postID uuid.UUID) (bool, error) {
+	affected, err := db.UnstarPost(ctx, database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("error unstarring post: %v", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/opml"
+	"github.com/google/uuid"
+)
+
+type importReport struct {
+	created   []string
+	followed  []string
+	duplicate []string
+	failed    []string
+}
+
+func handlerImport(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 || cmd.args[0] != "opml" {
+		return fmt.Errorf("command requires a format to import from, currently only opml is supported")
+	}
+
+	flags := flag.NewFlagSet("import opml", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "show what would be imported without changing anything")
+
+	if err := flags.Parse(cmd.args[1:]); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires the path of the opml file to import")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("unable to open opml file: %v", err)
+	}
+	defer file.Close()
+
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	// everything happens inside one transaction, a dry run simply never commits it
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	report, err := importSubscriptions(s.db.WithTx(tx), user, doc.Subscriptions())
+	if err != nil {
+		return fmt.Errorf("import aborted, nothing was changed: %v", err)
+	}
+
+	if !*dryRun {
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("unable to commit import: %v", err)
+		}
+	}
+
+	report.print(*dryRun)
+	return nil
+}
+
+func importSubscriptions(db database.SubscriptionRepository, user database.User, subs []opml.Subscription) (importReport, error) {
+	var report importReport
+
+	follows, err := db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return report, fmt.Errorf("error retrieving feed follows: %v", err)
+	}
+
+	following := make(map[string]bool)
+	for _, follow := range follows {
+		following[follow.FeedUrl] = true
+	}
+
+	for _, sub := range subs {
+		label := fmt.Sprintf("%v (%v)", sub.Title, sub.XMLURL)
+
+		parsed, err := url.Parse(sub.XMLURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			report.failed = append(report.failed, label+": not an http(s) url")
+			continue
+		}
+
+		if following[sub.XMLURL] {
+			report.duplicate = append(report.duplicate, label)
+			continue
+		}
+
+		name := sub.Title
+		if name == "" {
+			name = sub.XMLURL
+		}
+
+		feed, err := db.GetFeedByUrl(context.Background(), sub.XMLURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			feed, err = db.AddFeed(context.Background(), database.AddFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				Url:       sub.XMLURL,
+				UserID:    user.ID,
+				SiteUrl: sql.NullString{
+					String: sub.HTMLURL,
+					Valid:  sub.HTMLURL != "",
+				},
+			})
+			if err != nil {
+				return report, fmt.Errorf("error adding %v: %v", label, err)
+			}
+			report.created = append(report.created, label)
+		} else if err != nil {
+			return report, fmt.Errorf("error retrieving %v: %v", label, err)
+		}
+
+		params := database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		}
+		// keep the title from the old reader if someone else added the feed under another name
+		if name != feed.Name {
+			params.CustomName.Scan(name)
+		}
+		if sub.Folder != "" {
+			params.Folder.Scan(sub.Folder)
+		}
+
+		_, err = db.CreateFeedFollow(context.Background(), params)
+		if err != nil {
+			return report, fmt.Errorf("error following %v: %v", label, err)
+		}
+
+		following[sub.XMLURL] = true
+		report.followed = append(report.followed, label)
+	}
+
+	return report, nil
+}
+
+func exportOPML(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("export opml", flag.ContinueOnError)
+	path := flags.String("file", "", "write the opml to this file instead of stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving feed follows: %v", err)
+	}
+
+	subs := make([]opml.Subscription, 0, len(follows))
+	for _, follow := range follows {
+		subs = append(subs, opml.Subscription{
+			Title:   follow.FeedName,
+			XMLURL:  follow.FeedUrl,
+			HTMLURL: follow.SiteUrl.String,
+			Folder:  follow.Folder.String,
+		})
+	}
+
+	out, err := exportDestination(*path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	doc := opml.New(fmt.Sprintf("%v's subscriptions in gator", user.Name), time.Now(), subs)
+	if err := doc.Write(out); err != nil {
+		return err
+	}
+
+	if *path != "" {
+		fmt.Printf("Exported %v feeds to %v\n", len(subs), *path)
+	}
+	return out.Close()
+}
+
+func (r importReport) print(dryRun bool) {
+	if dryRun {
+		fmt.Println("Dry run, the following would have been imported:")
+	} else {
+		fmt.Println("Import finished:")
+	}
+
+	sections := []struct {
+		title string
+		items []string
+	}{
+		{"created feeds", r.created},
+		{"followed feeds", r.followed},
+		{"already following", r.duplicate},
+		{"failed", r.failed},
+	}
+
+	for _, section := range sections {
+		fmt.Printf("\t%v: %v\n", section.title, len(section.items))
+		for _, item := range section.items {
+			fmt.Printf("\t\t* %v\n", item)
+		}
+	}
+}
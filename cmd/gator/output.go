@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+======================================================
+
+		Output formats
+
+======================================================
+*/
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputPlain = "plain"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML, outputPlain}
+
+func validOutput(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+type column[T any] struct {
+	header string
+	value  func(T) string
+}
+
+// printList writes records in the format chosen with --output. table and plain
+// are built from the columns, json and yaml encode the records themselves so
+// their field names come from the record's struct tags
+func printList[T any](s *state, records []T, columns []column[T]) error {
+	if records == nil {
+		records = make([]T, 0)
+	}
+
+	switch s.output {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+
+	case outputYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+		return encoder.Close()
+
+	case outputPlain:
+		for _, record := range records {
+			values := make([]string, 0, len(columns))
+			for _, col := range columns {
+				values = append(values, col.value(record))
+			}
+			fmt.Println(strings.Join(values, "\t"))
+		}
+		return nil
+
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		headers := make([]string, 0, len(columns))
+		for _, col := range columns {
+			headers = append(headers, strings.ToUpper(col.header))
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+		for _, record := range records {
+			values := make([]string, 0, len(columns))
+			for _, col := range columns {
+				values = append(values, col.value(record))
+			}
+			fmt.Fprintln(w, strings.Join(values, "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+/*
+======================================================
+
+		Records
+
+	field names are part of gator's scripting interface,
+	see docs/schemas before changing any of them
+
+======================================================
+*/
+
+type userRecord struct {
+	Name      string    `json:"name" yaml:"name"`
+	Current   bool      `json:"current" yaml:"current"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+type feedRecord struct {
+	Name      string `json:"name" yaml:"name"`
+	URL       string `json:"url" yaml:"url"`
+	CreatedBy string `json:"created_by" yaml:"created_by"`
+}
+
+type followRecord struct {
+	Name    string `json:"name" yaml:"name"`
+	URL     string `json:"url" yaml:"url"`
+	SiteURL string `json:"site_url" yaml:"site_url"`
+	Folder  string `json:"folder" yaml:"folder"`
+}
+
+type postRecord struct {
+	ID          string     `json:"id" yaml:"id"`
+	Title       string     `json:"title" yaml:"title"`
+	URL         string     `json:"url" yaml:"url"`
+	Feed        string     `json:"feed" yaml:"feed"`
+	FeedURL     string     `json:"feed_url" yaml:"feed_url"`
+	Author      string     `json:"author" yaml:"author"`
+	PublishedAt *time.Time `json:"published_at" yaml:"published_at"`
+	FetchedAt   time.Time  `json:"fetched_at" yaml:"fetched_at"`
+	Read        bool       `json:"read" yaml:"read"`
+	Description string     `json:"description" yaml:"description"`
+}
+
+type webhookRecord struct {
+	ID      string `json:"id" yaml:"id"`
+	URL     string `json:"url" yaml:"url"`
+	Feed    string `json:"feed" yaml:"feed"`
+	Keyword string `json:"keyword" yaml:"keyword"`
+	Signed  bool   `json:"signed" yaml:"signed"`
+}
+
+type deliveryRecord struct {
+	WebhookID string    `json:"webhook_id" yaml:"webhook_id"`
+	SentAt    time.Time `json:"sent_at" yaml:"sent_at"`
+	Attempt   int       `json:"attempt" yaml:"attempt"`
+	Posts     int       `json:"posts" yaml:"posts"`
+	Status    int       `json:"status" yaml:"status"`
+	Succeeded bool      `json:"succeeded" yaml:"succeeded"`
+	Error     string    `json:"error" yaml:"error"`
+}
+
+type ruleRecord struct {
+	ID        string    `json:"id" yaml:"id"`
+	Field     string    `json:"field" yaml:"field"`
+	Pattern   string    `json:"pattern" yaml:"pattern"`
+	Regex     bool      `json:"regex" yaml:"regex"`
+	Action    string    `json:"action" yaml:"action"`
+	Tag       string    `json:"tag" yaml:"tag"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+type migrationRecord struct {
+	Version   int64      `json:"version" yaml:"version"`
+	Name      string     `json:"name" yaml:"name"`
+	Applied   bool       `json:"applied" yaml:"applied"`
+	AppliedAt *time.Time `json:"applied_at" yaml:"applied_at"`
+}
+
+type retentionRecord struct {
+	Name              string `json:"name" yaml:"name"`
+	URL               string `json:"url" yaml:"url"`
+	MaxAgeDays        *int   `json:"max_age_days" yaml:"max_age_days"`
+	MaxAgeIsDefault   bool   `json:"max_age_is_default" yaml:"max_age_is_default"`
+	MaxPosts          *int   `json:"max_posts" yaml:"max_posts"`
+	MaxPostsIsDefault bool   `json:"max_posts_is_default" yaml:"max_posts_is_default"`
+}
+
+type profileRecord struct {
+	Name    string `json:"name" yaml:"name"`
+	DBURL   string `json:"db_url" yaml:"db_url"`
+	User    string `json:"user" yaml:"user"`
+	Current bool   `json:"current" yaml:"current"`
+	InUse   bool   `json:"in_use" yaml:"in_use"`
+}
+
+func newPostRecord(item database.BrowsePostsForUserRow) postRecord {
+	record := postRecord{
+		ID:          item.ID.String(),
+		Title:       item.Title.String,
+		URL:         item.Url,
+		Feed:        item.FeedName,
+		FeedURL:     item.FeedUrl,
+		Author:      item.Author.String,
+		FetchedAt:   item.CreatedAt,
+		Read:        item.ReadAt.Valid,
+		Description: item.Description.String,
+	}
+	if item.PublishedAt.Valid {
+		record.PublishedAt = &item.PublishedAt.Time
+	}
+	return record
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
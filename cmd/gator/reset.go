@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+// deletedCounts is how many rows of each kind a reset or deleting a user removed
+type deletedCounts struct {
+	users   int64
+	feeds   int64
+	follows int64
+	posts   int64
+}
+
+func handlerReset(s *state, cmd command) error {
+	flags := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "don't ask for confirmation")
+	posts := flags.Bool("posts", false, "only delete posts")
+	feeds := flags.Bool("feeds", false, "only delete feeds, along with their follows and posts")
+	userName := flags.String("user", "", "only delete this user and their follows, the feeds they added go to their followers")
+	userFeeds := flags.Bool("user-feeds", false, "with --user, delete the feeds the user added instead of handing them over")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 0 {
+		return fmt.Errorf("command takes no arguments besides its flags")
+	}
+	if *userFeeds && *userName == "" {
+		return fmt.Errorf("--user-feeds can only be used with --user")
+	}
+
+	var user database.User
+	var scope []string
+	if *userName != "" {
+		var err error
+		user, err = getUser(context.Background(), s.db, *userName)
+		if err != nil {
+			return err
+		}
+		if err := authorize(s, user); err != nil {
+			return err
+		}
+		if *userFeeds {
+			scope = append(scope, fmt.Sprintf("user %v, their follows and the feeds they added", user.Name))
+		} else {
+			scope = append(scope, fmt.Sprintf("user %v and their follows, the feeds they added go to their followers", user.Name))
+		}
+	}
+	if *feeds {
+		scope = append(scope, "every feed, follow and post")
+	}
+	if *posts {
+		scope = append(scope, "every post")
+	}
+	everything := len(scope) == 0
+	if everything {
+		scope = append(scope, "every user, feed, follow and post")
+	}
+
+	// anything beyond a single user takes something from every user, the ones with a
+	// password have to be logged in or give it
+	if *feeds || *posts || everything {
+		if err := authorizeEveryone(s); err != nil {
+			return err
+		}
+	}
+
+	if !*yes {
+		fmt.Printf("This will delete %v\n", strings.Join(scope, ", and "))
+		confirmed, err := confirm("Continue? [y/N] ")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Reset cancelled, nothing was deleted")
+			return nil
+		}
+	}
+
+	var counts deletedCounts
+	var transferred int64
+	err := withTx(context.Background(), s, func(db database.Store) error {
+		if *userName != "" {
+			// like users delete, feeds others follow aren't taken from them unless asked
+			if !*userFeeds {
+				var err error
+				transferred, err = handOverFeeds(db, user, nil)
+				if err != nil {
+					return err
+				}
+			}
+			if err := deleteUser(db, user, &counts); err != nil {
+				return err
+			}
+		}
+		if *feeds || everything {
+			if err := resetFeeds(db, &counts); err != nil {
+				return err
+			}
+		}
+		if *posts {
+			if err := resetPosts(db, &counts); err != nil {
+				return err
+			}
+		}
+		if everything {
+			removed, err := db.UsersReset(context.Background())
+			if err != nil {
+				return fmt.Errorf("couldn't delete users: %v", err)
+			}
+			counts.users += removed
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reset aborted, nothing was deleted: %v", err)
+	}
+
+	if everything || user.Name == s.cfg.User_name {
+		if err := s.cfg.SetSession("", nil); err != nil {
+			return fmt.Errorf("received an error when trying to update username: %v", err)
+		}
+	}
+
+	if transferred > 0 {
+		fmt.Printf("Handed over %v feed(s)\n", transferred)
+	}
+	counts.print()
+	return nil
+}
+
+func (c deletedCounts) print() {
+	fmt.Println("Deleted:")
+	fmt.Printf("\tusers: %v\n\tfeeds: %v\n\tfollows: %v\n\tposts: %v\n", c.users, c.feeds, c.follows, c.posts)
+}
+
+// stdin is shared by every prompt, a reader of its own would buffer input meant for
+// the next one
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes or no question on the terminal, anything but yes is a no
+func confirm(question string) (bool, error) {
+	fmt.Print(question)
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false, nil
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+func resetFeeds(db database.Store, counts *deletedCounts) error {
+	if err := resetPosts(db, counts); err != nil {
+		return err
+	}
+
+	removed, err := db.FeedFollowsReset(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't delete feed follows: %v", err)
+	}
+	counts.follows += removed
+
+	removed, err = db.FeedsReset(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't delete feeds: %v", err)
+	}
+	counts.feeds += removed
+	return nil
+}
+
+// resetPosts also forgets which posts were pruned, so the next fetch starts over
+func resetPosts(db database.Store, counts *deletedCounts) error {
+	removed, err := db.PostsReset(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't delete posts: %v", err)
+	}
+	counts.posts += removed
+
+	if err := db.PrunedPostsReset(context.Background()); err != nil {
+		return fmt.Errorf("couldn't delete pruned posts: %v", err)
+	}
+	return nil
+}
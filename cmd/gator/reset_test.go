@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// seedReset gives alice and bob a feed each, with bob following both
+func seedReset(t *testing.T, s *state) {
+	t.Helper()
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Alice's", "https://alice.example.com/feed.xml")
+	addPosts(t, s, mustGetFeed(t, s, "https://alice.example.com/feed.xml"), 3)
+
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Bob's", "https://bob.example.com/feed.xml")
+	mustRun(t, s, "follow", "https://alice.example.com/feed.xml")
+	addPosts(t, s, mustGetFeed(t, s, "https://bob.example.com/feed.xml"), 2)
+}
+
+func countPosts(t *testing.T, s *state, name string) int64 {
+	t.Helper()
+	count, err := s.db.CountPostsForUser(context.Background(), mustGetUser(t, s, name).ID)
+	if err != nil {
+		t.Fatalf("counting posts: %v", err)
+	}
+	return count
+}
+
+func countRows(t *testing.T, s *state) (users, feeds int) {
+	t.Helper()
+	allUsers, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		t.Fatalf("retrieving users: %v", err)
+	}
+	allFeeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		t.Fatalf("retrieving feeds: %v", err)
+	}
+	return len(allUsers), len(allFeeds)
+}
+
+func TestResetAsksFirst(t *testing.T) {
+	s := newTestState(t)
+	seedReset(t, s)
+
+	withInput(t, "n")
+	mustRun(t, s, "reset")
+
+	if users, feeds := countRows(t, s); users != 2 || feeds != 2 {
+		t.Fatalf("declining shouldn't delete anything, left %v users and %v feeds", users, feeds)
+	}
+	if posts := countPosts(t, s, "bob"); posts != 5 {
+		t.Fatalf("expected bob to still see 5 posts, got %v", posts)
+	}
+}
+
+func TestResetPosts(t *testing.T) {
+	s := newTestState(t)
+	seedReset(t, s)
+
+	mustRun(t, s, "reset", "--yes", "--posts")
+
+	if posts := countPosts(t, s, "bob"); posts != 0 {
+		t.Fatalf("expected every post to be gone, bob still sees %v", posts)
+	}
+	if users, feeds := countRows(t, s); users != 2 || feeds != 2 {
+		t.Fatalf("--posts should keep users and feeds, left %v users and %v feeds", users, feeds)
+	}
+	if urls := followedURLs(t, s, mustGetUser(t, s, "bob")); len(urls) != 2 {
+		t.Fatalf("--posts should keep follows, bob follows %v", urls)
+	}
+}
+
+func TestResetUser(t *testing.T) {
+	s := newTestState(t)
+	seedReset(t, s)
+
+	mustRun(t, s, "reset", "--yes", "--user", "bob")
+
+	if _, err := s.db.GetUser(context.Background(), "bob"); err == nil {
+		t.Fatal("bob should be deleted")
+	}
+	if s.cfg.User_name != "" {
+		t.Fatalf("deleting the current user should log out, logged in as %q", s.cfg.User_name)
+	}
+	if _, err := s.db.GetFeedByUrl(context.Background(), "https://bob.example.com/feed.xml"); err == nil {
+		t.Fatal("the feed bob added should be deleted with him since nobody else follows it")
+	}
+	mustGetFeed(t, s, "https://alice.example.com/feed.xml")
+	if posts := countPosts(t, s, "alice"); posts != 3 {
+		t.Fatalf("alice's posts should be kept, she sees %v", posts)
+	}
+
+	if err := run(t, s, "reset", "--yes", "--user-feeds"); err == nil {
+		t.Fatal("--user-feeds without --user should fail")
+	}
+}
+
+func TestResetUserHandsOverFeeds(t *testing.T) {
+	const bobsFeed = "https://bob.example.com/feed.xml"
+	cases := []struct {
+		name string
+		args []string
+		kept bool
+	}{
+		{"followed feeds go to their followers", []string{"reset", "--yes", "--user", "bob"}, true},
+		{"--user-feeds deletes them anyway", []string{"reset", "--yes", "--user", "bob", "--user-feeds"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestState(t)
+			seedReset(t, s)
+			mustRun(t, s, "login", "alice")
+			mustRun(t, s, "follow", bobsFeed)
+
+			out, _, err := runOutput(t, s, tc.args...)
+			if err != nil {
+				t.Fatalf("reset failed: %v", err)
+			}
+
+			alice := mustGetUser(t, s, "alice")
+			follows := followedURLs(t, s, alice)
+			feed, feedErr := s.db.GetFeedByUrl(context.Background(), bobsFeed)
+			if !tc.kept {
+				if feedErr == nil || len(follows) != 1 {
+					t.Fatalf("expected bob's feed deleted along with alice's follow, she follows %v", follows)
+				}
+				return
+			}
+
+			if feedErr != nil || feed.UserID != alice.ID {
+				t.Fatalf("expected bob's feed handed to alice, got %+v (%v)", feed, feedErr)
+			}
+			if len(follows) != 2 {
+				t.Fatalf("alice's follow of bob's feed should survive, she follows %v", follows)
+			}
+			if posts := countPosts(t, s, "alice"); posts != 5 {
+				t.Fatalf("expected alice to keep seeing 5 posts, got %v", posts)
+			}
+			if !strings.Contains(out, "Handed over 1 feed(s)") || !strings.Contains(out, "follows: 2") {
+				t.Fatalf("expected the hand over and bob's 2 follows reported, got\n%v", out)
+			}
+		})
+	}
+}
+
+func TestResetEverything(t *testing.T) {
+	s := newTestState(t)
+	seedReset(t, s)
+
+	mustRun(t, s, "reset", "--yes")
+
+	if users, feeds := countRows(t, s); users != 0 || feeds != 0 {
+		t.Fatalf("expected an empty database, left %v users and %v feeds", users, feeds)
+	}
+	if s.cfg.User_name != "" {
+		t.Fatalf("reset should log out, logged in as %q", s.cfg.User_name)
+	}
+}
+
+// seedProtected adds carol, who has a password, to seedReset's users and leaves bob
+// logged in
+func seedProtected(t *testing.T, s *state) {
+	t.Helper()
+	seedReset(t, s)
+
+	withInput(t, "secret", "secret")
+	mustRun(t, s, "register", "--password", "carol")
+	mustRun(t, s, "follow", "https://alice.example.com/feed.xml")
+	mustRun(t, s, "login", "bob")
+}
+
+func TestResetNeedsProtectedUsers(t *testing.T) {
+	cases := []struct {
+		args  []string
+		users int
+		feeds int
+	}{
+		{[]string{"--yes"}, 0, 0},
+		{[]string{"--yes", "--feeds"}, 3, 0},
+		{[]string{"--yes", "--posts"}, 3, 2},
+	}
+
+	for _, tc := range cases {
+		s := newTestState(t)
+		seedProtected(t, s)
+		args := append([]string{"reset"}, tc.args...)
+
+		withInput(t, "wrong")
+		if err := run(t, s, args...); err == nil {
+			t.Fatalf("%v shouldn't go ahead without carol's password", args)
+		}
+		if users, feeds := countRows(t, s); users != 3 || feeds != 2 {
+			t.Fatalf("%v deleted %v users and %v feeds without carol's password", args, 3-users, 2-feeds)
+		}
+		if posts := countPosts(t, s, "carol"); posts != 3 {
+			t.Fatalf("%v deleted carol's posts without her password", args)
+		}
+
+		withInput(t, "secret")
+		mustRun(t, s, args...)
+		if users, feeds := countRows(t, s); users != tc.users || feeds != tc.feeds {
+			t.Fatalf("%v with carol's password left %v users and %v feeds", args, users, feeds)
+		}
+		if tc.users > 0 && countPosts(t, s, "carol") != 0 {
+			t.Fatalf("%v with carol's password should delete every post", args)
+		}
+	}
+}
+
+func TestResetSkipsPasswordWhenLoggedIn(t *testing.T) {
+	s := newTestState(t)
+	seedProtected(t, s)
+
+	withInput(t, "secret")
+	mustRun(t, s, "login", "carol")
+
+	// no input is left for a prompt, carol's login has to be enough
+	withInput(t)
+	mustRun(t, s, "reset", "--yes", "--feeds")
+	if users, feeds := countRows(t, s); users != 3 || feeds != 0 {
+		t.Fatalf("expected only feeds deleted, left %v users and %v feeds", users, feeds)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// posts are removed in batches of this size, each in its own transaction
+const pruneBatchSize = 500
+
+// retentionLimit is one of a feed's limits, 0 means there is none
+type retentionLimit struct {
+	value     int
+	inherited bool
+}
+
+func (l retentionLimit) String() string {
+	return formatRetentionLimit(l.record(), l.inherited)
+}
+
+func formatRetentionLimit(value *int, inherited bool) string {
+	formatted := "none"
+	if value != nil {
+		formatted = strconv.Itoa(*value)
+	}
+	if inherited {
+		return formatted + " (default)"
+	}
+	return formatted
+}
+
+func (l retentionLimit) record() *int {
+	if l.value == 0 {
+		return nil
+	}
+	return &l.value
+}
+
+// feedRetention works out the limits that apply to a feed, its own settings win over
+// the ones in the config
+func feedRetention(s *state, feed database.Feed) (maxAgeDays, maxPosts retentionLimit) {
+	maxAgeDays.inherited = !feed.RetentionMaxAgeDays.Valid
+	maxPosts.inherited = !feed.RetentionMaxPosts.Valid
+
+	if s.cfg.Retention != nil {
+		maxAgeDays.value = s.cfg.Retention.MaxAgeDays
+		maxPosts.value = s.cfg.Retention.MaxPostsPerFeed
+	}
+	if feed.RetentionMaxAgeDays.Valid {
+		maxAgeDays.value = int(feed.RetentionMaxAgeDays.Int32)
+	}
+	if feed.RetentionMaxPosts.Valid {
+		maxPosts.value = int(feed.RetentionMaxPosts.Int32)
+	}
+	return maxAgeDays, maxPosts
+}
+
+func handlerRetention(s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("command requires one of list or set")
+	}
+
+	switch cmd.args[0] {
+	case "list":
+		return listRetention(s, cmd.args[1:])
+	case "set":
+		return middlewareLoggedIn(setRetention)(s, command{name: cmd.name, args: cmd.args[1:]})
+	default:
+		return fmt.Errorf("unknown retention command %v, expected list or set", cmd.args[0])
+	}
+}
+
+func listRetention(s *state, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("command takes no arguments")
+	}
+
+	feeds, err := s.db.GetAllFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("error retrieving feeds: %v", err)
+	}
+
+	records := make([]retentionRecord, 0, len(feeds))
+	for _, feed := range feeds {
+		maxAgeDays, maxPosts := feedRetention(s, feed)
+		records = append(records, retentionRecord{
+			Name:              feed.Name,
+			URL:               feed.Url,
+			MaxAgeDays:        maxAgeDays.record(),
+			MaxAgeIsDefault:   maxAgeDays.inherited,
+			MaxPosts:          maxPosts.record(),
+			MaxPostsIsDefault: maxPosts.inherited,
+		})
+	}
+
+	return printList(s, records, []column[retentionRecord]{
+		{"name", func(r retentionRecord) string { return r.Name }},
+		{"url", func(r retentionRecord) string { return r.URL }},
+		{"max_age_days", func(r retentionRecord) string { return formatRetentionLimit(r.MaxAgeDays, r.MaxAgeIsDefault) }},
+		{"max_posts", func(r retentionRecord) string { return formatRetentionLimit(r.MaxPosts, r.MaxPostsIsDefault) }},
+	})
+}
+
+// setRetention changes one feed's limits, only its owner may since pruning removes posts
+// for everyone following it
+func setRetention(s *state, cmd command, user database.User) error {
+	flags := flag.NewFlagSet("retention set", flag.ContinueOnError)
+	maxAge := flags.String("max-age", "", "days posts are kept for, off to keep them forever or default to use the config's")
+	maxPosts := flags.String("max-posts", "", "number of posts kept, off to keep them all or default to use the config's")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires the url of a feed")
+	}
+	if *maxAge == "" && *maxPosts == "" {
+		return fmt.Errorf("command requires --max-age, --max-posts or both")
+	}
+	feedURL := flags.Arg(0)
+
+	feed, err := getOwnedFeed(s.db, user, feedURL, "change the retention of")
+	if err != nil {
+		return err
+	}
+
+	params := database.SetFeedRetentionParams{
+		Url:                 feedURL,
+		RetentionMaxAgeDays: feed.RetentionMaxAgeDays,
+		RetentionMaxPosts:   feed.RetentionMaxPosts,
+		UpdatedAt:           time.Now(),
+	}
+	if *maxAge != "" {
+		params.RetentionMaxAgeDays, err = parseRetentionLimit("--max-age", *maxAge)
+		if err != nil {
+			return err
+		}
+	}
+	if *maxPosts != "" {
+		params.RetentionMaxPosts, err = parseRetentionLimit("--max-posts", *maxPosts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.SetFeedRetention(context.Background(), params); err != nil {
+		return fmt.Errorf("error updating feed: %v", err)
+	}
+
+	feed.RetentionMaxAgeDays = params.RetentionMaxAgeDays
+	feed.RetentionMaxPosts = params.RetentionMaxPosts
+	maxAgeDays, maxPostsLimit := feedRetention(s, feed)
+	fmt.Printf("Retention for %v is now:\n", feedURL)
+	fmt.Printf("\tmax age in days: %v\n\tmax posts: %v\n", maxAgeDays, maxPostsLimit)
+	return nil
+}
+
+// parseRetentionLimit reads a limit given on the command line, NULL falls back to the
+// config and 0 turns the limit off
+func parseRetentionLimit(name, value string) (sql.NullInt32, error) {
+	switch value {
+	case "default":
+		return sql.NullInt32{}, nil
+	case "off":
+		return sql.NullInt32{Int32: 0, Valid: true}, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 1 {
+		return sql.NullInt32{}, fmt.Errorf("%v must be a positive number, off or default", name)
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}, nil
+}
+
+func handlerPrune(s *state, cmd command) error {
+	flags := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "count the posts that would be removed without removing them")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+	if len(flags.Args()) > 1 {
+		return fmt.Errorf("command takes at most the url of a single feed")
+	}
+
+	var feeds []database.Feed
+	if len(flags.Args()) == 1 {
+		feed, err := s.db.GetFeedByUrl(context.Background(), flags.Arg(0))
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no feed found at %v", flags.Arg(0))
+		}
+		if err != nil {
+			return fmt.Errorf("error retrieving feed: %v", err)
+		}
+		feeds = append(feeds, feed)
+	} else {
+		var err error
+		feeds, err = s.db.GetAllFeeds(context.Background())
+		if err != nil {
+			return fmt.Errorf("error retrieving feeds: %v", err)
+		}
+	}
+
+	total := int64(0)
+	for _, feed := range feeds {
+		removed, err := pruneFeed(context.Background(), s, feed, *dryRun)
+		total += removed
+		if err != nil {
+			return fmt.Errorf("error pruning %v after removing %v post(s): %v", feed.Url, total, err)
+		}
+		if removed > 0 {
+			fmt.Printf("\t* %v (%v): %v post(s)\n", feed.Name, feed.Url, removed)
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run, %v post(s) would have been removed\n", total)
+		return nil
+	}
+	fmt.Printf("Removed %v post(s)\n", total)
+	return nil
+}
+
+// pruneFeed removes the posts past a feed's retention that every follower has read and
+// nobody has starred or tagged, returning how many there were. Their urls are kept so
+// the next fetch doesn't bring them back as new posts
+func pruneFeed(ctx context.Context, s *state, feed database.Feed, dryRun bool) (int64, error) {
+	maxAgeDays, maxPosts := feedRetention(s, feed)
+	if maxAgeDays.value == 0 && maxPosts.value == 0 {
+		return 0, nil
+	}
+
+	params := database.GetPrunablePostsParams{
+		FeedID:    feed.ID,
+		BatchSize: pruneBatchSize,
+	}
+	if maxAgeDays.value > 0 {
+		params.Before = sql.NullTime{Time: time.Now().AddDate(0, 0, -maxAgeDays.value), Valid: true}
+	}
+	if maxPosts.value > 0 {
+		params.Keep = sql.NullInt64{Int64: int64(maxPosts.value), Valid: true}
+	}
+
+	total := int64(0)
+	if dryRun {
+		for {
+			batch, err := s.db.GetPrunablePosts(ctx, params)
+			if err != nil {
+				return total, fmt.Errorf("error retrieving posts: %v", err)
+			}
+			total += int64(len(batch))
+			if len(batch) < pruneBatchSize {
+				return total, nil
+			}
+			params.AfterSeq = batch[len(batch)-1].Seq
+		}
+	}
+
+	for {
+		found := 0
+		err := withTx(ctx, s, func(db database.Store) error {
+			batch, err := db.GetPrunablePosts(ctx, params)
+			if err != nil {
+				return fmt.Errorf("error retrieving posts: %v", err)
+			}
+			found = len(batch)
+			if found == 0 {
+				return nil
+			}
+
+			ids := make([]uuid.UUID, 0, len(batch))
+			urls := make([]string, 0, len(batch))
+			for _, post := range batch {
+				ids = append(ids, post.ID)
+				urls = append(urls, post.Url)
+			}
+
+			err = db.CreatePrunedPosts(ctx, database.CreatePrunedPostsParams{
+				Urls:     urls,
+				FeedID:   feed.ID,
+				PrunedAt: time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("error recording pruned posts: %v", err)
+			}
+
+			removed, err := db.DeletePosts(ctx, ids)
+			if err != nil {
+				return fmt.Errorf("error removing posts: %v", err)
+			}
+			total += removed
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		if found < pruneBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/rules"
+	"github.com/google/uuid"
+)
+
+// posts are read in batches of this size when rules are tested or applied to old posts
+const ruleBatchSize = 500
+
+func handlerRules(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("command requires one of list, add, remove, test or apply")
+	}
+
+	switch cmd.args[0] {
+	case "list":
+		return listRules(s, user)
+	case "add":
+		return addRule(s, cmd.args[1:], user)
+	case "remove":
+		return removeRule(s, cmd.args[1:], user)
+	case "test":
+		return testRule(s, cmd.args[1:], user)
+	case "apply":
+		return applyRules(s, cmd.args[1:], user)
+	default:
+		return fmt.Errorf("unknown rules command %v, expected list, add, remove, test or apply", cmd.args[0])
+	}
+}
+
+func listRules(s *state, user database.User) error {
+	list, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving rules: %v", err)
+	}
+
+	records := make([]ruleRecord, 0, len(list))
+	for _, rule := range list {
+		records = append(records, ruleRecord{
+			ID:        rule.ID.String(),
+			Field:     rule.Field,
+			Pattern:   rule.Pattern,
+			Regex:     rule.Regex,
+			Action:    rule.Action,
+			Tag:       rule.Tag.String,
+			CreatedAt: rule.CreatedAt,
+		})
+	}
+
+	return printList(s, records, []column[ruleRecord]{
+		{"id", func(r ruleRecord) string { return r.ID }},
+		{"field", func(r ruleRecord) string { return r.Field }},
+		{"pattern", func(r ruleRecord) string { return r.Pattern }},
+		{"regex", func(r ruleRecord) string { return strconv.FormatBool(r.Regex) }},
+		{"action", func(r ruleRecord) string { return r.Action }},
+		{"tag", func(r ruleRecord) string { return r.Tag }},
+	})
+}
+
+func addRule(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("rules add", flag.ContinueOnError)
+	regex := flags.Bool("regex", false, "treat the pattern as a regular expression")
+	tag := flags.String("tag", "", "tag given to matching posts, required by the tag action")
+	apply := flags.Bool("apply", false, "also apply the rule to posts fetched before it was added")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 3 {
+		return fmt.Errorf("command requires a field, a pattern and an action")
+	}
+	field, pattern, action := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+
+	if _, err := rules.Compile(field, pattern, *regex); err != nil {
+		return err
+	}
+	if !rules.ValidAction(action) {
+		return fmt.Errorf("unknown action %v, expected one of %v", action, strings.Join(rules.Actions, ", "))
+	}
+	if action == rules.ActionTag && strings.TrimSpace(*tag) == "" {
+		return fmt.Errorf("the tag action requires --tag")
+	}
+	if action != rules.ActionTag && *tag != "" {
+		return fmt.Errorf("--tag can only be used with the tag action")
+	}
+
+	rule, err := s.db.CreateRule(context.Background(), database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Field:     field,
+		Pattern:   pattern,
+		Regex:     *regex,
+		Action:    action,
+		Tag:       nullString(strings.TrimSpace(*tag)),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating rule: %v", err)
+	}
+
+	fmt.Println("Successfuly added rule:")
+	fmt.Printf("\tid: %v | %v\n", rule.ID, describeRule(rule))
+
+	if *apply {
+		return applyRulesRetroactively(s, user, []database.Rule{rule})
+	}
+	return nil
+}
+
+func removeRule(s *state, args []string, user database.User) error {
+	if len(args) != 1 {
+		return fmt.Errorf("command requires the id of the rule to remove")
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid rule id: %v", err)
+	}
+
+	affected, err := s.db.DeleteRule(context.Background(), database.DeleteRuleParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error removing rule: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("you have no rule %v", id)
+	}
+
+	// posts the rule read, starred or tagged keep that state, only hiding is undone
+	fmt.Println("Rule has been removed")
+	return nil
+}
+
+// testRule lists the posts a rule matches without acting on them, either for a saved
+// rule or for a field and pattern that haven't been added yet
+func testRule(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("rules test", flag.ContinueOnError)
+	regex := flags.Bool("regex", false, "treat the pattern as a regular expression")
+	limit := flags.Int("limit", 20, "number of matching posts to show")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var matcher rules.Matcher
+	switch len(flags.Args()) {
+	case 1:
+		rule, err := getRule(s, flags.Arg(0), user)
+		if err != nil {
+			return err
+		}
+		matcher, err = rules.Compile(rule.Field, rule.Pattern, rule.Regex)
+		if err != nil {
+			return err
+		}
+	case 2:
+		var err error
+		matcher, err = rules.Compile(flags.Arg(0), flags.Arg(1), *regex)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("command requires the id of a rule, or a field and a pattern")
+	}
+
+	matched := 0
+	err := eachRuleCandidate(s, user, func(post database.GetRuleCandidatesForUserRow) error {
+		if !matcher.Match(candidatePost(post)) {
+			return nil
+		}
+		matched++
+		if matched <= *limit {
+			fmt.Printf("\t*\t%v (%v)\n\t\t%v\n", post.Title.String, post.FeedName, post.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%v post(s) match\n", matched)
+	return nil
+}
+
+func applyRules(s *state, args []string, user database.User) error {
+	if len(args) > 1 {
+		return fmt.Errorf("command takes at most the id of a single rule")
+	}
+
+	if len(args) == 1 {
+		rule, err := getRule(s, args[0], user)
+		if err != nil {
+			return err
+		}
+		return applyRulesRetroactively(s, user, []database.Rule{rule})
+	}
+
+	list, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving rules: %v", err)
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("you have no rules, add one with rules add")
+	}
+	return applyRulesRetroactively(s, user, list)
+}
+
+func getRule(s *state, value string, user database.User) (database.Rule, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return database.Rule{}, fmt.Errorf("invalid rule id: %v", err)
+	}
+
+	rule, err := s.db.GetRuleForUser(context.Background(), database.GetRuleForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Rule{}, fmt.Errorf("you have no rule %v", id)
+	}
+	if err != nil {
+		return database.Rule{}, fmt.Errorf("error retrieving rule: %v", err)
+	}
+	return rule, nil
+}
+
+// applyRulesRetroactively runs rules over every post of the feeds the user follows,
+// actions are idempotent so running it twice changes nothing
+func applyRulesRetroactively(s *state, user database.User, list []database.Rule) error {
+	matchers := make([]rules.Matcher, len(list))
+	for i, rule := range list {
+		matcher, err := rules.Compile(rule.Field, rule.Pattern, rule.Regex)
+		if err != nil {
+			return fmt.Errorf("rule %v is invalid: %v", rule.ID, err)
+		}
+		matchers[i] = matcher
+	}
+
+	counts := make([]int, len(list))
+	err := eachRuleCandidate(s, user, func(post database.GetRuleCandidatesForUserRow) error {
+		for i, rule := range list {
+			if !matchers[i].Match(candidatePost(post)) {
+				continue
+			}
+			if err := applyRule(context.Background(), s.db, rule, post.ID); err != nil {
+				return err
+			}
+			counts[i]++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range list {
+		fmt.Printf("\t%v matched %v post(s)\n", describeRule(rule), counts[i])
+	}
+	return nil
+}
+
+func eachRuleCandidate(s *state, user database.User, fn func(database.GetRuleCandidatesForUserRow) error) error {
+	var after int64
+	for {
+		batch, err := s.db.GetRuleCandidatesForUser(context.Background(), database.GetRuleCandidatesForUserParams{
+			UserID:    user.ID,
+			AfterSeq:  after,
+			BatchSize: ruleBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("error retrieving posts: %v", err)
+		}
+
+		for _, post := range batch {
+			if err := fn(post); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < ruleBatchSize {
+			return nil
+		}
+		after = batch[len(batch)-1].Seq
+	}
+}
+
+// postHide is a post a rule hid from one user
+type postHide struct {
+	user uuid.UUID
+	post uuid.UUID
+}
+
+// applyRulesToNewPosts runs the rules of every user following the feed over the posts
+// just fetched from it, returning the posts it hid. Problems are only warned about so
+// they never stop agg
+func applyRulesToNewPosts(s *state, feed database.Feed, posts []database.Post, categories map[uuid.UUID][]string) map[postHide]bool {
+	hidden := map[postHide]bool{}
+	if len(posts) == 0 {
+		return hidden
+	}
+
+	feedRules, err := s.db.GetRulesForFeed(context.Background(), feed.ID)
+	if err != nil {
+		fmt.Printf("\twarning: couldn't retrieve rules: %v\n", err)
+		return hidden
+	}
+
+	for _, row := range feedRules {
+		rule := database.Rule{
+			ID:      row.ID,
+			UserID:  row.UserID,
+			Field:   row.Field,
+			Pattern: row.Pattern,
+			Regex:   row.Regex,
+			Action:  row.Action,
+			Tag:     row.Tag,
+		}
+
+		matcher, err := rules.Compile(rule.Field, rule.Pattern, rule.Regex)
+		if err != nil {
+			fmt.Printf("\twarning: skipping invalid rule %v: %v\n", rule.ID, err)
+			continue
+		}
+
+		for _, post := range posts {
+			matched := matcher.Match(rules.Post{
+				Title:       post.Title.String,
+				Description: post.Description.String,
+				Author:      post.Author.String,
+				Categories:  categories[post.ID],
+				Feed:        row.FeedName,
+				FeedURL:     feed.Url,
+			})
+			if !matched {
+				continue
+			}
+			if err := applyRule(context.Background(), s.db, rule, post.ID); err != nil {
+				fmt.Printf("\twarning: %v\n", err)
+				continue
+			}
+			if rule.Action == rules.ActionHide {
+				hidden[postHide{user: rule.UserID, post: post.ID}] = true
+			}
+		}
+	}
+	return hidden
+}
+
+func applyRule(ctx context.Context, db database.PostRepository, rule database.Rule, postID uuid.UUID) error {
+	var err error
+	switch rule.Action {
+	case rules.ActionHide:
+		err = db.HidePost(ctx, database.HidePostParams{
+			UserID:   rule.UserID,
+			PostID:   postID,
+			RuleID:   rule.ID,
+			HiddenAt: time.Now(),
+		})
+	case rules.ActionRead:
+		err = db.MarkPostRead(ctx, database.MarkPostReadParams{
+			UserID: rule.UserID,
+			PostID: postID,
+			ReadAt: time.Now(),
+		})
+	case rules.ActionStar:
+		err = db.StarPost(ctx, database.StarPostParams{
+			UserID:    rule.UserID,
+			PostID:    postID,
+			StarredAt: time.Now(),
+		})
+	case rules.ActionTag:
+		err = db.TagPost(ctx, database.TagPostParams{
+			UserID:    rule.UserID,
+			PostID:    postID,
+			Tag:       rule.Tag.String,
+			CreatedAt: time.Now(),
+		})
+	default:
+		return fmt.Errorf("rule %v has unknown action %v", rule.ID, rule.Action)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error applying rule %v to post %v: %v", rule.ID, postID, err)
+	}
+	return nil
+}
+
+func candidatePost(post database.GetRuleCandidatesForUserRow) rules.Post {
+	return rules.Post{
+		Title:       post.Title.String,
+		Description: post.Description.String,
+		Author:      post.Author.String,
+		Categories:  post.Categories,
+		Feed:        post.FeedName,
+		FeedURL:     post.FeedUrl,
+	}
+}
+
+func describeRule(rule database.Rule) string {
+	kind := "contains"
+	if rule.Regex {
+		kind = "matches"
+	}
+	description := fmt.Sprintf("%v %v %q -> %v", rule.Field, kind, rule.Pattern, rule.Action)
+	if rule.Tag.Valid {
+		description += " " + rule.Tag.String
+	}
+	return description
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/rss"
+)
+
+const (
+	timelineAtom = "atom"
+	timelineRSS  = "rss"
+
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 500
+)
+
+func handlerServe(s *state, cmd command) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           newServeMux(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("Serving on http://%v\n", *addr)
+	return server.ListenAndServe()
+}
+
+// newServeMux routes everything gator serve answers
+func newServeMux(s *state) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{name}/feed.atom", serveTimeline(s, timelineAtom))
+	mux.HandleFunc("GET /users/{name}/feed.rss", serveTimeline(s, timelineRSS))
+	registerAPI(s, mux)
+	registerGReader(s, mux)
+	mux.HandleFunc("/fever/", serveFever(s))
+	return mux
+}
+
+// serveTimeline publishes a user's followed posts as a feed. The url has to carry the
+// user's feed token, and can be narrowed down with ?folder= and ?tag=
+func serveTimeline(s *state, format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// unknown users and bad tokens look the same so names can't be probed
+		user, err := s.db.GetUser(r.Context(), r.PathValue("name"))
+		if err != nil || !validFeedToken(user, query.Get("token")) {
+			http.NotFound(w, r)
+			return
+		}
+
+		params := database.GetPostsForUserParams{
+			UserID:    user.ID,
+			PostLimit: defaultTimelineLimit,
+		}
+		if folder := query.Get("folder"); folder != "" {
+			params.Folder.Scan(folder)
+		}
+		if tag := query.Get("tag"); tag != "" {
+			params.Tag.Scan(tag)
+		}
+		if limit := query.Get("limit"); limit != "" {
+			parsed, err := strconv.Atoi(limit)
+			if err != nil || parsed < 1 || parsed > maxTimelineLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %v", maxTimelineLimit), http.StatusBadRequest)
+				return
+			}
+			params.PostLimit = int32(parsed)
+		}
+
+		posts, err := s.db.GetPostsForUser(r.Context(), params)
+		if err != nil {
+			http.Error(w, "error retrieving posts", http.StatusInternalServerError)
+			return
+		}
+
+		etag := timelineETag(format, params, posts)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		timeline := buildTimeline(user, requestURL(r), params, posts)
+
+		var body bytes.Buffer
+		contentType := "application/atom+xml; charset=utf-8"
+		if format == timelineRSS {
+			contentType = "application/rss+xml; charset=utf-8"
+			err = timeline.WriteRSS(&body)
+		} else {
+			err = timeline.WriteAtom(&body)
+		}
+		if err != nil {
+			http.Error(w, "error building feed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body.Bytes())
+	}
+}
+
+func buildTimeline(user database.User, self string, params database.GetPostsForUserParams, posts []database.GetPostsForUserRow) rss.Timeline {
+	title := fmt.Sprintf("%v's gator timeline", user.Name)
+	if params.Folder.Valid {
+		title += " - " + params.Folder.String
+	}
+	if params.Tag.Valid {
+		title += " #" + params.Tag.String
+	}
+
+	timeline := rss.Timeline{
+		ID:          "urn:uuid:" + user.ID.String(),
+		Title:       title,
+		Description: "Posts collected by gator from the feeds " + user.Name + " follows",
+		SelfURL:     self,
+		Updated:     time.Now(),
+	}
+	if len(posts) > 0 {
+		timeline.Updated = posts[0].CreatedAt
+	}
+
+	for _, post := range posts {
+		entry := rss.TimelineEntry{
+			ID:          "urn:uuid:" + post.ID.String(),
+			Title:       post.Title.String,
+			Link:        post.Url,
+			Author:      post.Author.String,
+			Summary:     post.Description.String,
+			Content:     post.Content.String,
+			Published:   post.CreatedAt,
+			Updated:     post.UpdatedAt,
+			SourceTitle: post.FeedName,
+			SourceURL:   post.FeedUrl,
+		}
+		if post.PublishedAt.Valid {
+			entry.Published = post.PublishedAt.Time
+		}
+		timeline.Entries = append(timeline.Entries, entry)
+	}
+
+	return timeline
+}
+
+// timelineETag changes whenever a post enters, leaves or is updated within the timeline
+func timelineETag(format string, params database.GetPostsForUserParams, posts []database.GetPostsForUserRow) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%v|%v|%v|%v\n", format, params.Folder.String, params.Tag.String, params.PostLimit)
+	for _, post := range posts {
+		fmt.Fprintf(hash, "%v|%v\n", post.ID, post.UpdatedAt.UnixNano())
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+func validFeedToken(user database.User, token string) bool {
+	if !user.FeedToken.Valid || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(user.FeedToken.String), []byte(token)) == 1
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return (&url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}).String()
+}
+
+func handlerFeedToken(s *state, cmd command, user database.User) error {
+	flags := flag.NewFlagSet("feedtoken", flag.ContinueOnError)
+	rotate := flags.Bool("rotate", false, "replace the current token, old feed urls stop working")
+	revoke := flags.Bool("revoke", false, "remove the token, disabling your published feeds")
+	host := flags.String("host", "http://localhost:8080", "address gator serve is reachable at, used to print the feed urls")
+
+	if err := flags.Parse(cmd.args); err != nil {
+		return err
+	}
+
+	params := database.SetUserFeedTokenParams{
+		ID:        user.ID,
+		FeedToken: user.FeedToken,
+	}
+	params.UpdatedAt.Scan(time.Now())
+
+	switch {
+	case *revoke:
+		params.FeedToken.Valid = false
+	case *rotate || !user.FeedToken.Valid:
+		token, err := newFeedToken()
+		if err != nil {
+			return err
+		}
+		params.FeedToken.Scan(token)
+	}
+
+	if params.FeedToken != user.FeedToken {
+		err := s.db.SetUserFeedToken(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("error updating feed token: %v", err)
+		}
+	}
+
+	if !params.FeedToken.Valid {
+		fmt.Println("Feed token revoked, your timeline is no longer published")
+		return nil
+	}
+
+	base := fmt.Sprintf("%v/users/%v", *host, url.PathEscape(user.Name))
+	token := url.QueryEscape(params.FeedToken.String)
+	fmt.Println("Your timeline is published by gator serve at:")
+	fmt.Printf("\tatom: %v/feed.atom?token=%v\n", base, token)
+	fmt.Printf("\trss:  %v/feed.rss?token=%v\n", base, token)
+	fmt.Println("Add &folder=[folder] or &tag=[tag] to either url to only include part of your timeline")
+	return nil
+}
+
+func newFeedToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("unable to generate token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
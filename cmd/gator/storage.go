@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/migrate"
+	"github.com/Andrew-The-Cat/gator/internal/sqlite"
+	"github.com/Andrew-The-Cat/gator/sql/schema"
+	_ "github.com/lib/pq"
+)
+
+// backend holds what differs between the databases gator can run on
+type backend struct {
+	name       string
+	dialect    migrate.Dialect
+	migrations fs.FS
+}
+
+var (
+	postgresBackend = backend{"postgres", migrate.Postgres, schema.Migrations}
+	sqliteBackend   = backend{"sqlite", migrate.SQLite, schema.SQLiteMigrations}
+)
+
+// openDatabase picks the backend from the scheme of db_url, sqlite:// followed by a
+// file path uses sqlite and anything else is handed to postgres
+func openDatabase(dbURL string) (*sql.DB, database.Store, backend, error) {
+	if path, ok := sqlitePath(dbURL); ok {
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return nil, nil, sqliteBackend, err
+		}
+		return db, sqlite.New(db), sqliteBackend, nil
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, nil, postgresBackend, err
+	}
+	return db, database.NewStore(db), postgresBackend, nil
+}
+
+func sqlitePath(dbURL string) (string, bool) {
+	path, ok := strings.CutPrefix(dbURL, "sqlite://")
+	if !ok {
+		path, ok = strings.CutPrefix(dbURL, "sqlite:")
+	}
+	if !ok {
+		return "", false
+	}
+
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	return path, true
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+func handlerUsers(s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		return listUsers(s)
+	}
+
+	switch cmd.args[0] {
+	case "list":
+		return listUsers(s)
+	case "show":
+		return showUser(s, cmd.args[1:])
+	case "rename":
+		return renameUser(s, cmd.args[1:])
+	case "delete":
+		return removeUser(s, cmd.args[1:])
+	default:
+		return fmt.Errorf("unknown users command %v, expected list, show, rename or delete", cmd.args[0])
+	}
+}
+
+func listUsers(s *state) error {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return err
+	}
+
+	records := make([]userRecord, 0, len(users))
+	for _, user := range users {
+		records = append(records, userRecord{
+			Name:      user.Name,
+			Current:   user.Name == s.cfg.User_name,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+
+	return printList(s, records, []column[userRecord]{
+		{"name", func(r userRecord) string { return r.Name }},
+		{"current", func(r userRecord) string { return strconv.FormatBool(r.Current) }},
+		{"created_at", func(r userRecord) string { return formatTime(&r.CreatedAt) }},
+	})
+}
+
+func showUser(s *state, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("command requires a username")
+	}
+
+	user, err := getUser(context.Background(), s.db, args[0])
+	if err != nil {
+		return err
+	}
+
+	stats, err := s.db.GetUserStats(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving user stats: %v", err)
+	}
+
+	fmt.Printf("Name:        %v\n", user.Name)
+	fmt.Printf("Created:     %v\n", formatTime(&user.CreatedAt))
+	fmt.Printf("Current:     %v\n", user.Name == s.cfg.User_name)
+	fmt.Printf("Following:   %v feed(s)\n", stats.Follows)
+	fmt.Printf("Unread:      %v post(s)\n", stats.Unread)
+	fmt.Printf("Feeds added: %v\n", stats.Feeds)
+	return nil
+}
+
+func renameUser(s *state, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("command requires the current and the new username")
+	}
+
+	newName := strings.TrimSpace(args[1])
+	if newName == "" {
+		return fmt.Errorf("username can't be empty")
+	}
+
+	user, err := getUser(context.Background(), s.db, args[0])
+	if err != nil {
+		return err
+	}
+	if err := authorize(s, user); err != nil {
+		return err
+	}
+
+	_, err = s.db.RenameUser(context.Background(), database.RenameUserParams{
+		ID:   user.ID,
+		Name: newName,
+		UpdatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+	})
+	if isUniqueViolation(err) {
+		return fmt.Errorf("user %v already exists", newName)
+	}
+	if err != nil {
+		return fmt.Errorf("error renaming user: %v", err)
+	}
+
+	if s.cfg.User_name == user.Name {
+		if err := s.cfg.SetUser(newName); err != nil {
+			return fmt.Errorf("received an error when trying to update username: %v", err)
+		}
+	}
+
+	fmt.Printf("User %v has been renamed to %v\n", user.Name, newName)
+	return nil
+}
+
+// removeUser deletes a user along with everything that's theirs. The feeds they added
+// go to the user given with --to, or otherwise to whoever has followed each of them
+// the longest, only feeds nobody else follows are deleted with them
+func removeUser(s *state, args []string) error {
+	flags := flag.NewFlagSet("users delete", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "don't ask for confirmation")
+	feeds := flags.Bool("feeds", false, "delete the feeds the user added instead of handing them over")
+	to := flags.String("to", "", "user the feeds the deleted user added are handed to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires a username")
+	}
+	if *feeds && *to != "" {
+		return fmt.Errorf("--feeds and --to can't be used together")
+	}
+
+	user, err := getUser(context.Background(), s.db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := authorize(s, user); err != nil {
+		return err
+	}
+
+	var heir database.User
+	if *to != "" {
+		heir, err = getUser(context.Background(), s.db, *to)
+		if err != nil {
+			return err
+		}
+		if heir.ID == user.ID {
+			return fmt.Errorf("can't hand %v's feeds to themselves", user.Name)
+		}
+	}
+
+	if !*yes {
+		switch {
+		case *feeds:
+			fmt.Printf("This will delete user %v, their follows and the feeds they added\n", user.Name)
+		case *to != "":
+			fmt.Printf("This will delete user %v and their follows, the feeds they added go to %v\n", user.Name, heir.Name)
+		default:
+			fmt.Printf("This will delete user %v and their follows, the feeds they added go to their followers\n", user.Name)
+		}
+		confirmed, err := confirm("Continue? [y/N] ")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Nothing was deleted")
+			return nil
+		}
+	}
+
+	var counts deletedCounts
+	var transferred int64
+	err = withTx(context.Background(), s, func(db database.Store) error {
+		if !*feeds {
+			var recipient *database.User
+			if *to != "" {
+				recipient = &heir
+			}
+			var err error
+			transferred, err = handOverFeeds(db, user, recipient)
+			if err != nil {
+				return err
+			}
+		}
+
+		return deleteUser(db, user, &counts)
+	})
+	if err != nil {
+		return fmt.Errorf("nothing was deleted: %v", err)
+	}
+
+	if s.cfg.User_name == user.Name {
+		if err := s.cfg.SetSession("", nil); err != nil {
+			return fmt.Errorf("received an error when trying to update username: %v", err)
+		}
+	}
+
+	if transferred > 0 {
+		fmt.Printf("Handed over %v feed(s)\n", transferred)
+	}
+	counts.print()
+	return nil
+}
+
+// handOverFeeds gives the feeds user added to heir, or without one to whoever has
+// followed each of them the longest, so deleting user doesn't take them from anyone
+// else. Feeds nobody else follows stay with user
+func handOverFeeds(db database.Store, user database.User, heir *database.User) (int64, error) {
+	var transferred int64
+	var err error
+	if heir != nil {
+		transferred, err = db.TransferFeeds(context.Background(), database.TransferFeedsParams{
+			ToUserID:   heir.ID,
+			UpdatedAt:  time.Now(),
+			FromUserID: user.ID,
+		})
+	} else {
+		transferred, err = db.TransferFeedsToFollowers(context.Background(), database.TransferFeedsToFollowersParams{
+			UserID:    user.ID,
+			UpdatedAt: time.Now(),
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("couldn't hand over feeds: %v", err)
+	}
+	return transferred, nil
+}
+
+// deleteUser deletes posts and follows before the feeds and user they'd cascade from,
+// so every row removed is counted. Feeds the user still owns are deleted with them
+func deleteUser(db database.Store, user database.User, counts *deletedCounts) error {
+	removed, err := db.DeletePostsForUserFeeds(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't delete posts: %v", err)
+	}
+	counts.posts += removed
+
+	removed, err = db.DeleteFeedFollowsInvolvingUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't delete feed follows: %v", err)
+	}
+	counts.follows += removed
+
+	removed, err = db.DeleteFeedsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't delete feeds: %v", err)
+	}
+	counts.feeds += removed
+
+	removed, err = db.DeleteUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't delete user: %v", err)
+	}
+	counts.users += removed
+	return nil
+}
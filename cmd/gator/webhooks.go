@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/export"
+	"github.com/Andrew-The-Cat/gator/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	// a delivery is attempted this many times before it's given up on
+	webhookAttempts = 5
+	// wait before the first retry, doubled after every failed attempt
+	webhookBackoff = 10 * time.Second
+	webhookTimeout = 15 * time.Second
+)
+
+func handlerWebhooks(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("command requires one of list, add, remove or log")
+	}
+
+	switch cmd.args[0] {
+	case "list":
+		return listWebhooks(s, user)
+	case "add":
+		return addWebhook(s, cmd.args[1:], user)
+	case "remove":
+		return removeWebhook(s, cmd.args[1:], user)
+	case "log":
+		return webhookLog(s, cmd.args[1:], user)
+	default:
+		return fmt.Errorf("unknown webhooks command %v, expected list, add, remove or log", cmd.args[0])
+	}
+}
+
+func listWebhooks(s *state, user database.User) error {
+	hooks, err := s.db.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving webhooks: %v", err)
+	}
+
+	records := make([]webhookRecord, 0, len(hooks))
+	for _, hook := range hooks {
+		records = append(records, webhookRecord{
+			ID:      hook.ID.String(),
+			URL:     hook.Url,
+			Feed:    hook.FeedUrl.String,
+			Keyword: hook.Keyword.String,
+			Signed:  hook.Secret.Valid,
+		})
+	}
+
+	return printList(s, records, []column[webhookRecord]{
+		{"id", func(r webhookRecord) string { return r.ID }},
+		{"url", func(r webhookRecord) string { return r.URL }},
+		{"feed", func(r webhookRecord) string { return r.Feed }},
+		{"keyword", func(r webhookRecord) string { return r.Keyword }},
+		{"signed", func(r webhookRecord) string { return strconv.FormatBool(r.Signed) }},
+	})
+}
+
+func addWebhook(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("webhooks add", flag.ContinueOnError)
+	secret := flags.String("secret", "", "sign deliveries with this secret")
+	feed := flags.String("feed", "", "only send posts from the followed feed with this url")
+	keyword := flags.String("keyword", "", "only send posts whose title or description contain this keyword")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) != 1 {
+		return fmt.Errorf("command requires the url deliveries are sent to")
+	}
+
+	target, err := url.Parse(flags.Arg(0))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%v is not an http(s) url", flags.Arg(0))
+	}
+
+	params := database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Url:       target.String(),
+		Secret:    nullString(*secret),
+		Keyword:   nullString(strings.TrimSpace(*keyword)),
+	}
+
+	if *feed != "" {
+		targetFeed, err := s.db.GetFeedByUrl(context.Background(), *feed)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no feed found at %v", *feed)
+		}
+		if err != nil {
+			return fmt.Errorf("error retrieving requested feed: %v", err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: targetFeed.ID, Valid: true}
+	}
+
+	hook, err := s.db.CreateWebhook(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("error creating webhook: %v", err)
+	}
+
+	fmt.Println("Successfuly added webhook:")
+	fmt.Printf("\tid: %v | url: %v\n", hook.ID, hook.Url)
+	if !hook.Secret.Valid {
+		fmt.Println("Deliveries won't be signed, pass --secret to let the receiver verify them")
+	}
+	return nil
+}
+
+func removeWebhook(s *state, args []string, user database.User) error {
+	if len(args) != 1 {
+		return fmt.Errorf("command requires the id of the webhook to remove")
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid webhook id: %v", err)
+	}
+
+	affected, err := s.db.DeleteWebhook(context.Background(), database.DeleteWebhookParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error removing webhook: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("you have no webhook %v", id)
+	}
+
+	fmt.Println("Webhook has been removed")
+	return nil
+}
+
+func webhookLog(s *state, args []string, user database.User) error {
+	flags := flag.NewFlagSet("webhooks log", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "number of deliveries to show")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.Args()) > 1 {
+		return fmt.Errorf("command takes at most the id of a single webhook")
+	}
+
+	params := database.GetWebhookDeliveriesParams{
+		UserID:        user.ID,
+		DeliveryLimit: int32(*limit),
+	}
+	if len(flags.Args()) == 1 {
+		id, err := uuid.Parse(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid webhook id: %v", err)
+		}
+		params.WebhookID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	deliveries, err := s.db.GetWebhookDeliveries(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("error retrieving deliveries: %v", err)
+	}
+
+	records := make([]deliveryRecord, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		record := deliveryRecord{
+			WebhookID: delivery.WebhookID.String(),
+			SentAt:    delivery.CreatedAt,
+			Attempt:   int(delivery.Attempt),
+			Posts:     int(delivery.PostCount),
+			Succeeded: delivery.Succeeded,
+			Error:     delivery.Error.String,
+		}
+		if delivery.StatusCode.Valid {
+			record.Status = int(delivery.StatusCode.Int32)
+		}
+		records = append(records, record)
+	}
+
+	return printList(s, records, []column[deliveryRecord]{
+		{"webhook_id", func(r deliveryRecord) string { return r.WebhookID }},
+		{"sent_at", func(r deliveryRecord) string { return formatTime(&r.SentAt) }},
+		{"attempt", func(r deliveryRecord) string { return strconv.Itoa(r.Attempt) }},
+		{"posts", func(r deliveryRecord) string { return strconv.Itoa(r.Posts) }},
+		{"status", func(r deliveryRecord) string { return strconv.Itoa(r.Status) }},
+		{"error", func(r deliveryRecord) string { return r.Error }},
+	})
+}
+
+// notifyWebhooks sends the posts just fetched from a feed to every matching webhook of the
+// users following it, leaving out posts their rules just hid. Deliveries are stored before they're attempted so one that hasn't
+// gone through survives agg stopping, and run in the background so slow receivers don't
+// hold up agg
+func notifyWebhooks(s *state, feed database.Feed, posts []database.Post, hidden map[postHide]bool) {
+	if len(posts) == 0 {
+		return
+	}
+
+	hooks, err := s.db.GetWebhooksForFeed(context.Background(), feed.ID)
+	if err != nil {
+		fmt.Printf("\twarning: couldn't retrieve webhooks: %v\n", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		payload := webhook.Payload{
+			Event:      webhook.EventPostsCreated,
+			DeliveryID: uuid.New().String(),
+			WebhookID:  hook.ID.String(),
+			User:       hook.UserName,
+			Feed:       webhook.Feed{Name: hook.FeedName, URL: feed.Url},
+		}
+		for _, post := range posts {
+			if webhookMatches(hook, post) && !hidden[postHide{user: hook.UserID, post: post.ID}] {
+				payload.Posts = append(payload.Posts, webhookPost(hook, feed, post))
+			}
+		}
+		if len(payload.Posts) == 0 {
+			continue
+		}
+
+		pending, err := queueWebhook(s, hook, payload)
+		if err != nil {
+			fmt.Printf("\twarning: couldn't queue webhook delivery to %v: %v\n", hook.Url, err)
+			continue
+		}
+		go deliverWebhook(s, pending)
+	}
+}
+
+func webhookMatches(hook database.GetWebhooksForFeedRow, post database.Post) bool {
+	if !hook.Keyword.Valid {
+		return true
+	}
+	keyword := strings.ToLower(hook.Keyword.String)
+	return strings.Contains(strings.ToLower(post.Title.String), keyword) ||
+		strings.Contains(strings.ToLower(post.Description.String), keyword)
+}
+
+// webhookPost names the feed the way the webhook's owner does, custom title and all
+func webhookPost(hook database.GetWebhooksForFeedRow, feed database.Feed, post database.Post) export.Post {
+	out := export.Post{
+		ID:          post.ID.String(),
+		Title:       post.Title.String,
+		URL:         post.Url,
+		Feed:        hook.FeedName,
+		FeedURL:     feed.Url,
+		Author:      post.Author.String,
+		FetchedAt:   post.CreatedAt,
+		Description: post.Description.String,
+		Content:     post.Content.String,
+	}
+	if post.PublishedAt.Valid {
+		out.PublishedAt = &post.PublishedAt.Time
+	}
+	return out
+}
+
+// queueWebhook stores a delivery that's due right away
+func queueWebhook(s *state, hook database.GetWebhooksForFeedRow, payload webhook.Payload) (database.GetDueWebhookRetriesRow, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return database.GetDueWebhookRetriesRow{}, fmt.Errorf("couldn't encode webhook payload: %v", err)
+	}
+
+	pending := database.GetDueWebhookRetriesRow{
+		ID:        uuid.MustParse(payload.DeliveryID),
+		CreatedAt: time.Now(),
+		WebhookID: hook.ID,
+		Payload:   string(body),
+		RetryAt:   time.Now(),
+		Url:       hook.Url,
+		Secret:    hook.Secret,
+	}
+	err = s.db.CreateWebhookRetry(context.Background(), database.CreateWebhookRetryParams{
+		ID:        pending.ID,
+		CreatedAt: pending.CreatedAt,
+		WebhookID: pending.WebhookID,
+		Payload:   pending.Payload,
+		Attempt:   pending.Attempt,
+		RetryAt:   pending.RetryAt,
+	})
+	return pending, err
+}
+
+// retryWebhooks picks up every delivery whose next attempt is due, including those
+// left behind by an agg that stopped before they went through
+func retryWebhooks(s *state) {
+	due, err := s.db.GetDueWebhookRetries(context.Background(), time.Now())
+	if err != nil {
+		fmt.Printf("\twarning: couldn't retrieve webhook retries: %v\n", err)
+		return
+	}
+
+	for _, pending := range due {
+		go deliverWebhook(s, pending)
+	}
+}
+
+// webhookRetryDelay is how long to wait after the given attempt fails before the next one
+func webhookRetryDelay(attempt int32) time.Duration {
+	return webhookBackoff << (attempt - 1)
+}
+
+// deliverWebhook retries with a growing delay until the receiver accepts the payload,
+// logging every attempt. Each attempt is claimed in the database first so a delivery is
+// never attempted twice at once, and is left there for retryWebhooks until it's done
+func deliverWebhook(s *state, pending database.GetDueWebhookRetriesRow) {
+	var payload webhook.Payload
+	if err := json.Unmarshal([]byte(pending.Payload), &payload); err != nil {
+		fmt.Printf("\twarning: couldn't decode webhook payload: %v\n", err)
+		finishWebhook(s, pending)
+		return
+	}
+
+	for {
+		attempt := pending.Attempt + 1
+		retryAt := time.Now().Add(webhookTimeout + webhookRetryDelay(attempt))
+		claimed, err := s.db.ClaimWebhookRetry(context.Background(), database.ClaimWebhookRetryParams{
+			RetryAt: retryAt,
+			ID:      pending.ID,
+			Attempt: pending.Attempt,
+		})
+		if err != nil {
+			fmt.Printf("\twarning: couldn't claim webhook delivery: %v\n", err)
+			return
+		}
+		if claimed == 0 {
+			return
+		}
+		pending.Attempt = attempt
+
+		if attempt > webhookAttempts {
+			// the last attempt never finished, most likely because agg stopped during it
+			logWebhookAttempt(s, pending, webhookAttempts, len(payload.Posts), 0, fmt.Errorf("gave up after %v attempts", webhookAttempts))
+			finishWebhook(s, pending)
+			return
+		}
+
+		payload.SentAt = time.Now().UTC()
+		body, err := json.Marshal(payload)
+		if err != nil {
+			fmt.Printf("\twarning: couldn't encode webhook payload: %v\n", err)
+			finishWebhook(s, pending)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+		status, err := webhook.Send(ctx, pending.Url, pending.Secret.String, payload.Event, payload.DeliveryID, body)
+		cancel()
+
+		logWebhookAttempt(s, pending, attempt, len(payload.Posts), status, err)
+
+		if err == nil {
+			finishWebhook(s, pending)
+			return
+		}
+		fmt.Printf("\twarning: webhook delivery to %v failed (attempt %v of %v): %v\n", pending.Url, attempt, webhookAttempts, err)
+
+		// the receiver rejected the payload itself, sending it again won't change that
+		if status >= 400 && status < 500 && status != 408 && status != 429 {
+			finishWebhook(s, pending)
+			return
+		}
+		if attempt == webhookAttempts {
+			finishWebhook(s, pending)
+			return
+		}
+
+		time.Sleep(time.Until(retryAt))
+	}
+}
+
+func logWebhookAttempt(s *state, pending database.GetDueWebhookRetriesRow, attempt int32, posts int, status int, err error) {
+	record := database.CreateWebhookDeliveryParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		WebhookID: pending.WebhookID,
+		Attempt:   attempt,
+		PostCount: int32(posts),
+		Succeeded: err == nil,
+	}
+	if status != 0 {
+		record.StatusCode = sql.NullInt32{Int32: int32(status), Valid: true}
+	}
+	if err != nil {
+		record.Error = nullString(err.Error())
+	}
+	if logErr := s.db.CreateWebhookDelivery(context.Background(), record); logErr != nil {
+		fmt.Printf("\twarning: couldn't log webhook delivery: %v\n", logErr)
+	}
+}
+
+// finishWebhook forgets a delivery that went through or won't be attempted again
+func finishWebhook(s *state, pending database.GetDueWebhookRetriesRow) {
+	if err := s.db.DeleteWebhookRetry(context.Background(), pending.ID); err != nil {
+		fmt.Printf("\twarning: couldn't clear webhook delivery: %v\n", err)
+	}
+}
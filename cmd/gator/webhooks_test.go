@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/export"
+	"github.com/Andrew-The-Cat/gator/internal/webhook"
+)
+
+// receiver answers deliveries with status and keeps the payloads it was sent
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	payloads []webhook.Payload
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var payload webhook.Payload
+	json.NewDecoder(req.Body).Decode(&payload)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, payload)
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) received() []webhook.Payload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]webhook.Payload(nil), r.payloads...)
+}
+
+// webhookSetup is alice following a feed with 2 posts and a webhook pointing at a receiver
+func webhookSetup(t *testing.T, status int) (*state, *receiver, database.GetWebhooksForFeedRow, []database.Post) {
+	t.Helper()
+	s := newTestState(t)
+	r := &receiver{status: status}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Example", "https://example.com/feed.xml")
+	mustRun(t, s, "webhooks", "add", server.URL)
+
+	feed := mustGetFeed(t, s, "https://example.com/feed.xml")
+	posts := addPosts(t, s, feed, 2)
+	hooks, err := s.db.GetWebhooksForFeed(context.Background(), feed.ID)
+	if err != nil || len(hooks) != 1 {
+		t.Fatalf("expected alice's webhook, got %v (%v)", hooks, err)
+	}
+	return s, r, hooks[0], posts
+}
+
+// waitForDeliveries waits until n attempts have been logged for the webhook
+func waitForDeliveries(t *testing.T, s *state, hook database.GetWebhooksForFeedRow, n int) []database.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := s.db.GetWebhookDeliveries(context.Background(), database.GetWebhookDeliveriesParams{
+			UserID:        hook.UserID,
+			DeliveryLimit: 50,
+		})
+		if err != nil {
+			t.Fatalf("reading delivery log: %v", err)
+		}
+		if len(deliveries) >= n {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %v logged attempts, got %v", n, len(deliveries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// pendingDeliveries is every delivery still waiting, due or not
+func pendingDeliveries(t *testing.T, s *state) []database.GetDueWebhookRetriesRow {
+	t.Helper()
+	pending, err := s.db.GetDueWebhookRetries(context.Background(), time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("reading pending deliveries: %v", err)
+	}
+	return pending
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	s, r, hook, posts := webhookSetup(t, http.StatusOK)
+
+	notifyWebhooks(s, mustGetFeed(t, s, "https://example.com/feed.xml"), posts, nil)
+	deliveries := waitForDeliveries(t, s, hook, 1)
+
+	if !deliveries[0].Succeeded || deliveries[0].Attempt != 1 || deliveries[0].PostCount != 2 {
+		t.Fatalf("unexpected delivery %+v", deliveries[0])
+	}
+	if got := r.received(); len(got) != 1 || len(got[0].Posts) != 2 || got[0].Feed.Name != "Example" {
+		t.Fatalf("unexpected payloads %+v", got)
+	}
+	if pending := pendingDeliveries(t, s); len(pending) != 0 {
+		t.Fatalf("a delivery that went through shouldn't be kept, got %+v", pending)
+	}
+}
+
+func TestWebhookRetriesSurviveAgg(t *testing.T) {
+	t.Run("queued but never attempted", func(t *testing.T) {
+		s, r, hook, posts := webhookSetup(t, http.StatusOK)
+		payload := webhook.Payload{
+			Event:      webhook.EventPostsCreated,
+			DeliveryID: "6a4d4bb4-4fd6-4a49-9e63-cb5ec3c2b3a4",
+			WebhookID:  hook.ID.String(),
+			Posts:      []export.Post{webhookPost(hook, database.Feed{}, posts[0])},
+		}
+		// agg stopping right after queueing is the same as never starting the attempt
+		if _, err := queueWebhook(s, hook, payload); err != nil {
+			t.Fatalf("queueing delivery: %v", err)
+		}
+
+		retryWebhooks(s)
+		deliveries := waitForDeliveries(t, s, hook, 1)
+		if !deliveries[0].Succeeded {
+			t.Fatalf("expected the queued delivery to go through, got %+v", deliveries[0])
+		}
+		if got := r.received(); len(got) != 1 || got[0].DeliveryID != payload.DeliveryID {
+			t.Fatalf("expected the queued delivery to keep its id, got %+v", got)
+		}
+		if pending := pendingDeliveries(t, s); len(pending) != 0 {
+			t.Fatalf("expected nothing left to retry, got %+v", pending)
+		}
+	})
+
+	t.Run("failed attempt waits for the next one", func(t *testing.T) {
+		s, r, hook, posts := webhookSetup(t, http.StatusServiceUnavailable)
+		notifyWebhooks(s, mustGetFeed(t, s, "https://example.com/feed.xml"), posts, nil)
+
+		deliveries := waitForDeliveries(t, s, hook, 1)
+		if deliveries[0].Succeeded || deliveries[0].StatusCode.Int32 != http.StatusServiceUnavailable {
+			t.Fatalf("unexpected delivery %+v", deliveries[0])
+		}
+		pending := pendingDeliveries(t, s)
+		if len(pending) != 1 || pending[0].Attempt != 1 || !pending[0].RetryAt.After(time.Now()) {
+			t.Fatalf("expected the delivery kept for a later attempt, got %+v", pending)
+		}
+
+		// it isn't due yet, so a new agg doesn't send it again straight away
+		retryWebhooks(s)
+		time.Sleep(50 * time.Millisecond)
+		if got := r.received(); len(got) != 1 {
+			t.Fatalf("expected a single attempt so far, got %v", len(got))
+		}
+	})
+
+	t.Run("rejected deliveries aren't retried", func(t *testing.T) {
+		s, _, hook, posts := webhookSetup(t, http.StatusBadRequest)
+		notifyWebhooks(s, mustGetFeed(t, s, "https://example.com/feed.xml"), posts, nil)
+
+		waitForDeliveries(t, s, hook, 1)
+		deadline := time.Now().Add(5 * time.Second)
+		for len(pendingDeliveries(t, s)) != 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("expected a rejected delivery to be dropped")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("cut short final attempt is logged as given up", func(t *testing.T) {
+		s, r, hook, posts := webhookSetup(t, http.StatusOK)
+		pending, err := queueWebhook(s, hook, webhook.Payload{
+			Event:      webhook.EventPostsCreated,
+			DeliveryID: "0f0e8f0e-5d1e-4a4c-9a0e-6c1d8b0b9c55",
+			Posts:      []export.Post{webhookPost(hook, database.Feed{}, posts[0])},
+		})
+		if err != nil {
+			t.Fatalf("queueing delivery: %v", err)
+		}
+		// every attempt was claimed but agg stopped during the last one
+		for attempt := int32(0); attempt < webhookAttempts; attempt++ {
+			_, err := s.db.ClaimWebhookRetry(context.Background(), database.ClaimWebhookRetryParams{
+				RetryAt: time.Now().Add(-time.Minute),
+				ID:      pending.ID,
+				Attempt: attempt,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		retryWebhooks(s)
+		deliveries := waitForDeliveries(t, s, hook, 1)
+		if deliveries[0].Succeeded || deliveries[0].Attempt != webhookAttempts || deliveries[0].Error.String == "" {
+			t.Fatalf("expected a failed final attempt in the log, got %+v", deliveries[0])
+		}
+		if len(r.received()) != 0 {
+			t.Fatal("nothing should be sent past the last attempt")
+		}
+		if pending := pendingDeliveries(t, s); len(pending) != 0 {
+			t.Fatalf("expected the delivery to be given up on, got %+v", pending)
+		}
+	})
+}
+
+func TestWebhooksLeaveOutHiddenPosts(t *testing.T) {
+	s, r, hook, posts := webhookSetup(t, http.StatusOK)
+	mustRun(t, s, "rules", "add", "title", "post 0", "hide")
+
+	feed := mustGetFeed(t, s, "https://example.com/feed.xml")
+	hidden := applyRulesToNewPosts(s, feed, posts, nil)
+	if !hidden[postHide{user: hook.UserID, post: posts[0].ID}] || len(hidden) != 1 {
+		t.Fatalf("expected only post 0 hidden, got %v", hidden)
+	}
+
+	notifyWebhooks(s, feed, posts, hidden)
+	waitForDeliveries(t, s, hook, 1)
+	got := r.received()
+	if len(got) != 1 || len(got[0].Posts) != 1 || got[0].Posts[0].URL != posts[1].Url {
+		t.Fatalf("expected only the post that wasn't hidden, got %+v", got)
+	}
+
+	// nothing is sent when every new post was hidden
+	notifyWebhooks(s, feed, posts[:1], hidden)
+	if pending := pendingDeliveries(t, s); len(pending) != 0 {
+		t.Fatalf("expected no delivery for hidden posts, got %+v", pending)
+	}
+}
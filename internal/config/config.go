@@ -2,12 +2,71 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"sort"
+	"time"
 )
 
+// the settings at the top level of the config file are this profile
+const DefaultProfile = "default"
+
+// Config is the settings of a single profile
 type Config struct {
 	Conn_str 	string 	`json:"db_url"`
 	User_name 	string 	`json:"current_user_name"`
+	SMTP 		*SMTP 	`json:"smtp,omitempty"`
+	Retention 	*Retention 	`json:"retention,omitempty"`
+	Session 	*Session 	`json:"session,omitempty"`
+	Fetch 		*Fetch 	`json:"fetch,omitempty"`
+
+	// the profile these settings were read from, changes are written back to it
+	Profile 	string 	`json:"-"`
+}
+
+// gator_file is the whole config file, configs from before profiles existed are
+// read as just the default profile
+type gator_file struct {
+	Config
+	Current_profile 	string 	`json:"current_profile,omitempty"`
+	Profiles 	map[string]Config 	`json:"profiles,omitempty"`
+}
+
+// SMTP is the mail server digests are sent through
+type SMTP struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
+	// connect over tls straight away (usually port 465) instead of upgrading with STARTTLS
+	TLS bool `json:"tls,omitempty"`
+}
+
+// Retention is how long posts are kept for feeds that don't set their own, 0 keeps
+// them forever. Only posts every follower has read and nobody has starred or tagged
+// are ever removed
+type Retention struct {
+	MaxAgeDays      int `json:"max_age_days,omitempty"`
+	MaxPostsPerFeed int `json:"max_posts_per_feed,omitempty"`
+}
+
+// Fetch is how gator agg fetches feeds, durations are written like 30s, 5m or 1h
+type Fetch struct {
+	// time between requests when agg isn't given one
+	Interval string `json:"interval,omitempty"`
+	// how long a feed gets to answer before it's skipped until its next turn, no limit
+	// when empty
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Session is what's left of logging in as a user with a password, the database only
+// keeps a hash of the token so it can't be read back out of there
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 func get_gator_path() string {
@@ -15,33 +74,167 @@ func get_gator_path() string {
 	return home_path + "/.gatorconfig.json"
 }
 
-func Read() (Config, error) {
+func read_file() (gator_file, error) {
 	json_data, err := os.ReadFile( get_gator_path() )
 
 	if err != nil {
-		return Config{}, err
+		return gator_file{}, err
 	}
 
-	var returned Config
+	var returned gator_file
 
 	if err := json.Unmarshal(json_data, &returned); err != nil {
-		return Config{}, err
+		return gator_file{}, err
 	}
 	return returned, nil
 }
 
-func (c Config) SetUser(user_name string) error {
-	c.User_name = user_name
+// write saves the whole file. It holds database passwords and login tokens, so only
+// its owner may read it, files written before that was the case are tightened too
+func (f gator_file) write() error {
+	json_data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
 
-	json_data, err := json.Marshal(c)
+	err = os.WriteFile(get_gator_path(), json_data, 0600)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(get_gator_path(), json_data, os.ModeDevice)
+	return os.Chmod(get_gator_path(), 0600)
+}
+
+// Read returns the settings of the named profile, or of the one picked with
+// `config profiles use` when profile is empty
+func Read(profile string) (Config, error) {
+	contents, err := read_file()
+	if err != nil {
+		return Config{}, err
+	}
+
+	if profile == "" {
+		profile = contents.Current_profile
+	}
+	if profile == "" || profile == DefaultProfile {
+		returned := contents.Config
+		returned.Profile = DefaultProfile
+		return returned, nil
+	}
+
+	returned, ok := contents.Profiles[profile]
+	if !ok {
+		return Config{}, fmt.Errorf("no profile named %v", profile)
+	}
+	returned.Profile = profile
+	return returned, nil
+}
+
+// Profiles returns every profile by name, along with the one `config profiles use`
+// picked
+func Profiles() (string, map[string]Config, error) {
+	contents, err := read_file()
+	if err != nil {
+		return "", nil, err
+	}
+
+	profiles := map[string]Config{DefaultProfile: contents.Config}
+	for name, profile := range contents.Profiles {
+		profiles[name] = profile
+	}
+
+	current := contents.Current_profile
+	if current == "" {
+		current = DefaultProfile
+	}
+	return current, profiles, nil
+}
+
+// ProfileNames sorts the names of profiles with the default one first
+func ProfileNames(profiles map[string]Config) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if _, ok := profiles[DefaultProfile]; ok {
+		names = append([]string{DefaultProfile}, names...)
+	}
+	return names
+}
+
+// UseProfile makes the named profile the one used when neither --profile nor
+// GATOR_PROFILE are given
+func UseProfile(profile string) error {
+	contents, err := read_file()
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
\ No newline at end of file
+	if profile == DefaultProfile {
+		contents.Current_profile = ""
+		return contents.write()
+	}
+	if _, ok := contents.Profiles[profile]; !ok {
+		return fmt.Errorf("no profile named %v", profile)
+	}
+
+	contents.Current_profile = profile
+	return contents.write()
+}
+
+// AddProfile creates a new profile, the config file is created if there isn't one yet
+func AddProfile(profile string, settings Config) error {
+	contents, err := read_file()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if _, ok := contents.Profiles[profile]; ok || profile == DefaultProfile {
+		return fmt.Errorf("profile %v already exists", profile)
+	}
+
+	if contents.Profiles == nil {
+		contents.Profiles = make(map[string]Config)
+	}
+	settings.Profile = ""
+	contents.Profiles[profile] = settings
+	return contents.write()
+}
+
+func (c Config) SetUser(user_name string) error {
+	c.User_name = user_name
+	return c.write()
+}
+
+// SetSession logs in as user_name, a nil session is used for users without a password
+func (c Config) SetSession(user_name string, session *Session) error {
+	c.User_name = user_name
+	c.Session = session
+	return c.write()
+}
+
+// write saves c as its profile, the file is read again first so the other profiles
+// are kept as they are
+func (c Config) write() error {
+	contents, err := read_file()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	profile := c.Profile
+	c.Profile = ""
+	if profile == "" || profile == DefaultProfile {
+		contents.Config = c
+		return contents.write()
+	}
+
+	if contents.Profiles == nil {
+		contents.Profiles = make(map[string]Config)
+	}
+	contents.Profiles[profile] = c
+	return contents.write()
+}
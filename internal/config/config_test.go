@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteKeepsFilePrivate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := AddProfile("staging", Config{Conn_str: "postgres://staging"}); err != nil {
+		t.Fatalf("adding profile: %v", err)
+	}
+	info, err := os.Stat(get_gator_path())
+	if err != nil {
+		t.Fatalf("config file wasn't created: %v", err)
+	}
+	if mode := info.Mode(); mode != 0600 {
+		t.Fatalf("expected a new config file to be 0600, got %v", mode)
+	}
+
+	// files from before gator cared are tightened the next time they're written
+	if err := os.Chmod(get_gator_path(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := UseProfile("staging"); err != nil {
+		t.Fatalf("switching profile: %v", err)
+	}
+	info, err = os.Stat(get_gator_path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode(); mode != 0600 {
+		t.Fatalf("expected an existing config file to become 0600, got %v", mode)
+	}
+}
+
+func TestProfilesKeepTheirOwnSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	base := Config{
+		Conn_str: "postgres://mine",
+		Fetch:    &Fetch{Interval: "1m"},
+		Profile:  DefaultProfile,
+	}
+	if err := base.SetUser("alice"); err != nil {
+		t.Fatalf("writing default profile: %v", err)
+	}
+	err := AddProfile("staging", Config{
+		Conn_str: "postgres://staging",
+		Fetch:    &Fetch{Interval: "1h", Timeout: "30s"},
+	})
+	if err != nil {
+		t.Fatalf("adding profile: %v", err)
+	}
+
+	staging, err := Read("staging")
+	if err != nil {
+		t.Fatalf("reading staging: %v", err)
+	}
+	if staging.Profile != "staging" || staging.Fetch == nil || staging.Fetch.Interval != "1h" || staging.Fetch.Timeout != "30s" {
+		t.Fatalf("unexpected staging settings %+v", staging)
+	}
+	if err := staging.SetUser("bob"); err != nil {
+		t.Fatalf("logging in on staging: %v", err)
+	}
+
+	// nothing was picked with use yet, so the default profile is read
+	mine, err := Read("")
+	if err != nil {
+		t.Fatalf("reading default profile: %v", err)
+	}
+	if mine.Profile != DefaultProfile || mine.User_name != "alice" || mine.Fetch.Interval != "1m" {
+		t.Fatalf("the default profile shouldn't change with staging, got %+v", mine)
+	}
+
+	if _, err := Read("missing"); err == nil {
+		t.Fatal("reading an unknown profile should fail")
+	}
+}
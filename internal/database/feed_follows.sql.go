@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,17 +15,19 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, custom_name, folder)
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
+        $5,
+        $6,
+        $7
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, custom_name, folder
 )
-SELECT inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+SELECT inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.custom_name, inserted_feed_follow.folder,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -35,21 +38,25 @@ ON users.id = inserted_feed_follow.user_id
 `
 
 type CreateFeedFollowParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	CustomName sql.NullString
+	Folder     sql.NullString
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	CustomName sql.NullString
+	Folder     sql.NullString
+	FeedName   string
+	UserName   string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -59,6 +66,8 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.CustomName,
+		arg.Folder,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -67,13 +76,15 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.CustomName,
+		&i.Folder,
 		&i.FeedName,
 		&i.UserName,
 	)
 	return i, err
 }
 
-const deleteFeedFollowForUser = `-- name: DeleteFeedFollowForUser :exec
+const deleteFeedFollowForUser = `-- name: DeleteFeedFollowForUser :execrows
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1
 AND feed_follows.feed_id = 
@@ -87,35 +98,46 @@ type DeleteFeedFollowForUserParams struct {
 	Url    string
 }
 
-func (q *Queries) DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFollowForUserParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollowForUser, arg.UserID, arg.Url)
-	return err
+func (q *Queries) DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFollowForUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowForUser, arg.UserID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const feedFollowsReset = `-- name: FeedFollowsReset :exec
+const feedFollowsReset = `-- name: FeedFollowsReset :execrows
 DELETE FROM feed_follows *
 `
 
-func (q *Queries) FeedFollowsReset(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, feedFollowsReset)
-	return err
+func (q *Queries) FeedFollowsReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, feedFollowsReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-feeds.name AS feed_name,
-feeds.url AS feed_url
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+feeds.site_url,
+feed_follows.folder
 FROM feed_follows
 INNER JOIN users
 ON users.id = feed_follows.user_id
 INNER JOIN feeds
 ON feeds.id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.folder NULLS FIRST, feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
 	FeedName string
 	FeedUrl  string
+	SiteUrl  sql.NullString
+	Folder   sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -127,7 +149,100 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedName, &i.FeedUrl); err != nil {
+		if err := rows.Scan(
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.SiteUrl,
+			&i.Folder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFollowName = `-- name: SetFeedFollowName :execrows
+UPDATE feed_follows
+SET custom_name = $1,
+    updated_at = $2
+WHERE feed_follows.user_id = $3
+AND feed_follows.feed_id =
+    (SELECT id FROM feeds
+    WHERE url = $4
+    )
+`
+
+type SetFeedFollowNameParams struct {
+	CustomName sql.NullString
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Url        string
+}
+
+func (q *Queries) SetFeedFollowName(ctx context.Context, arg SetFeedFollowNameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowName,
+		arg.CustomName,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Url,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getSubscriptionsForUser = `-- name: GetSubscriptionsForUser :many
+SELECT
+feeds.id,
+feeds.url,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.site_url,
+feeds.last_fetched_at,
+feed_follows.folder,
+feed_follows.created_at
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_name
+`
+
+type GetSubscriptionsForUserRow struct {
+	ID            uuid.UUID
+	Url           string
+	FeedName      string
+	SiteUrl       sql.NullString
+	LastFetchedAt sql.NullTime
+	Folder        sql.NullString
+	CreatedAt     time.Time
+}
+
+func (q *Queries) GetSubscriptionsForUser(ctx context.Context, userID uuid.UUID) ([]GetSubscriptionsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSubscriptionsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSubscriptionsForUserRow
+	for rows.Next() {
+		var i GetSubscriptionsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.FeedName,
+			&i.SiteUrl,
+			&i.LastFetchedAt,
+			&i.Folder,
+			&i.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -140,3 +255,70 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder = $1,
+    updated_at = $2
+WHERE feed_follows.user_id = $3
+AND feed_follows.feed_id =
+    (SELECT id FROM feeds
+    WHERE url = $4
+    )
+`
+
+type SetFeedFollowFolderParams struct {
+	Folder    sql.NullString
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.Folder,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Url,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedFollowsInvolvingUser = `-- name: DeleteFeedFollowsInvolvingUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1
+OR feed_id IN (
+    SELECT id FROM feeds
+    WHERE feeds.user_id = $1
+)
+`
+
+// the user's own follows and every follow of a feed they added
+func (q *Queries) DeleteFeedFollowsInvolvingUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsInvolvingUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countOtherFeedFollowers = `-- name: CountOtherFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1
+AND user_id != $2
+`
+
+type CountOtherFeedFollowersParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CountOtherFeedFollowers(ctx context.Context, arg CountOtherFeedFollowersParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherFeedFollowers, arg.FeedID, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
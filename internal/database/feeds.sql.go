@@ -14,16 +14,17 @@ import (
 )
 
 const addFeed = `-- name: AddFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id) 
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_url) 
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts
 `
 
 type AddFeedParams struct {
@@ -33,6 +34,7 @@ type AddFeedParams struct {
 	Name      string
 	Url       string
 	UserID    uuid.UUID
+	SiteUrl   sql.NullString
 }
 
 func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error) {
@@ -43,6 +45,7 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.SiteUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -53,21 +56,28 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
-const feedsReset = `-- name: FeedsReset :exec
+const feedsReset = `-- name: FeedsReset :execrows
 DELETE FROM feeds *
 `
 
-func (q *Queries) FeedsReset(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, feedsReset)
-	return err
+func (q *Queries) FeedsReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, feedsReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts FROM feeds
 WHERE url = $1
 `
 
@@ -82,6 +92,10 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
@@ -122,7 +136,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts FROM feeds
 ORDER BY last_fetched_at ASC
 NULLS FIRST
 LIMIT 1
@@ -139,16 +153,85 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
+const listFeeds = `-- name: ListFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.fetch_full_content, feeds.site_url, feeds.retention_max_age_days, feeds.retention_max_posts, users.name AS user_name FROM feeds
+INNER JOIN users
+ON users.id = feeds.user_id
+ORDER BY feeds.created_at, feeds.id
+LIMIT $1
+OFFSET $2
+`
+
+type ListFeedsParams struct {
+	Limit  int32
+	Offset int32
+}
+
+type ListFeedsRow struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	FetchFullContent    bool
+	SiteUrl             sql.NullString
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
+	UserName            string
+}
+
+func (q *Queries) ListFeeds(ctx context.Context, arg ListFeedsParams) ([]ListFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeds, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFeedsRow
+	for rows.Next() {
+		var i ListFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.SiteUrl,
+			&i.RetentionMaxAgeDays,
+			&i.RetentionMaxPosts,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :one
 UPDATE feeds
 SET last_fetched_at = $2,
     updated_at = $2
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts
 `
 
 type MarkFeedFetchedParams struct {
@@ -167,6 +250,251 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
+
+const setFeedFullContent = `-- name: SetFeedFullContent :execrows
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = $3
+WHERE url = $1
+`
+
+type SetFeedFullContentParams struct {
+	Url              string
+	FetchFullContent bool
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) SetFeedFullContent(ctx context.Context, arg SetFeedFullContentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFullContent, arg.Url, arg.FetchFullContent, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedSiteUrl = `-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET site_url = $2
+WHERE id = $1
+`
+
+type SetFeedSiteUrlParams struct {
+	ID      uuid.UUID
+	SiteUrl sql.NullString
+}
+
+func (q *Queries) SetFeedSiteUrl(ctx context.Context, arg SetFeedSiteUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteUrl, arg.ID, arg.SiteUrl)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts FROM feeds
+ORDER BY name, url
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.SiteUrl,
+			&i.RetentionMaxAgeDays,
+			&i.RetentionMaxPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :execrows
+UPDATE feeds
+SET retention_max_age_days = $2,
+    retention_max_posts = $3,
+    updated_at = $4
+WHERE url = $1
+`
+
+type SetFeedRetentionParams struct {
+	Url                 string
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
+	UpdatedAt           time.Time
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedRetention,
+		arg.Url,
+		arg.RetentionMaxAgeDays,
+		arg.RetentionMaxPosts,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedsForUser = `-- name: DeleteFeedsForUser :execrows
+DELETE FROM feeds
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteFeedsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedsForUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = $1,
+    updated_at = $2
+WHERE user_id = $3
+`
+
+type TransferFeedsParams struct {
+	ToUserID   uuid.UUID
+	UpdatedAt  time.Time
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds,
+		arg.ToUserID,
+		arg.UpdatedAt,
+		arg.FromUserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFeedsToFollowers = `-- name: TransferFeedsToFollowers :execrows
+UPDATE feeds
+SET user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        AND feed_follows.user_id != $1
+        ORDER BY feed_follows.created_at, feed_follows.id
+        LIMIT 1
+    ),
+    updated_at = $2
+WHERE user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id != $1
+)
+`
+
+type TransferFeedsToFollowersParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+}
+
+// hands each feed the user added to whoever has followed it the longest, feeds
+// nobody else follows are left alone
+func (q *Queries) TransferFeedsToFollowers(ctx context.Context, arg TransferFeedsToFollowersParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeedsToFollowers, arg.UserID, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFeed = `-- name: RenameFeed :execrows
+UPDATE feeds
+SET name = $2,
+    updated_at = $3
+WHERE id = $1
+`
+
+type RenameFeedParams struct {
+	ID        uuid.UUID
+	Name      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameFeed,
+		arg.ID,
+		arg.Name,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :execrows
+UPDATE feeds
+SET url = $2,
+    last_fetched_at = NULL,
+    updated_at = $3
+WHERE id = $1
+`
+
+type SetFeedUrlParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+// the feed is fetched again straight away from its new url
+func (q *Queries) SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedUrl,
+		arg.ID,
+		arg.Url,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -12,21 +12,27 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	FetchFullContent    bool
+	SiteUrl             sql.NullString
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
 }
 
 type FeedFollow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	CustomName sql.NullString
+	Folder     sql.NullString
 }
 
 type Post struct {
@@ -38,11 +44,101 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+}
+
+type PostCategory struct {
+	PostID   uuid.UUID
+	Category string
+}
+
+type PostHide struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	RuleID   uuid.UUID
+	HiddenAt time.Time
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type PostTag struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type Rule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Field     string
+	Pattern   string
+	Regex     bool
+	Action    string
+	Tag       sql.NullString
+}
+
+type Session struct {
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UserID    uuid.UUID
 }
 
 type User struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     sql.NullTime
+	Name          string
+	FeedToken     sql.NullString
+	ApiPassword   sql.NullString
+	Email         sql.NullString
+	LastDigestAt  sql.NullTime
+	PasswordHash  sql.NullString
+	LastDigestSeq sql.NullInt64
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+	Secret    sql.NullString
+	FeedID    uuid.NullUUID
+	Keyword   sql.NullString
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	WebhookID  uuid.UUID
+	Attempt    int32
+	PostCount  int32
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+	Succeeded  bool
+}
+
+type WebhookRetry struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt sql.NullTime
-	Name      string
+	WebhookID uuid.UUID
+	Payload   string
+	Attempt   int32
+	RetryAt   time.Time
 }
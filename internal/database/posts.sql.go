@@ -11,8 +11,137 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const browsePostsForUser = `-- name: BrowsePostsForUser :many
+WITH timeline AS (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+    COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    post_reads.read_at,
+    (CASE WHEN $1::boolean
+        THEN COALESCE(posts.published_at, posts.created_at)
+        ELSE posts.created_at
+    END)::timestamp AS sort_key
+    FROM posts
+    INNER JOIN feed_follows
+    ON feed_follows.feed_id = posts.feed_id
+    INNER JOIN feeds
+    ON feeds.id = posts.feed_id
+    LEFT JOIN post_reads
+    ON post_reads.post_id = posts.id
+    AND post_reads.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = posts.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+    AND ($3::text IS NULL
+        OR feeds.url = $3
+        OR feeds.name = $3
+        OR feed_follows.custom_name = $3)
+    AND (NOT $4::boolean OR post_reads.read_at IS NULL)
+)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, seq, feed_name, feed_url, read_at, sort_key FROM timeline
+WHERE ($5::timestamp IS NULL OR sort_key >= $5)
+AND ($6::timestamp IS NULL OR sort_key < $6)
+AND ($7::timestamp IS NULL
+    OR ($8::boolean AND (sort_key, id) > ($7, $9::uuid))
+    OR (NOT $8 AND (sort_key, id) < ($7, $9::uuid)))
+ORDER BY
+    CASE WHEN $8 THEN sort_key END ASC,
+    CASE WHEN $8 THEN id END ASC,
+    CASE WHEN NOT $8 THEN sort_key END DESC,
+    CASE WHEN NOT $8 THEN id END DESC
+LIMIT $10
+OFFSET $11
+`
+
+type BrowsePostsForUserParams struct {
+	ByPublished bool
+	UserID      uuid.UUID
+	Feed        sql.NullString
+	UnreadOnly  bool
+	Since       sql.NullTime
+	Until       sql.NullTime
+	CursorKey   sql.NullTime
+	Ascending   bool
+	CursorID    uuid.NullUUID
+	PageLimit   int32
+	PageOffset  int32
+}
+
+type BrowsePostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	ReadAt      sql.NullTime
+	SortKey     time.Time
+}
+
+func (q *Queries) BrowsePostsForUser(ctx context.Context, arg BrowsePostsForUserParams) ([]BrowsePostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsForUser,
+		arg.ByPublished,
+		arg.UserID,
+		arg.Feed,
+		arg.UnreadOnly,
+		arg.Since,
+		arg.Until,
+		arg.CursorKey,
+		arg.Ascending,
+		arg.CursorID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsForUserRow
+	for rows.Next() {
+		var i BrowsePostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.ReadAt,
+			&i.SortKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
     id,
@@ -22,7 +151,9 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    author,
+    content
 )
 VALUES (
     $1,
@@ -32,9 +163,11 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, seq
 `
 
 type CreatePostParams struct {
@@ -46,6 +179,8 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -58,6 +193,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Content,
 	)
 	var i Post
 	err := row.Scan(
@@ -69,33 +206,248 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const exportPostsForUser = `-- name: ExportPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+post_reads.read_at,
+post_stars.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND ($2::text IS NULL
+    OR feeds.url = $2
+    OR feeds.name = $2
+    OR feed_follows.custom_name = $2)
+AND ($3::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $3)
+AND ($4::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $4)
+AND (NOT $5::boolean OR post_stars.starred_at IS NOT NULL)
+AND ($6::timestamp IS NULL
+    OR (posts.created_at, posts.id) > ($6, $7::uuid))
+ORDER BY posts.created_at, posts.id
+LIMIT $8
+`
+
+type ExportPostsForUserParams struct {
+	UserID         uuid.UUID
+	Feed           sql.NullString
+	Since          sql.NullTime
+	Until          sql.NullTime
+	StarredOnly    bool
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	BatchSize      int32
+}
+
+type ExportPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+func (q *Queries) ExportPostsForUser(ctx context.Context, arg ExportPostsForUserParams) ([]ExportPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, exportPostsForUser,
+		arg.UserID,
+		arg.Feed,
+		arg.Since,
+		arg.Until,
+		arg.StarredOnly,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportPostsForUserRow
+	for rows.Next() {
+		var i ExportPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+post_reads.read_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE posts.id = $1
+AND feed_follows.user_id = $2
+`
+
+type GetPostForUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+type GetPostForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	ReadAt      sql.NullTime
+}
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.ID, arg.UserID)
+	var i GetPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Seq,
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.ReadAt,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url
+FROM posts
 INNER JOIN feed_follows
 ON feed_follows.feed_id = posts.feed_id
-WHERE user_id = $1
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND ($2::text IS NULL
+    OR feed_follows.folder = $2
+    OR substr(feed_follows.folder, 1, length($2) + 1) = $2 || '/')
+AND ($3::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag = $3
+    ))
 ORDER BY posts.created_at DESC
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID    uuid.UUID
+	Folder    sql.NullString
+	Tag       sql.NullString
+	PostLimit int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Folder,
+		arg.Tag,
+		arg.PostLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -105,6 +457,87 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2,
+    updated_at = $3
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID        uuid.UUID
+	Content   sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content, arg.UpdatedAt)
+	return err
+}
+
+const countUnreadPostsByFeed = `-- name: CountUnreadPostsByFeed :many
+SELECT feeds.url AS feed_url,
+feed_follows.folder,
+COUNT(posts.id) AS unread,
+MAX(posts.created_at)::timestamp AS newest
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND post_reads.read_at IS NULL
+GROUP BY feeds.url, feed_follows.folder
+`
+
+type CountUnreadPostsByFeedRow struct {
+	FeedUrl string
+	Folder  sql.NullString
+	Unread  int64
+	Newest  time.Time
+}
+
+func (q *Queries) CountUnreadPostsByFeed(ctx context.Context, userID uuid.UUID) ([]CountUnreadPostsByFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, countUnreadPostsByFeed, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountUnreadPostsByFeedRow
+	for rows.Next() {
+		var i CountUnreadPostsByFeedRow
+		if err := rows.Scan(
+			&i.FeedUrl,
+			&i.Folder,
+			&i.Unread,
+			&i.Newest,
 		); err != nil {
 			return nil, err
 		}
@@ -118,3 +551,539 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const listSyncPostRefs = `-- name: ListSyncPostRefs :many
+SELECT posts.seq,
+posts.created_at,
+feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND ($2::text IS NULL OR feeds.url = $2)
+AND ($3::text IS NULL OR feed_follows.folder = $3)
+AND (NOT $4::boolean OR post_reads.read_at IS NULL)
+AND (NOT $5::boolean OR post_reads.read_at IS NOT NULL)
+AND (NOT $6::boolean OR post_stars.starred_at IS NOT NULL)
+AND ($7::timestamp IS NULL OR posts.created_at >= $7)
+AND ($8::timestamp IS NULL OR posts.created_at < $8)
+AND ($9::bigint IS NULL OR posts.seq > $9)
+AND ($10::bigint IS NULL OR posts.seq < $10)
+ORDER BY
+    CASE WHEN $11::boolean THEN posts.seq END ASC,
+    posts.seq DESC
+LIMIT $12
+`
+
+type ListSyncPostRefsParams struct {
+	UserID      uuid.UUID
+	FeedUrl     sql.NullString
+	Folder      sql.NullString
+	UnreadOnly  bool
+	ReadOnly    bool
+	StarredOnly bool
+	Since       sql.NullTime
+	Until       sql.NullTime
+	AfterSeq    sql.NullInt64
+	BeforeSeq   sql.NullInt64
+	Ascending   bool
+	ItemLimit   int32
+}
+
+type ListSyncPostRefsRow struct {
+	Seq       int64
+	CreatedAt time.Time
+	FeedUrl   string
+}
+
+func (q *Queries) ListSyncPostRefs(ctx context.Context, arg ListSyncPostRefsParams) ([]ListSyncPostRefsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSyncPostRefs,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.Folder,
+		arg.UnreadOnly,
+		arg.ReadOnly,
+		arg.StarredOnly,
+		arg.Since,
+		arg.Until,
+		arg.AfterSeq,
+		arg.BeforeSeq,
+		arg.Ascending,
+		arg.ItemLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSyncPostRefsRow
+	for rows.Next() {
+		var i ListSyncPostRefsRow
+		if err := rows.Scan(&i.Seq, &i.CreatedAt, &i.FeedUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncPosts = `-- name: ListSyncPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+feeds.site_url AS feed_site_url,
+feed_follows.folder,
+post_reads.read_at,
+post_stars.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND ($2::text IS NULL OR feeds.url = $2)
+AND ($3::text IS NULL OR feed_follows.folder = $3)
+AND ($4::bigint[] IS NULL OR posts.seq = ANY($4::bigint[]))
+AND (NOT $5::boolean OR post_reads.read_at IS NULL)
+AND (NOT $6::boolean OR post_reads.read_at IS NOT NULL)
+AND (NOT $7::boolean OR post_stars.starred_at IS NOT NULL)
+AND ($8::timestamp IS NULL OR posts.created_at >= $8)
+AND ($9::timestamp IS NULL OR posts.created_at < $9)
+AND ($10::bigint IS NULL OR posts.seq > $10)
+AND ($11::bigint IS NULL OR posts.seq < $11)
+ORDER BY
+    CASE WHEN $12::boolean THEN posts.seq END ASC,
+    posts.seq DESC
+LIMIT $13
+`
+
+type ListSyncPostsParams struct {
+	UserID      uuid.UUID
+	FeedUrl     sql.NullString
+	Folder      sql.NullString
+	Seqs        []int64
+	UnreadOnly  bool
+	ReadOnly    bool
+	StarredOnly bool
+	Since       sql.NullTime
+	Until       sql.NullTime
+	AfterSeq    sql.NullInt64
+	BeforeSeq   sql.NullInt64
+	Ascending   bool
+	ItemLimit   int32
+}
+
+type ListSyncPostsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl sql.NullString
+	Folder      sql.NullString
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+func (q *Queries) ListSyncPosts(ctx context.Context, arg ListSyncPostsParams) ([]ListSyncPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSyncPosts,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.Folder,
+		pq.Array(arg.Seqs),
+		arg.UnreadOnly,
+		arg.ReadOnly,
+		arg.StarredOnly,
+		arg.Since,
+		arg.Until,
+		arg.AfterSeq,
+		arg.BeforeSeq,
+		arg.Ascending,
+		arg.ItemLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSyncPostsRow
+	for rows.Next() {
+		var i ListSyncPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
+			&i.Folder,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+`
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getDigestPostsForUser = `-- name: GetDigestPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND post_reads.read_at IS NULL
+AND ($2::timestamp IS NULL
+    OR posts.created_at > $2
+    OR (posts.created_at = $2
+        AND ($3::bigint IS NULL OR posts.seq > $3)))
+AND posts.created_at < $4::timestamp
+ORDER BY posts.created_at, posts.seq
+LIMIT $5
+`
+
+type GetDigestPostsForUserParams struct {
+	UserID    uuid.UUID
+	Since     sql.NullTime
+	SinceSeq  sql.NullInt64
+	Until     time.Time
+	PostLimit int32
+}
+
+type GetDigestPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Content     sql.NullString
+	Seq         int64
+	FeedName    string
+}
+
+// unread posts oldest first, a digest that hits its limit leaves the rest to the next
+// one which starts after the last post sent, by fetch time and then seq
+func (q *Queries) GetDigestPostsForUser(ctx context.Context, arg GetDigestPostsForUserParams) ([]GetDigestPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPostsForUser,
+		arg.UserID,
+		arg.Since,
+		arg.SinceSeq,
+		arg.Until,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDigestPostsForUserRow
+	for rows.Next() {
+		var i GetDigestPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRuleCandidatesForUser = `-- name: GetRuleCandidatesForUser :many
+SELECT posts.id,
+posts.seq,
+posts.title,
+posts.description,
+posts.author,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+ARRAY(
+    SELECT post_categories.category FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    ORDER BY post_categories.category
+)::text[] AS categories
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND posts.seq > $2
+ORDER BY posts.seq
+LIMIT $3
+`
+
+type GetRuleCandidatesForUserParams struct {
+	UserID    uuid.UUID
+	AfterSeq  int64
+	BatchSize int32
+}
+
+type GetRuleCandidatesForUserRow struct {
+	ID          uuid.UUID
+	Seq         int64
+	Title       sql.NullString
+	Description sql.NullString
+	Author      sql.NullString
+	FeedName    string
+	FeedUrl     string
+	Categories  []string
+}
+
+func (q *Queries) GetRuleCandidatesForUser(ctx context.Context, arg GetRuleCandidatesForUserParams) ([]GetRuleCandidatesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRuleCandidatesForUser, arg.UserID, arg.AfterSeq, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRuleCandidatesForUserRow
+	for rows.Next() {
+		var i GetRuleCandidatesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Seq,
+			&i.Title,
+			&i.Description,
+			&i.Author,
+			&i.FeedName,
+			&i.FeedUrl,
+			pq.Array(&i.Categories),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+SELECT ranked.id, ranked.seq, ranked.url
+FROM (
+    SELECT posts.id, posts.seq, posts.url, posts.created_at,
+    ROW_NUMBER() OVER (ORDER BY posts.created_at DESC, posts.seq DESC) AS position
+    FROM posts
+    WHERE posts.feed_id = $1
+) AS ranked
+WHERE ranked.seq > $2
+AND (
+    ranked.created_at < $3
+    OR ranked.position > $4
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = $1
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = ranked.id
+        AND post_reads.user_id = feed_follows.user_id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = ranked.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+)
+ORDER BY ranked.seq
+LIMIT $5
+`
+
+type GetPrunablePostsParams struct {
+	FeedID    uuid.UUID
+	AfterSeq  int64
+	Before    sql.NullTime
+	Keep      sql.NullInt64
+	BatchSize int32
+}
+
+type GetPrunablePostsRow struct {
+	ID  uuid.UUID
+	Seq int64
+	Url string
+}
+
+// posts past a feed's retention that every follower has read or hidden and nobody
+// has starred or tagged, oldest first
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.FeedID,
+		arg.AfterSeq,
+		arg.Before,
+		arg.Keep,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(&i.ID, &i.Seq, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeletePosts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const postsReset = `-- name: PostsReset :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) PostsReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, postsReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostsForUserFeeds = `-- name: DeletePostsForUserFeeds :execrows
+DELETE FROM posts
+WHERE feed_id IN (
+    SELECT id FROM feeds
+    WHERE user_id = $1
+)
+`
+
+func (q *Queries) DeletePostsForUserFeeds(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsForUserFeeds, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
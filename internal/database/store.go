@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The repositories split Querier by what the queries are about, so code that only
+// deals with one part of gator can ask for just that part and be handed a fake in tests
+
+// UserRepository covers accounts and the per user settings stored with them
+type UserRepository interface {
+	CreateSession(ctx context.Context, arg CreateSessionParams) error
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	// DeleteSessionsForUser logs a user out everywhere, used when their password changes
+	DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) (int64, error)
+	GetSession(ctx context.Context, arg GetSessionParams) (Session, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUserStats(ctx context.Context, userID uuid.UUID) (GetUserStatsRow, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	RenameUser(ctx context.Context, arg RenameUserParams) (int64, error)
+	SetUserApiPassword(ctx context.Context, arg SetUserApiPasswordParams) error
+	SetUserEmail(ctx context.Context, arg SetUserEmailParams) error
+	SetUserFeedToken(ctx context.Context, arg SetUserFeedTokenParams) error
+	// where the next digest starts, the seq is only set when a digest was cut short and
+	// the next one starts part way through the posts fetched at last_digest_at
+	SetUserLastDigest(ctx context.Context, arg SetUserLastDigestParams) error
+	SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error
+	UsersReset(ctx context.Context) (int64, error)
+}
+
+// FeedRepository covers the feeds shared between every user and their fetch state
+type FeedRepository interface {
+	AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
+	DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error)
+	DeleteFeedsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	FeedsReset(ctx context.Context) (int64, error)
+	GetAllFeeds(ctx context.Context) ([]Feed, error)
+	GetFeedByUrl(ctx context.Context, url string) (Feed, error)
+	GetFeeds(ctx context.Context) ([]GetFeedsRow, error)
+	GetNextFeedToFetch(ctx context.Context) (Feed, error)
+	ListFeeds(ctx context.Context, arg ListFeedsParams) ([]ListFeedsRow, error)
+	MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error)
+	RenameFeed(ctx context.Context, arg RenameFeedParams) (int64, error)
+	SetFeedFullContent(ctx context.Context, arg SetFeedFullContentParams) (int64, error)
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (int64, error)
+	SetFeedSiteUrl(ctx context.Context, arg SetFeedSiteUrlParams) error
+	// the feed is fetched again straight away from its new url
+	SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) (int64, error)
+	TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error)
+	// hands each feed the user added to whoever has followed it the longest, feeds
+	// nobody else follows are left alone
+	TransferFeedsToFollowers(ctx context.Context, arg TransferFeedsToFollowersParams) (int64, error)
+}
+
+// FollowRepository covers which users follow which feeds, and the titles and folders they give them
+type FollowRepository interface {
+	CountOtherFeedFollowers(ctx context.Context, arg CountOtherFeedFollowersParams) (int64, error)
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFollowForUserParams) (int64, error)
+	// the user's own follows and every follow of a feed they added
+	DeleteFeedFollowsInvolvingUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	FeedFollowsReset(ctx context.Context) (int64, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	GetSubscriptionsForUser(ctx context.Context, userID uuid.UUID) ([]GetSubscriptionsForUserRow, error)
+	SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error)
+	SetFeedFollowName(ctx context.Context, arg SetFeedFollowNameParams) (int64, error)
+}
+
+// SubscriptionRepository is what adding or following a feed needs, the feed itself
+// and the follow tying a user to it
+type SubscriptionRepository interface {
+	FeedRepository
+	FollowRepository
+}
+
+// PostRepository covers fetched posts and what each user has read, starred, tagged or hidden
+type PostRepository interface {
+	BrowsePostsForUser(ctx context.Context, arg BrowsePostsForUserParams) ([]BrowsePostsForUserRow, error)
+	CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUnreadPostsByFeed(ctx context.Context, userID uuid.UUID) ([]CountUnreadPostsByFeedRow, error)
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error
+	CreatePrunedPosts(ctx context.Context, arg CreatePrunedPostsParams) error
+	DeletePosts(ctx context.Context, ids []uuid.UUID) (int64, error)
+	DeletePostsForUserFeeds(ctx context.Context, userID uuid.UUID) (int64, error)
+	ExportPostsForUser(ctx context.Context, arg ExportPostsForUserParams) ([]ExportPostsForUserRow, error)
+	// unread posts oldest first, a digest that hits its limit leaves the rest to the next
+	// one which starts after the last post sent, by fetch time and then seq
+	GetDigestPostsForUser(ctx context.Context, arg GetDigestPostsForUserParams) ([]GetDigestPostsForUserRow, error)
+	GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error)
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+	// posts past a feed's retention that every follower has read or hidden and nobody
+	// has starred or tagged, oldest first
+	GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error)
+	GetRuleCandidatesForUser(ctx context.Context, arg GetRuleCandidatesForUserParams) ([]GetRuleCandidatesForUserRow, error)
+	HidePost(ctx context.Context, arg HidePostParams) error
+	ListSyncPostRefs(ctx context.Context, arg ListSyncPostRefsParams) ([]ListSyncPostRefsRow, error)
+	ListSyncPosts(ctx context.Context, arg ListSyncPostsParams) ([]ListSyncPostsRow, error)
+	MarkPostRead(ctx context.Context, arg MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) (int64, error)
+	MarkPostsReadBefore(ctx context.Context, arg MarkPostsReadBeforeParams) (int64, error)
+	MarkPostsReadBySeq(ctx context.Context, arg MarkPostsReadBySeqParams) (int64, error)
+	MarkPostsUnreadBySeq(ctx context.Context, arg MarkPostsUnreadBySeqParams) (int64, error)
+	PostWasPruned(ctx context.Context, url string) (bool, error)
+	PostsReset(ctx context.Context) (int64, error)
+	PrunedPostsReset(ctx context.Context) error
+	SetPostContent(ctx context.Context, arg SetPostContentParams) error
+	StarPost(ctx context.Context, arg StarPostParams) error
+	StarPostsBySeq(ctx context.Context, arg StarPostsBySeqParams) (int64, error)
+	TagPost(ctx context.Context, arg TagPostParams) error
+	UnstarPost(ctx context.Context, arg UnstarPostParams) (int64, error)
+	UnstarPostsBySeq(ctx context.Context, arg UnstarPostsBySeqParams) (int64, error)
+	UntagPost(ctx context.Context, arg UntagPostParams) (int64, error)
+}
+
+// RuleRepository covers the filter rules users set up
+type RuleRepository interface {
+	CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error)
+	DeleteRule(ctx context.Context, arg DeleteRuleParams) (int64, error)
+	GetRuleForUser(ctx context.Context, arg GetRuleForUserParams) (Rule, error)
+	GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetRulesForFeedRow, error)
+	GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]Rule, error)
+}
+
+// WebhookRepository covers webhooks, the log of their deliveries and the deliveries
+// waiting to be retried
+type WebhookRepository interface {
+	// takes the next attempt of a delivery, nothing is claimed when someone else already
+	// made it. retry_at moves to when the attempt after it is due should this one never finish
+	ClaimWebhookRetry(ctx context.Context, arg ClaimWebhookRetryParams) (int64, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error
+	// a delivery waiting for its next attempt, kept in the database so agg stopping doesn't
+	// lose it
+	CreateWebhookRetry(ctx context.Context, arg CreateWebhookRetryParams) error
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	DeleteWebhookRetry(ctx context.Context, id uuid.UUID) error
+	GetDueWebhookRetries(ctx context.Context, retryAt time.Time) ([]GetDueWebhookRetriesRow, error)
+	GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	GetWebhooksForFeed(ctx context.Context, feedID uuid.UUID) ([]GetWebhooksForFeedRow, error)
+	GetWebhooksForUser(ctx context.Context, userID uuid.UUID) ([]GetWebhooksForUserRow, error)
+}
+
+// Store is everything gator asks of its database. The generated Queries back it for
+// postgres and the sqlite package implements it for sqlite
+type Store interface {
+	UserRepository
+	FeedRepository
+	FollowRepository
+	PostRepository
+	RuleRepository
+	WebhookRepository
+	// WithTx runs every query of the returned store in tx
+	WithTx(tx *sql.Tx) Store
+}
+
+// the repositories have to cover every generated query and nothing else
+var (
+	_ Querier = Store(nil)
+	_ Store   = postgresStore{}
+)
+
+// NewStore wraps the generated postgres queries
+func NewStore(db DBTX) Store {
+	return postgresStore{New(db)}
+}
+
+type postgresStore struct {
+	*Queries
+}
+
+func (s postgresStore) WithTx(tx *sql.Tx) Store {
+	return postgresStore{s.Queries.WithTx(tx)}
+}
@@ -0,0 +1,736 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/migrate"
+	"github.com/Andrew-The-Cat/gator/internal/sqlite"
+	"github.com/Andrew-The-Cat/gator/sql/schema"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// the suite runs against postgres too when this points at a database it may wipe
+const postgresURLEnv = "GATOR_TEST_POSTGRES_URL"
+
+var start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func hours(n int) time.Time {
+	return start.Add(time.Duration(n) * time.Hour)
+}
+
+// backends returns a constructor for an empty store per database the suite runs against
+func backends(t *testing.T) map[string]func(t *testing.T) database.Store {
+	stores := map[string]func(t *testing.T) database.Store{
+		"sqlite": func(t *testing.T) database.Store {
+			conn, db, err := sqlite.OpenMemory(context.Background())
+			if err != nil {
+				t.Fatalf("opening in-memory store: %v", err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			return db
+		},
+	}
+
+	dbURL := os.Getenv(postgresURLEnv)
+	if dbURL == "" {
+		t.Logf("%v isn't set, only running against sqlite", postgresURLEnv)
+		return stores
+	}
+
+	stores["postgres"] = func(t *testing.T) database.Store {
+		conn, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			t.Fatalf("connecting to postgres: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		migrations, err := migrate.Load(schema.Migrations)
+		if err != nil {
+			t.Fatalf("reading migrations: %v", err)
+		}
+		// rolling everything back and up again leaves empty tables behind
+		ctx := context.Background()
+		if _, err := migrate.Down(ctx, conn, migrate.Postgres, migrations, 0); err != nil {
+			t.Fatalf("clearing postgres: %v", err)
+		}
+		if _, err := migrate.Up(ctx, conn, migrate.Postgres, migrations, 0); err != nil {
+			t.Fatalf("migrating postgres: %v", err)
+		}
+		return database.NewStore(conn)
+	}
+	return stores
+}
+
+// fixture is what every case starts with. alice follows Alpha, named Mine in her news
+// folder, and Beta. bob only follows Beta. Posts alternate between the two feeds an
+// hour apart, a0 at 00:00 up to a3 at 06:00, so their seqs follow the same order.
+// a0 claims to be published at 07:00, later than everything else
+type fixture struct {
+	db    database.Store
+	alice database.User
+	bob   database.User
+	alpha database.Feed
+	beta  database.Feed
+	posts map[string]database.Post
+	names map[uuid.UUID]string
+}
+
+func newFixture(t *testing.T, db database.Store) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &fixture{
+		db:    db,
+		posts: map[string]database.Post{},
+		names: map[uuid.UUID]string{},
+	}
+
+	user := func(name string) database.User {
+		user, err := db.CreateUser(ctx, database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: start,
+			UpdatedAt: sql.NullTime{Time: start, Valid: true},
+			Name:      name,
+		})
+		if err != nil {
+			t.Fatalf("creating user %v: %v", name, err)
+		}
+		return user
+	}
+	f.alice = user("alice")
+	f.bob = user("bob")
+
+	feed := func(name, url string, owner database.User) database.Feed {
+		feed, err := db.AddFeed(ctx, database.AddFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: start,
+			UpdatedAt: start,
+			Name:      name,
+			Url:       url,
+			UserID:    owner.ID,
+		})
+		if err != nil {
+			t.Fatalf("adding feed %v: %v", name, err)
+		}
+		return feed
+	}
+	f.alpha = feed("Alpha", "https://alpha.example.com/feed.xml", f.alice)
+	f.beta = feed("Beta", "https://beta.example.com/feed.xml", f.bob)
+
+	follow := func(user database.User, feed database.Feed, name, folder string) {
+		_, err := db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:         uuid.New(),
+			CreatedAt:  start,
+			UpdatedAt:  start,
+			UserID:     user.ID,
+			FeedID:     feed.ID,
+			CustomName: sql.NullString{String: name, Valid: name != ""},
+			Folder:     sql.NullString{String: folder, Valid: folder != ""},
+		})
+		if err != nil {
+			t.Fatalf("following %v: %v", feed.Name, err)
+		}
+	}
+	follow(f.alice, f.alpha, "Mine", "news")
+	follow(f.alice, f.beta, "", "")
+	follow(f.bob, f.beta, "", "")
+
+	for i, name := range []string{"a0", "b0", "a1", "b1", "a2", "b2", "a3"} {
+		feed, published := f.alpha, hours(i)
+		if name[0] == 'b' {
+			feed = f.beta
+		}
+		if name == "a0" {
+			published = hours(7)
+		}
+		f.addPost(t, name, feed, hours(i), published)
+	}
+	return f
+}
+
+func (f *fixture) addPost(t *testing.T, name string, feed database.Feed, created, published time.Time) {
+	t.Helper()
+	post, err := f.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   created,
+		UpdatedAt:   created,
+		Title:       sql.NullString{String: name, Valid: true},
+		Url:         fmt.Sprintf("%v/%v", feed.Url, name),
+		PublishedAt: sql.NullTime{Time: published, Valid: true},
+		FeedID:      feed.ID,
+	})
+	if err != nil {
+		t.Fatalf("creating post %v: %v", name, err)
+	}
+	f.posts[name] = post
+	f.names[post.ID] = name
+}
+
+func (f *fixture) seqs(names ...string) []int64 {
+	seqs := make([]int64, 0, len(names))
+	for _, name := range names {
+		seqs = append(seqs, f.posts[name].Seq)
+	}
+	return seqs
+}
+
+func (f *fixture) markRead(t *testing.T, user database.User, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		err := f.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: f.posts[name].ID,
+			ReadAt: hours(10),
+		})
+		if err != nil {
+			t.Fatalf("marking %v read: %v", name, err)
+		}
+	}
+}
+
+func (f *fixture) hide(t *testing.T, user database.User, names ...string) {
+	t.Helper()
+	ctx := context.Background()
+	rule, err := f.db.CreateRule(ctx, database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: start,
+		UpdatedAt: start,
+		UserID:    user.ID,
+		Field:     "title",
+		Pattern:   "hidden",
+		Action:    "hide",
+	})
+	if err != nil {
+		t.Fatalf("creating rule: %v", err)
+	}
+	for _, name := range names {
+		err := f.db.HidePost(ctx, database.HidePostParams{
+			UserID:   user.ID,
+			PostID:   f.posts[name].ID,
+			RuleID:   rule.ID,
+			HiddenAt: hours(10),
+		})
+		if err != nil {
+			t.Fatalf("hiding %v: %v", name, err)
+		}
+	}
+}
+
+func (f *fixture) browse(t *testing.T, arg database.BrowsePostsForUserParams) []database.BrowsePostsForUserRow {
+	t.Helper()
+	if arg.UserID == uuid.Nil {
+		arg.UserID = f.alice.ID
+	}
+	if arg.PageLimit == 0 {
+		arg.PageLimit = 100
+	}
+	rows, err := f.db.BrowsePostsForUser(context.Background(), arg)
+	if err != nil {
+		t.Fatalf("browsing posts: %v", err)
+	}
+	return rows
+}
+
+func (f *fixture) sync(t *testing.T, arg database.ListSyncPostsParams) []string {
+	t.Helper()
+	if arg.UserID == uuid.Nil {
+		arg.UserID = f.alice.ID
+	}
+	if arg.ItemLimit == 0 {
+		arg.ItemLimit = 100
+	}
+	rows, err := f.db.ListSyncPosts(context.Background(), arg)
+	if err != nil {
+		t.Fatalf("listing sync posts: %v", err)
+	}
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, f.names[row.ID])
+	}
+
+	// the refs query takes the same filters apart from seqs and must agree with it
+	if arg.Seqs == nil {
+		refs, err := f.db.ListSyncPostRefs(context.Background(), database.ListSyncPostRefsParams{
+			UserID:      arg.UserID,
+			FeedUrl:     arg.FeedUrl,
+			Folder:      arg.Folder,
+			UnreadOnly:  arg.UnreadOnly,
+			ReadOnly:    arg.ReadOnly,
+			StarredOnly: arg.StarredOnly,
+			Since:       arg.Since,
+			Until:       arg.Until,
+			AfterSeq:    arg.AfterSeq,
+			BeforeSeq:   arg.BeforeSeq,
+			Ascending:   arg.Ascending,
+			ItemLimit:   arg.ItemLimit,
+		})
+		if err != nil {
+			t.Fatalf("listing sync refs: %v", err)
+		}
+		for i, ref := range refs {
+			if i >= len(rows) || ref.Seq != rows[i].Seq {
+				t.Fatalf("refs %+v don't match posts %v", refs, names)
+			}
+		}
+		if len(refs) != len(rows) {
+			t.Fatalf("got %v refs for %v posts", len(refs), len(rows))
+		}
+	}
+	return names
+}
+
+func (f *fixture) prunable(t *testing.T, arg database.GetPrunablePostsParams) []string {
+	t.Helper()
+	if arg.BatchSize == 0 {
+		arg.BatchSize = 100
+	}
+	rows, err := f.db.GetPrunablePosts(context.Background(), arg)
+	if err != nil {
+		t.Fatalf("listing prunable posts: %v", err)
+	}
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, f.names[row.ID])
+	}
+	return names
+}
+
+func (f *fixture) browseNames(rows []database.BrowsePostsForUserRow) []string {
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, f.names[row.ID])
+	}
+	return names
+}
+
+func expect(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Fatalf("%v: expected %v, got %v", what, want, got)
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func nullInt(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: true}
+}
+
+var storeCases = []struct {
+	name string
+	run  func(t *testing.T, f *fixture)
+}{
+	{"browse pages newest first by cursor", func(t *testing.T, f *fixture) {
+		for _, ascending := range []bool{false, true} {
+			var seen []string
+			arg := database.BrowsePostsForUserParams{Ascending: ascending, PageLimit: 3}
+			for page := 0; page < 5; page++ {
+				rows := f.browse(t, arg)
+				if len(rows) == 0 {
+					break
+				}
+				seen = append(seen, f.browseNames(rows)...)
+				last := rows[len(rows)-1]
+				arg.CursorKey = nullTime(last.SortKey)
+				arg.CursorID = uuid.NullUUID{UUID: last.ID, Valid: true}
+			}
+
+			want := []string{"a3", "b2", "a2", "b1", "a1", "b0", "a0"}
+			if ascending {
+				slices.Reverse(want)
+			}
+			expect(t, fmt.Sprintf("pages ascending=%v", ascending), seen, want...)
+		}
+	}},
+	{"browse by published date", func(t *testing.T, f *fixture) {
+		rows := f.browse(t, database.BrowsePostsForUserParams{ByPublished: true})
+		expect(t, "by published", f.browseNames(rows), "a0", "a3", "b2", "a2", "b1", "a1", "b0")
+		if !rows[0].SortKey.Equal(hours(7)) {
+			t.Fatalf("expected a0 to sort by its published date, got %v", rows[0].SortKey)
+		}
+
+		arg := database.BrowsePostsForUserParams{
+			ByPublished: true,
+			CursorKey:   nullTime(rows[0].SortKey),
+			CursorID:    uuid.NullUUID{UUID: rows[0].ID, Valid: true},
+			PageLimit:   2,
+		}
+		expect(t, "after a0", f.browseNames(f.browse(t, arg)), "a3", "b2")
+	}},
+	{"browse with an offset", func(t *testing.T, f *fixture) {
+		rows := f.browse(t, database.BrowsePostsForUserParams{PageOffset: 5})
+		expect(t, "offset 5", f.browseNames(rows), "b0", "a0")
+		rows = f.browse(t, database.BrowsePostsForUserParams{Ascending: true, PageOffset: 2, PageLimit: 2})
+		expect(t, "offset 2 ascending", f.browseNames(rows), "a1", "b1")
+	}},
+	{"browse unread only", func(t *testing.T, f *fixture) {
+		f.markRead(t, f.alice, "a3", "b2")
+		f.markRead(t, f.bob, "b1")
+
+		rows := f.browse(t, database.BrowsePostsForUserParams{UnreadOnly: true})
+		expect(t, "alice unread", f.browseNames(rows), "a2", "b1", "a1", "b0", "a0")
+		rows = f.browse(t, database.BrowsePostsForUserParams{UserID: f.bob.ID, UnreadOnly: true})
+		expect(t, "bob unread", f.browseNames(rows), "b2", "b0")
+
+		rows = f.browse(t, database.BrowsePostsForUserParams{})
+		if !rows[0].ReadAt.Valid || rows[2].ReadAt.Valid {
+			t.Fatalf("read_at should only be set on posts alice read: %+v", rows)
+		}
+	}},
+	{"browse one feed", func(t *testing.T, f *fixture) {
+		for _, feed := range []string{"Mine", "Alpha", f.alpha.Url} {
+			rows := f.browse(t, database.BrowsePostsForUserParams{Feed: nullString(feed)})
+			expect(t, "feed "+feed, f.browseNames(rows), "a3", "a2", "a1", "a0")
+			if rows[0].FeedName != "Mine" {
+				t.Fatalf("expected alice's name for the feed, got %v", rows[0].FeedName)
+			}
+		}
+		rows := f.browse(t, database.BrowsePostsForUserParams{Feed: nullString(f.beta.Url)})
+		expect(t, "beta", f.browseNames(rows), "b2", "b1", "b0")
+		rows = f.browse(t, database.BrowsePostsForUserParams{UserID: f.bob.ID, Feed: nullString("Mine")})
+		expect(t, "bob with alice's name", f.browseNames(rows))
+	}},
+	{"browse between two times", func(t *testing.T, f *fixture) {
+		rows := f.browse(t, database.BrowsePostsForUserParams{
+			Since: nullTime(hours(2)),
+			Until: nullTime(hours(5)),
+		})
+		expect(t, "02:00 to 05:00", f.browseNames(rows), "a2", "b1", "a1")
+	}},
+	{"hidden posts are left out", func(t *testing.T, f *fixture) {
+		f.hide(t, f.alice, "a2", "b1")
+
+		rows := f.browse(t, database.BrowsePostsForUserParams{})
+		expect(t, "alice browsing", f.browseNames(rows), "a3", "b2", "a1", "b0", "a0")
+		rows = f.browse(t, database.BrowsePostsForUserParams{UserID: f.bob.ID})
+		expect(t, "bob browsing", f.browseNames(rows), "b2", "b1", "b0")
+		expect(t, "alice syncing", f.sync(t, database.ListSyncPostsParams{}), "a3", "b2", "a1", "b0", "a0")
+
+		counts, err := f.db.CountUnreadPostsByFeed(context.Background(), f.alice.ID)
+		if err != nil {
+			t.Fatalf("counting unread posts: %v", err)
+		}
+		want := map[string]int64{f.alpha.Url: 3, f.beta.Url: 2}
+		for _, count := range counts {
+			if count.Unread != want[count.FeedUrl] {
+				t.Fatalf("expected %v unread posts in %v, got %v", want[count.FeedUrl], count.FeedUrl, count.Unread)
+			}
+		}
+
+		// the total reader apps are given has to agree with the posts they're sent
+		for user, want := range map[database.User]int64{f.alice: 5, f.bob: 3} {
+			total, err := f.db.CountPostsForUser(context.Background(), user.ID)
+			if err != nil || total != want {
+				t.Fatalf("expected %v posts for %v, got %v (%v)", want, user.Name, total, err)
+			}
+		}
+	}},
+	{"sync pages by seq", func(t *testing.T, f *fixture) {
+		after := database.ListSyncPostsParams{AfterSeq: nullInt(f.posts["b0"].Seq), Ascending: true, ItemLimit: 3}
+		expect(t, "after b0", f.sync(t, after), "a1", "b1", "a2")
+
+		before := database.ListSyncPostsParams{BeforeSeq: nullInt(f.posts["a2"].Seq)}
+		expect(t, "before a2", f.sync(t, before), "b1", "a1", "b0", "a0")
+
+		between := database.ListSyncPostsParams{
+			AfterSeq:  nullInt(f.posts["a0"].Seq),
+			BeforeSeq: nullInt(f.posts["b1"].Seq),
+		}
+		expect(t, "between a0 and b1", f.sync(t, between), "a1", "b0")
+
+		seqs := database.ListSyncPostsParams{Seqs: f.seqs("a0", "b2", "a3")}
+		expect(t, "by seq", f.sync(t, seqs), "a3", "b2", "a0")
+
+		since := database.ListSyncPostsParams{Since: nullTime(hours(5)), Ascending: true}
+		expect(t, "since 05:00", f.sync(t, since), "b2", "a3")
+	}},
+	{"sync filters", func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		read, err := f.db.MarkPostsReadBySeq(ctx, database.MarkPostsReadBySeqParams{
+			ReadAt: hours(10),
+			UserID: f.alice.ID,
+			Seqs:   f.seqs("a0", "b1"),
+		})
+		if err != nil || read != 2 {
+			t.Fatalf("expected 2 posts marked read, got %v (%v)", read, err)
+		}
+		starred, err := f.db.StarPostsBySeq(ctx, database.StarPostsBySeqParams{
+			StarredAt: hours(10),
+			UserID:    f.alice.ID,
+			Seqs:      f.seqs("a1", "b2"),
+		})
+		if err != nil || starred != 2 {
+			t.Fatalf("expected 2 posts starred, got %v (%v)", starred, err)
+		}
+
+		expect(t, "read", f.sync(t, database.ListSyncPostsParams{ReadOnly: true}), "b1", "a0")
+		expect(t, "unread", f.sync(t, database.ListSyncPostsParams{UnreadOnly: true}), "a3", "b2", "a2", "a1", "b0")
+		expect(t, "starred", f.sync(t, database.ListSyncPostsParams{StarredOnly: true}), "b2", "a1")
+		expect(t, "news folder", f.sync(t, database.ListSyncPostsParams{Folder: nullString("news")}), "a3", "a2", "a1", "a0")
+		expect(t, "beta", f.sync(t, database.ListSyncPostsParams{FeedUrl: nullString(f.beta.Url), UnreadOnly: true}), "b2", "b0")
+		expect(t, "bob starred", f.sync(t, database.ListSyncPostsParams{UserID: f.bob.ID, StarredOnly: true}))
+	}},
+	{"timeline folders include subfolders and nothing else", func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		timeline := func(folder string) []string {
+			t.Helper()
+			rows, err := f.db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+				UserID:    f.alice.ID,
+				Folder:    nullString(folder),
+				PostLimit: 50,
+			})
+			if err != nil {
+				t.Fatalf("listing timeline for %v: %v", folder, err)
+			}
+			names := make([]string, 0, len(rows))
+			for _, row := range rows {
+				names = append(names, f.names[row.ID])
+			}
+			return names
+		}
+		move := func(feed database.Feed, folder string) {
+			t.Helper()
+			_, err := f.db.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+				Folder:    nullString(folder),
+				UpdatedAt: hours(10),
+				UserID:    f.alice.ID,
+				Url:       feed.Url,
+			})
+			if err != nil {
+				t.Fatalf("moving %v to %v: %v", feed.Name, folder, err)
+			}
+		}
+
+		move(f.alpha, "a_b/tech")
+		move(f.beta, "axb/tech")
+		expect(t, "a_b", timeline("a_b"), "a3", "a2", "a1", "a0")
+		expect(t, "exact", timeline("a_b/tech"), "a3", "a2", "a1", "a0")
+		expect(t, "partial name", timeline("a_"))
+
+		move(f.beta, "News/tech")
+		move(f.alpha, "100%/x")
+		expect(t, "case", timeline("news"))
+		expect(t, "percent", timeline("100"))
+		expect(t, "percent exact", timeline("100%"), "a3", "a2", "a1", "a0")
+	}},
+	{"marking by seq only touches followed feeds", func(t *testing.T, f *fixture) {
+		read, err := f.db.MarkPostsReadBySeq(context.Background(), database.MarkPostsReadBySeqParams{
+			ReadAt: hours(10),
+			UserID: f.bob.ID,
+			Seqs:   f.seqs("a0", "b0"),
+		})
+		if err != nil || read != 1 {
+			t.Fatalf("expected only b0 marked read, got %v (%v)", read, err)
+		}
+		expect(t, "bob read", f.sync(t, database.ListSyncPostsParams{UserID: f.bob.ID, ReadOnly: true}), "b0")
+		expect(t, "alice read", f.sync(t, database.ListSyncPostsParams{ReadOnly: true}))
+	}},
+	{"marking everything before a time read", func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		read, err := f.db.MarkPostsReadBefore(ctx, database.MarkPostsReadBeforeParams{
+			ReadAt: hours(10),
+			UserID: f.alice.ID,
+			Folder: nullString("news"),
+			Before: hours(4),
+		})
+		if err != nil || read != 2 {
+			t.Fatalf("expected a0 and a1 marked read, got %v (%v)", read, err)
+		}
+		read, err = f.db.MarkPostsReadBefore(ctx, database.MarkPostsReadBeforeParams{
+			ReadAt:  hours(10),
+			UserID:  f.alice.ID,
+			FeedUrl: nullString(f.beta.Url),
+			Before:  hours(2),
+		})
+		if err != nil || read != 1 {
+			t.Fatalf("expected b0 marked read, got %v (%v)", read, err)
+		}
+
+		counts, err := f.db.CountUnreadPostsByFeed(ctx, f.alice.ID)
+		if err != nil {
+			t.Fatalf("counting unread posts: %v", err)
+		}
+		got := map[string]database.CountUnreadPostsByFeedRow{}
+		for _, count := range counts {
+			got[count.FeedUrl] = count
+		}
+		alpha, beta := got[f.alpha.Url], got[f.beta.Url]
+		if len(got) != 2 || alpha.Unread != 2 || alpha.Folder.String != "news" || !alpha.Newest.Equal(hours(6)) {
+			t.Fatalf("unexpected unread counts for alpha: %+v", counts)
+		}
+		if beta.Unread != 2 || beta.Folder.Valid || !beta.Newest.Equal(hours(5)) {
+			t.Fatalf("unexpected unread counts for beta: %+v", counts)
+		}
+	}},
+	{"pruning keeps the newest posts", func(t *testing.T, f *fixture) {
+		f.markRead(t, f.alice, "a0", "a1", "a2", "a3")
+
+		keep := database.GetPrunablePostsParams{FeedID: f.alpha.ID, Keep: nullInt(2)}
+		expect(t, "keep 2", f.prunable(t, keep), "a0", "a1")
+
+		keep.AfterSeq = f.posts["a0"].Seq
+		expect(t, "keep 2 after a0", f.prunable(t, keep), "a1")
+
+		keep.AfterSeq, keep.BatchSize = 0, 1
+		expect(t, "a batch of 1", f.prunable(t, keep), "a0")
+
+		before := database.GetPrunablePostsParams{FeedID: f.alpha.ID, Before: nullTime(hours(3))}
+		expect(t, "before 03:00", f.prunable(t, before), "a0", "a1")
+
+		both := database.GetPrunablePostsParams{FeedID: f.alpha.ID, Before: nullTime(hours(1)), Keep: nullInt(3)}
+		expect(t, "either limit", f.prunable(t, both), "a0")
+	}},
+	{"pruning spares starred, tagged and unread posts", func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		f.markRead(t, f.alice, "a0", "a2", "b0", "b1", "b2")
+		if err := f.db.StarPost(ctx, database.StarPostParams{UserID: f.alice.ID, PostID: f.posts["a3"].ID, StarredAt: hours(10)}); err != nil {
+			t.Fatalf("starring a3: %v", err)
+		}
+		f.markRead(t, f.alice, "a3")
+		err := f.db.TagPost(ctx, database.TagPostParams{UserID: f.alice.ID, PostID: f.posts["a2"].ID, Tag: "keep", CreatedAt: hours(10)})
+		if err != nil {
+			t.Fatalf("tagging a2: %v", err)
+		}
+
+		all := database.GetPrunablePostsParams{FeedID: f.alpha.ID, Keep: nullInt(0)}
+		expect(t, "alpha", f.prunable(t, all), "a0")
+
+		// bob follows beta too and hasn't read anything, hiding counts as reading
+		all.FeedID = f.beta.ID
+		expect(t, "beta", f.prunable(t, all))
+		f.markRead(t, f.bob, "b0")
+		f.hide(t, f.bob, "b2")
+		expect(t, "beta once bob caught up", f.prunable(t, all), "b0", "b2")
+	}},
+	{"pruned urls are remembered", func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		for i := 0; i < 2; i++ {
+			err := f.db.CreatePrunedPosts(ctx, database.CreatePrunedPostsParams{
+				Urls:     []string{f.posts["a0"].Url, f.posts["a1"].Url},
+				FeedID:   f.alpha.ID,
+				PrunedAt: hours(10),
+			})
+			if err != nil {
+				t.Fatalf("recording pruned posts: %v", err)
+			}
+		}
+
+		for name, want := range map[string]bool{"a0": true, "a1": true, "a2": false} {
+			pruned, err := f.db.PostWasPruned(ctx, f.posts[name].Url)
+			if err != nil {
+				t.Fatalf("checking %v: %v", name, err)
+			}
+			if pruned != want {
+				t.Fatalf("expected %v pruned to be %v", name, want)
+			}
+		}
+	}},
+	{"digests take the oldest unread posts first", func(t *testing.T, f *fixture) {
+		f.markRead(t, f.alice, "b0")
+		digest := func(arg database.GetDigestPostsForUserParams) []string {
+			arg.UserID = f.alice.ID
+			rows, err := f.db.GetDigestPostsForUser(context.Background(), arg)
+			if err != nil {
+				t.Fatalf("retrieving digest posts: %v", err)
+			}
+			names := make([]string, 0, len(rows))
+			for _, row := range rows {
+				names = append(names, f.names[row.ID])
+			}
+			return names
+		}
+
+		expect(t, "first 3", digest(database.GetDigestPostsForUserParams{Until: hours(10), PostLimit: 3}), "a0", "a1", "b1")
+		expect(t, "from 03:00", digest(database.GetDigestPostsForUserParams{
+			Since:     nullTime(hours(3)),
+			Until:     hours(6),
+			PostLimit: 10,
+		}), "b1", "a2", "b2")
+
+		// posts fetched in the same instant are told apart by their seq
+		for _, name := range []string{"s0", "s1", "s2"} {
+			f.addPost(t, name, f.alpha, hours(8), hours(8))
+		}
+		expect(t, "from 08:00", digest(database.GetDigestPostsForUserParams{
+			Since:     nullTime(hours(8)),
+			Until:     hours(10),
+			PostLimit: 10,
+		}), "s0", "s1", "s2")
+		expect(t, "after s0", digest(database.GetDigestPostsForUserParams{
+			Since:     nullTime(hours(8)),
+			SinceSeq:  nullInt(f.posts["s0"].Seq),
+			Until:     hours(10),
+			PostLimit: 10,
+		}), "s1", "s2")
+	}},
+	{"webhooks name feeds the way their owner does", func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		for _, user := range []database.User{f.alice, f.bob} {
+			_, err := f.db.CreateWebhook(ctx, database.CreateWebhookParams{
+				ID:        uuid.New(),
+				CreatedAt: start,
+				UpdatedAt: start,
+				UserID:    user.ID,
+				Url:       "https://hooks.example.com/" + user.Name,
+			})
+			if err != nil {
+				t.Fatalf("creating webhook: %v", err)
+			}
+		}
+
+		hooks, err := f.db.GetWebhooksForFeed(ctx, f.alpha.ID)
+		if err != nil {
+			t.Fatalf("retrieving webhooks: %v", err)
+		}
+		if len(hooks) != 1 || hooks[0].UserName != "alice" || hooks[0].FeedName != "Mine" {
+			t.Fatalf("expected alice's webhook with her title for alpha, got %+v", hooks)
+		}
+
+		hooks, err = f.db.GetWebhooksForFeed(ctx, f.beta.ID)
+		if err != nil {
+			t.Fatalf("retrieving webhooks: %v", err)
+		}
+		if len(hooks) != 2 || hooks[0].FeedName != "Beta" || hooks[1].FeedName != "Beta" {
+			t.Fatalf("expected both webhooks with beta's own name, got %+v", hooks)
+		}
+	}},
+	{"seqs are never reused", func(t *testing.T, f *fixture) {
+		newest := f.posts["a3"].Seq
+		deleted, err := f.db.DeletePosts(context.Background(), []uuid.UUID{f.posts["a3"].ID})
+		if err != nil || deleted != 1 {
+			t.Fatalf("expected a3 deleted, got %v (%v)", deleted, err)
+		}
+
+		f.addPost(t, "a4", f.alpha, hours(8), hours(8))
+		if f.posts["a4"].Seq <= newest {
+			t.Fatalf("a4 got seq %v, a3 had %v", f.posts["a4"].Seq, newest)
+		}
+		after := database.ListSyncPostsParams{AfterSeq: nullInt(f.posts["b2"].Seq)}
+		expect(t, "after b2", f.sync(t, after), "a4")
+	}},
+}
+
+func TestStore(t *testing.T) {
+	for backend, open := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			for _, tc := range storeCases {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.run(t, newFixture(t, open(t)))
+				})
+			}
+		})
+	}
+}
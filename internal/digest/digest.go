@@ -0,0 +1,221 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Post is a single entry of a digest, its summary is plain text
+type Post struct {
+	Title     string
+	URL       string
+	Feed      string
+	Author    string
+	Published time.Time
+	Summary   string
+}
+
+type Digest struct {
+	From string
+	To   string
+	User string
+	// posts are grouped under their feed in the order given, so they should come sorted by feed
+	Posts []Post
+	// there were more unread posts than fit in the digest
+	Truncated bool
+	Date      time.Time
+}
+
+type feedGroup struct {
+	Feed  string
+	Posts []Post
+}
+
+func (d Digest) Subject() string {
+	if d.Truncated {
+		return fmt.Sprintf("gator digest: over %v new posts", len(d.Posts))
+	}
+	if len(d.Posts) == 1 {
+		return "gator digest: 1 new post"
+	}
+	return fmt.Sprintf("gator digest: %v new posts", len(d.Posts))
+}
+
+func (d Digest) groups() []feedGroup {
+	var groups []feedGroup
+	for _, post := range d.Posts {
+		if len(groups) == 0 || groups[len(groups)-1].Feed != post.Feed {
+			groups = append(groups, feedGroup{Feed: post.Feed})
+		}
+		last := &groups[len(groups)-1]
+		last.Posts = append(last.Posts, post)
+	}
+	return groups
+}
+
+// Message renders the digest as a multipart/alternative mail, plain text first so
+// clients that can show html prefer it
+func (d Digest) Message() ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	text := d.plainText()
+	html, err := d.html()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		w, err := parts.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(d.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %v\r\n", d.From)
+	fmt.Fprintf(&msg, "To: %v\r\n", d.To)
+	fmt.Fprintf(&msg, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", d.Subject()))
+	fmt.Fprintf(&msg, "Date: %v\r\n", d.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: %v\r\n", messageID)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%v\r\n", parts.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func (d Digest) plainText() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%v for %v\n", d.Subject(), d.User)
+
+	for _, group := range d.groups() {
+		fmt.Fprintf(&out, "\n== %v ==\n", group.Feed)
+		for _, post := range group.Posts {
+			fmt.Fprintf(&out, "\n* %v\n", post.Title)
+			if meta := post.meta(); meta != "" {
+				fmt.Fprintf(&out, "  %v\n", meta)
+			}
+			fmt.Fprintf(&out, "  %v\n", post.URL)
+			if post.Summary != "" {
+				fmt.Fprintf(&out, "\n  %v\n", strings.ReplaceAll(post.Summary, "\n", "\n  "))
+			}
+		}
+	}
+
+	if d.Truncated {
+		fmt.Fprintf(&out, "\n...and more, they'll be in your next digest or run gator browse --unread to see them now\n")
+	}
+	return out.String()
+}
+
+var htmlTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 40em; margin: auto;">
+<h1 style="font-size: 1.3em;">{{.Subject}}</h1>
+{{range .Groups}}
+<h2 style="font-size: 1.1em; border-bottom: 1px solid #ccc;">{{.Feed}}</h2>
+{{range .Posts}}
+<div style="margin-bottom: 1.2em;">
+<a href="{{.URL}}" style="font-weight: bold;">{{.Title}}</a>
+{{with .Meta}}<div style="color: #666; font-size: 0.9em;">{{.}}</div>{{end}}
+{{with .Summary}}<p style="white-space: pre-wrap;">{{.}}</p>{{end}}
+</div>
+{{end}}
+{{end}}
+{{if .Truncated}}<p>...and more, they'll be in your next digest or run <code>gator browse --unread</code> to see them now</p>{{end}}
+</body>
+</html>
+`))
+
+type htmlPost struct {
+	Post
+	Meta string
+}
+
+type htmlGroup struct {
+	Feed  string
+	Posts []htmlPost
+}
+
+// html only ever contains text gator extracted from the feeds, never their markup
+func (d Digest) html() (string, error) {
+	data := struct {
+		Subject   string
+		Groups    []htmlGroup
+		Truncated bool
+	}{Subject: d.Subject(), Truncated: d.Truncated}
+
+	for _, group := range d.groups() {
+		out := htmlGroup{Feed: group.Feed}
+		for _, post := range group.Posts {
+			out.Posts = append(out.Posts, htmlPost{Post: post, Meta: post.meta()})
+		}
+		data.Groups = append(data.Groups, out)
+	}
+
+	var out bytes.Buffer
+	if err := htmlTemplate.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("unable to render digest: %v", err)
+	}
+	return out.String(), nil
+}
+
+func (p Post) meta() string {
+	var parts []string
+	if p.Author != "" {
+		parts = append(parts, p.Author)
+	}
+	if !p.Published.IsZero() {
+		parts = append(parts, p.Published.Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func newMessageID(from string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("unable to generate message id: %v", err)
+	}
+
+	domain := "gator.local"
+	if _, host, found := strings.Cut(strings.Trim(from, "<> "), "@"); found {
+		domain = strings.TrimRight(host, ">")
+	}
+	return fmt.Sprintf("<%v@%v>", hex.EncodeToString(buf), domain), nil
+}
@@ -0,0 +1,379 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what the stand-in server saw of a delivery
+type smtpSession struct {
+	tls      bool
+	auth     string
+	from     string
+	to       string
+	data     []byte
+	commands []string
+}
+
+// smtpStandIn accepts a single delivery. It offers STARTTLS when starttls is set and
+// speaks tls from the first byte when implicit is
+type smtpStandIn struct {
+	listener net.Listener
+	config   *tls.Config
+	starttls bool
+	sessions chan smtpSession
+	errs     chan error
+}
+
+func newSMTPStandIn(t *testing.T, starttls, implicit bool) *smtpStandIn {
+	t.Helper()
+
+	config := selfSignedTLS(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	if implicit {
+		listener = tls.NewListener(listener, config)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &smtpStandIn{
+		listener: listener,
+		config:   config,
+		starttls: starttls,
+		sessions: make(chan smtpSession, 1),
+		errs:     make(chan error, 1),
+	}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			server.errs <- err
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		session, err := server.serve(conn, implicit)
+		if err != nil {
+			server.errs <- err
+			return
+		}
+		server.sessions <- session
+	}()
+	return server
+}
+
+func (s *smtpStandIn) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) session(t *testing.T) smtpSession {
+	t.Helper()
+	select {
+	case session := <-s.sessions:
+		return session
+	case err := <-s.errs:
+		t.Fatalf("smtp stand-in: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("smtp stand-in never finished")
+	}
+	return smtpSession{}
+}
+
+func (s *smtpStandIn) serve(conn net.Conn, implicit bool) (smtpSession, error) {
+	session := smtpSession{tls: implicit}
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...any) error {
+		return text.PrintfLine(format, args...)
+	}
+
+	if err := reply("220 localhost stand-in"); err != nil {
+		return session, err
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return session, err
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		session.commands = append(session.commands, verb)
+
+		switch verb {
+		case "EHLO", "HELO":
+			extensions := []string{"localhost", "8BITMIME", "AUTH PLAIN"}
+			if s.starttls && !session.tls {
+				extensions = append(extensions, "STARTTLS")
+			}
+			for i, extension := range extensions {
+				separator := "-"
+				if i == len(extensions)-1 {
+					separator = " "
+				}
+				reply("250%v%v", separator, extension)
+			}
+		case "STARTTLS":
+			reply("220 go ahead")
+			secure := tls.Server(conn, s.config)
+			if err := secure.Handshake(); err != nil {
+				return session, err
+			}
+			conn, session.tls = secure, true
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			credentials, _ := strings.CutPrefix(arg, "PLAIN ")
+			decoded, err := base64.StdEncoding.DecodeString(credentials)
+			if err != nil {
+				return session, err
+			}
+			session.auth = string(decoded)
+			reply("235 accepted")
+		case "MAIL":
+			session.from = arg
+			reply("250 ok")
+		case "RCPT":
+			session.to = arg
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			session.data, err = text.ReadDotBytes()
+			if err != nil {
+				return session, err
+			}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return session, nil
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+// selfSignedTLS makes a certificate for 127.0.0.1 and trusts it for the test
+func selfSignedTLS(t *testing.T) *tls.Config {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gator test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+
+	previous := rootCAs
+	rootCAs = x509.NewCertPool()
+	rootCAs.AddCert(cert)
+	t.Cleanup(func() { rootCAs = previous })
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func testDigest() Digest {
+	published := time.Date(2025, 1, 2, 7, 30, 0, 0, time.UTC)
+	return Digest{
+		From: "gator <gator@example.com>",
+		To:   "alice@example.com",
+		User: "alice",
+		Date: published,
+		Posts: []Post{
+			{Title: "First <post>", URL: "https://a.example.com/1", Feed: "Alpha", Author: "Ann", Published: published, Summary: "Café summary that's long enough to wrap when quoted printable encodes it, which happens past seventy six characters"},
+			{Title: "Second", URL: "https://a.example.com/2", Feed: "Alpha"},
+			{Title: "Third", URL: "https://b.example.com/1", Feed: "Beta"},
+		},
+		Truncated: true,
+	}
+}
+
+// parts reads a multipart/alternative message back into its decoded parts
+func parts(t *testing.T, msg []byte) (*mail.Message, []string, []string) {
+	t.Helper()
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatalf("unreadable message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (%v)", parsed.Header.Get("Content-Type"), err)
+	}
+
+	var types, bodies []string
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		if encoding := part.Header.Get("Content-Transfer-Encoding"); encoding != "quoted-printable" {
+			t.Fatalf("expected quoted-printable parts, got %q", encoding)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("decoding part: %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+	return parsed, types, bodies
+}
+
+func TestMessage(t *testing.T) {
+	d := testDigest()
+	msg, err := d.Message()
+	if err != nil {
+		t.Fatalf("building message: %v", err)
+	}
+	for _, line := range strings.Split(string(msg), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line too long for smtp: %v characters", len(line))
+		}
+	}
+
+	parsed, types, bodies := parts(t, msg)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "gator digest: over 3 new posts" {
+		t.Fatalf("unexpected subject %q (%v)", subject, err)
+	}
+	if parsed.Header.Get("To") != d.To || parsed.Header.Get("Message-ID") == "" {
+		t.Fatalf("missing headers: %v", parsed.Header)
+	}
+
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("expected a plain text then an html part, got %v", types)
+	}
+
+	text, html := bodies[0], bodies[1]
+	for _, want := range []string{"== Alpha ==", "== Beta ==", "* First <post>", "Ann · 2025-01-02 07:30", "Café summary", "next digest"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("plain text part is missing %q:\n%v", want, text)
+		}
+	}
+	if strings.Index(text, "Alpha") > strings.Index(text, "Beta") || strings.Count(text, "== Alpha ==") != 1 {
+		t.Fatalf("posts should be grouped under their feed in order:\n%v", text)
+	}
+	if !strings.Contains(html, "First &lt;post&gt;") || strings.Contains(html, "<post>") {
+		t.Fatalf("titles should be escaped in the html part:\n%v", html)
+	}
+	if !strings.Contains(html, `href="https://b.example.com/1"`) {
+		t.Fatalf("html part is missing a link:\n%v", html)
+	}
+}
+
+func TestSend(t *testing.T) {
+	cases := []struct {
+		name     string
+		starttls bool
+		implicit bool
+		username string
+		wantTLS  bool
+	}{
+		{"plain", false, false, "", false},
+		{"plain with auth on localhost", false, false, "alice", false},
+		{"starttls", true, false, "alice", true},
+		{"implicit tls", false, true, "alice", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newSMTPStandIn(t, tc.starttls, tc.implicit)
+			msg, err := testDigest().Message()
+			if err != nil {
+				t.Fatalf("building message: %v", err)
+			}
+
+			err = Send(Server{
+				Host:     "127.0.0.1",
+				Port:     server.port(),
+				Username: tc.username,
+				Password: "secret",
+				TLS:      tc.implicit,
+			}, "gator <gator@example.com>", "Alice <alice@example.com>", msg)
+			if err != nil {
+				t.Fatalf("sending: %v", err)
+			}
+
+			session := server.session(t)
+			if session.tls != tc.wantTLS {
+				t.Fatalf("expected tls to be %v, commands were %v", tc.wantTLS, session.commands)
+			}
+			if tc.starttls && !strings.Contains(strings.Join(session.commands, " "), "STARTTLS EHLO") {
+				t.Fatalf("expected a second EHLO after STARTTLS, commands were %v", session.commands)
+			}
+
+			wantAuth := ""
+			if tc.username != "" {
+				wantAuth = "\x00" + tc.username + "\x00secret"
+			}
+			if session.auth != wantAuth {
+				t.Fatalf("expected auth %q, got %q", wantAuth, session.auth)
+			}
+			if !strings.Contains(session.from, "<gator@example.com>") || !strings.Contains(session.to, "<alice@example.com>") {
+				t.Fatalf("unexpected envelope from %q to %q", session.from, session.to)
+			}
+
+			// the server gets the message as built, apart from line endings
+			got := strings.ReplaceAll(string(session.data), "\r\n", "\n")
+			want := strings.ReplaceAll(strings.TrimRight(string(msg), "\r\n"), "\r\n", "\n")
+			if strings.TrimRight(got, "\n") != want {
+				t.Fatalf("message changed in transit:\n%v", got)
+			}
+			parts(t, session.data)
+		})
+	}
+}
+
+func TestSendRefusesUntrustedCertificates(t *testing.T) {
+	server := newSMTPStandIn(t, true, false)
+	rootCAs = x509.NewCertPool()
+
+	err := Send(Server{Host: "127.0.0.1", Port: server.port()}, "gator@example.com", "alice@example.com", []byte("Subject: hi\r\n\r\nhi\r\n"))
+	if err == nil || !strings.Contains(err.Error(), "tls") {
+		t.Fatalf("expected the tls upgrade to fail, got %v", err)
+	}
+}
+
+func TestSendRejectsBadAddresses(t *testing.T) {
+	for _, addresses := range [][2]string{{"not an address", "alice@example.com"}, {"gator@example.com", ""}} {
+		err := Send(Server{Host: "127.0.0.1", Port: 1}, addresses[0], addresses[1], nil)
+		if err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Fatalf("expected %v to be rejected, got %v", addresses, err)
+		}
+	}
+}
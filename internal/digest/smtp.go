@@ -0,0 +1,98 @@
+package digest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// rootCAs verifies the server's certificate, nil trusts the system's roots
+var rootCAs *x509.CertPool
+
+type Server struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// connect over tls straight away instead of upgrading with STARTTLS
+	TLS bool
+}
+
+// Send delivers msg to a single recipient, upgrading the connection with STARTTLS
+// whenever the server offers it
+func Send(server Server, from, to string, msg []byte) error {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %v: %v", from, err)
+	}
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %v: %v", to, err)
+	}
+
+	port := server.Port
+	if port == 0 {
+		port = 25
+		if server.TLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: server.Host, RootCAs: rootCAs}
+
+	var client *smtp.Client
+	if server.TLS {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("unable to connect to %v: %v", addr, err)
+		}
+		client, err = smtp.NewClient(conn, server.Host)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("unable to start smtp session: %v", err)
+		}
+	} else {
+		client, err = smtp.Dial(addr)
+		if err != nil {
+			return fmt.Errorf("unable to connect to %v: %v", addr, err)
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return fmt.Errorf("unable to start tls: %v", err)
+			}
+		}
+	}
+	defer client.Close()
+
+	if server.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", server.Username, server.Password, server.Host))
+		if err != nil {
+			return fmt.Errorf("unable to authenticate: %v", err)
+		}
+	}
+
+	if err := client.Mail(sender.Address); err != nil {
+		return fmt.Errorf("server refused sender: %v", err)
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return fmt.Errorf("server refused recipient: %v", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("unable to send message: %v", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("unable to send message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("unable to send message: %v", err)
+	}
+
+	return client.Quit()
+}
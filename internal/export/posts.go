@@ -0,0 +1,201 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/render"
+)
+
+// Post is the stable shape posts are exported in, field names here are part of the
+// export format and shouldn't change
+type Post struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Feed        string     `json:"feed"`
+	FeedURL     string     `json:"feed_url"`
+	Author      string     `json:"author,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	FetchedAt   time.Time  `json:"fetched_at"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+	Description string     `json:"description"`
+	Content     string     `json:"content,omitempty"`
+}
+
+// Writer receives posts one at a time so exports never have to hold every post in memory
+type Writer interface {
+	Write(post Post) error
+	// Close finishes the document, it doesn't close the underlying io.Writer
+	Close() error
+}
+
+var Formats = []string{"json", "ndjson", "csv", "markdown"}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "ndjson":
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "markdown", "md":
+		return &markdownWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %v, expected one of %v", format, strings.Join(Formats, ", "))
+	}
+}
+
+// jsonWriter writes a single array, one element at a time
+type jsonWriter struct {
+	w       io.Writer
+	written int
+}
+
+func (j *jsonWriter) Write(post Post) error {
+	data, err := json.MarshalIndent(post, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n  "
+	if j.written == 0 {
+		separator = "[\n  "
+	}
+	j.written++
+
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	closing := "\n]\n"
+	if j.written == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(post Post) error {
+	return n.encoder.Encode(post)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+var csvHeader = []string{"id", "title", "url", "feed", "feed_url", "author", "published_at", "fetched_at", "read", "starred", "description", "content"}
+
+type csvWriter struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (c *csvWriter) Write(post Post) error {
+	if !c.started {
+		c.started = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	published := ""
+	if post.PublishedAt != nil {
+		published = post.PublishedAt.Format(time.RFC3339)
+	}
+
+	err := c.w.Write([]string{
+		post.ID,
+		post.Title,
+		post.URL,
+		post.Feed,
+		post.FeedURL,
+		post.Author,
+		published,
+		post.FetchedAt.Format(time.RFC3339),
+		strconv.FormatBool(post.Read),
+		strconv.FormatBool(post.Starred),
+		post.Description,
+		post.Content,
+	})
+	if err != nil {
+		return err
+	}
+
+	// flushing as we go keeps memory flat on large exports
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if !c.started {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type markdownWriter struct {
+	w io.Writer
+}
+
+func (m *markdownWriter) Write(post Post) error {
+	var out strings.Builder
+
+	title := post.Title
+	if title == "" {
+		title = post.URL
+	}
+	fmt.Fprintf(&out, "## [%v](%v)\n\n", escapeMarkdown(title), post.URL)
+
+	meta := []string{escapeMarkdown(post.Feed)}
+	if post.Author != "" {
+		meta = append(meta, escapeMarkdown(post.Author))
+	}
+	if post.PublishedAt != nil {
+		meta = append(meta, post.PublishedAt.Format("2006-01-02 15:04"))
+	}
+	if post.Starred {
+		meta = append(meta, "starred")
+	}
+	fmt.Fprintf(&out, "*%v*\n\n", strings.Join(meta, " · "))
+
+	body := post.Description
+	if post.Content != "" {
+		body = post.Content
+	}
+	if text := render.HTMLToText(body, 80); text != "" {
+		fmt.Fprintf(&out, "%v\n\n", text)
+	}
+	out.WriteString("---\n\n")
+
+	_, err := io.WriteString(m.w, out.String())
+	return err
+}
+
+func (m *markdownWriter) Close() error {
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, "`", "\\`")
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
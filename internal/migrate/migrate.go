@@ -0,0 +1,279 @@
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versions are tracked in the same table goose uses, so databases migrated with goose
+// before gator could do it itself carry on from where they are
+const versionTable = "goose_db_version"
+
+// Dialect holds the statements that manage the version table on a given database
+type Dialect struct {
+	createTable   string
+	insertVersion string
+	deleteVersion string
+}
+
+var (
+	Postgres = Dialect{
+		createTable: `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT now()
+)`,
+		insertVersion: `INSERT INTO ` + versionTable + ` (version_id, is_applied) VALUES ($1, true)`,
+		deleteVersion: `DELETE FROM ` + versionTable + ` WHERE version_id = $1`,
+	}
+	SQLite = Dialect{
+		createTable: `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+		insertVersion: `INSERT INTO ` + versionTable + ` (version_id, is_applied) VALUES (?, true)`,
+		deleteVersion: `DELETE FROM ` + versionTable + ` WHERE version_id = ?`,
+	}
+)
+
+type Migration struct {
+	Version int64
+	// file name without the version and extension, e.g. posts for 005_posts.sql
+	Name string
+	Up   string
+	Down string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load reads every [version]_[name].sql file at the root of fsys, sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := map[int64]string{}
+	for _, file := range files {
+		prefix, name, found := strings.Cut(strings.TrimSuffix(path.Base(file), ".sql"), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !found || err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %v isn't named [version]_[name].sql", file)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %v and %v share version %v", other, file, version)
+		}
+		seen[version] = file
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %v: %v", file, err)
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: name, Up: up, Down: down})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parse splits a goose file into its up and down sections
+func parse(src string) (string, string, error) {
+	var up, down strings.Builder
+	var current *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose "); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				current = &up
+			case "Down":
+				current = &down
+			case "StatementBegin", "StatementEnd":
+				// sections are run whole, so statement boundaries don't matter
+			default:
+				return "", "", fmt.Errorf("unsupported annotation %v", annotation)
+			}
+			continue
+		}
+		if current != nil {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+	if strings.TrimSpace(up.String()) == "" {
+		return "", "", fmt.Errorf("no up section")
+	}
+	return up.String(), down.String(), nil
+}
+
+func ensureTable(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	_, err := db.ExecContext(ctx, dialect.createTable)
+	if err != nil {
+		return fmt.Errorf("unable to create %v: %v", versionTable, err)
+	}
+	return nil
+}
+
+// applied maps every applied version to when it was applied, going by the latest row
+// of each version like goose does
+func applied(ctx context.Context, db *sql.DB, dialect Dialect) (map[int64]time.Time, error) {
+	if err := ensureTable(ctx, db, dialect); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version_id, is_applied, tstamp FROM `+versionTable+` ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %v: %v", versionTable, err)
+	}
+	defer rows.Close()
+
+	out := map[int64]time.Time{}
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var at sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &at); err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied && version > 0 {
+			out[version] = at.Time
+		}
+	}
+	return out, rows.Err()
+}
+
+func Statuses(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration) ([]Status, error) {
+	done, err := applied(ctx, db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		at, ok := done[migration.Version]
+		out = append(out, Status{Migration: migration, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// Version is the highest applied version, 0 for an empty database
+func Version(ctx context.Context, db *sql.DB, dialect Dialect) (int64, error) {
+	done, err := applied(ctx, db, dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for applied := range done {
+		version = max(version, applied)
+	}
+	return version, nil
+}
+
+// Up applies every pending migration up to and including target (0 for all of them),
+// each in its own transaction so a failure leaves the earlier ones in place
+func Up(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration, target int64) ([]Migration, error) {
+	done, err := applied(ctx, db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+
+		err := run(ctx, db, migration.Up, dialect.insertVersion, migration.Version)
+		if err != nil {
+			return ran, fmt.Errorf("migration %v_%v failed: %v", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+// Down rolls back applied migrations newest first until only those at or below target
+// are left. A negative target rolls back just the newest one
+func Down(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration, target int64) ([]Migration, error) {
+	done, err := applied(ctx, db, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, ok := done[migration.Version]; !ok {
+			continue
+		}
+		if target >= 0 && migration.Version <= target {
+			break
+		}
+
+		err := run(ctx, db, migration.Down, dialect.deleteVersion, migration.Version)
+		if err != nil {
+			return ran, fmt.Errorf("rolling back migration %v_%v failed: %v", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+
+		if target < 0 {
+			break
+		}
+	}
+	return ran, nil
+}
+
+func run(ctx context.Context, db *sql.DB, statements, record string, version int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(statements) != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
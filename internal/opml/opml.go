@@ -0,0 +1,159 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	URL      string    `xml:"url,attr,omitempty"` // used instead of xmlUrl by some OPML 1 exporters
+	Outlines []Outline `xml:"outline"`
+}
+
+// Subscription is a single feed outline along with the folder it was nested in
+type Subscription struct {
+	Title   string
+	XMLURL  string
+	HTMLURL string
+	// nested folders are joined with a "/", empty when the feed sits at the top level
+	Folder string
+}
+
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+
+	decoder := xml.NewDecoder(r)
+	// OPML files in the wild often claim a charset that isn't utf-8 but only use ascii
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("unable to interpret opml: %v", err)
+	}
+
+	return &doc, nil
+}
+
+// Subscriptions flattens the outline tree into the feeds it contains, outlines without
+// a feed url are treated as folders
+func (d Document) Subscriptions() []Subscription {
+	subs := make([]Subscription, 0)
+	for _, outline := range d.Body.Outlines {
+		subs = outline.collect(subs, nil)
+	}
+	return subs
+}
+
+func (o Outline) collect(subs []Subscription, folders []string) []Subscription {
+	feedURL := strings.TrimSpace(o.XMLURL)
+	if feedURL == "" && o.Type != "link" {
+		feedURL = strings.TrimSpace(o.URL)
+	}
+
+	if feedURL != "" {
+		return append(subs, Subscription{
+			Title:   o.Name(),
+			XMLURL:  feedURL,
+			HTMLURL: strings.TrimSpace(o.HTMLURL),
+			Folder:  strings.Join(folders, "/"),
+		})
+	}
+
+	if name := o.Name(); name != "" {
+		folders = append(folders[:len(folders):len(folders)], name)
+	}
+	for _, child := range o.Outlines {
+		subs = child.collect(subs, folders)
+	}
+	return subs
+}
+
+// Name is the outline's title, readers disagree on whether text or title holds it
+func (o Outline) Name() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// New builds an OPML 2.0 document out of subscriptions, grouping them into nested
+// outlines by their folder
+func New(title string, created time.Time, subs []Subscription) Document {
+	doc := Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: created.Format(time.RFC1123Z),
+		},
+	}
+
+	for _, sub := range subs {
+		outlines := &doc.Body.Outlines
+		if sub.Folder != "" {
+			for _, folder := range strings.Split(sub.Folder, "/") {
+				outlines = &folderOutline(outlines, folder).Outlines
+			}
+		}
+
+		*outlines = append(*outlines, Outline{
+			Text:    sub.Title,
+			Title:   sub.Title,
+			Type:    "rss",
+			XMLURL:  sub.XMLURL,
+			HTMLURL: sub.HTMLURL,
+		})
+	}
+
+	return doc
+}
+
+func folderOutline(outlines *[]Outline, name string) *Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i]
+		}
+	}
+
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+func (d Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("unable to write opml: %v", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
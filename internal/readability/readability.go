@@ -0,0 +1,310 @@
+package readability
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// pages larger than this are almost certainly not articles
+const maxPageSize = 5 << 20
+
+// anything shorter than this is treated as a failed extraction, usually a paywall or a js only page
+const minArticleLength = 250
+
+var (
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negativeHint = regexp.MustCompile(`(?i)comment|meta|footer|footnote|masthead|sidebar|sponsor|shoutbox|share|social|related|promo|nav|menu|banner|combx|popup|cookie|subscribe|\bad-|\bads\b`)
+)
+
+// FetchArticle downloads the page at pageURL and returns the html of its main content
+func FetchArticle(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to form the http request: %v", err)
+	}
+
+	req.Header.Set("User-Agent", "gator")
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error when making the request: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response status: %v", res.Status)
+	}
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return "", fmt.Errorf("page is not html: %v", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxPageSize))
+	if err != nil {
+		return "", fmt.Errorf("unable to read response body: %v", err)
+	}
+
+	return Extract(bytes.NewReader(data), res.Request.URL)
+}
+
+// Extract finds the element of the page that most looks like the article body, in the
+// spirit of arc90's readability: paragraphs award points to their ancestors based on how
+// much prose they hold, and the best scoring ancestor (plus related siblings) wins
+func Extract(r io.Reader, base *url.URL) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse page: %v", err)
+	}
+
+	strip(doc)
+
+	raw := make(map[*html.Node]float64)
+	score(doc, raw)
+
+	// candidates that are mostly links are navigation rather than prose, they're walked in
+	// document order so a tie always goes to the earliest one
+	scores := make(map[*html.Node]float64, len(raw))
+	var top *html.Node
+	var rank func(*html.Node)
+	rank = func(node *html.Node) {
+		if value, ok := raw[node]; ok {
+			scores[node] = value * (1 - linkDensity(node))
+			if top == nil || scores[node] > scores[top] {
+				top = node
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			rank(child)
+		}
+	}
+	rank(doc)
+	if top == nil {
+		return "", fmt.Errorf("no article content found")
+	}
+
+	content := gather(top, scores)
+	if len(strings.TrimSpace(textOf(content))) < minArticleLength {
+		return "", fmt.Errorf("no article content found")
+	}
+
+	if base != nil {
+		resolveLinks(content, base)
+	}
+
+	var out bytes.Buffer
+	for child := content.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&out, child); err != nil {
+			return "", fmt.Errorf("unable to render article: %v", err)
+		}
+	}
+
+	return out.String(), nil
+}
+
+// strip removes everything that can never be part of the article
+func strip(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+
+		remove := child.Type == html.CommentNode
+		if child.Type == html.ElementNode {
+			switch child.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Iframe, atom.Form, atom.Nav,
+				atom.Aside, atom.Footer, atom.Header, atom.Button, atom.Input, atom.Select,
+				atom.Textarea, atom.Svg, atom.Object, atom.Embed, atom.Link, atom.Meta:
+				remove = true
+			default:
+				hints := attr(child, "class") + " " + attr(child, "id")
+				remove = negativeHint.MatchString(hints) && !positiveHint.MatchString(hints) &&
+					child.DataAtom != atom.Body && child.DataAtom != atom.Article
+			}
+		}
+
+		if remove {
+			node.RemoveChild(child)
+		} else {
+			strip(child)
+		}
+		child = next
+	}
+}
+
+func score(node *html.Node, scores map[*html.Node]float64) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		score(child, scores)
+	}
+
+	if node.Type != html.ElementNode {
+		return
+	}
+	switch node.DataAtom {
+	case atom.P, atom.Pre, atom.Td:
+	default:
+		return
+	}
+
+	text := strings.TrimSpace(textOf(node))
+	if len(text) < 25 {
+		return
+	}
+
+	points := 1 + float64(strings.Count(text, ",")) + min(float64(len(text)/100), 3)
+
+	parent := node.Parent
+	if parent == nil {
+		return
+	}
+	initialise(parent, scores)
+	scores[parent] += points
+
+	if grandparent := parent.Parent; grandparent != nil {
+		initialise(grandparent, scores)
+		scores[grandparent] += points / 2
+	}
+}
+
+func initialise(node *html.Node, scores map[*html.Node]float64) {
+	if _, ok := scores[node]; ok {
+		return
+	}
+
+	value := 0.0
+	switch node.DataAtom {
+	case atom.Article:
+		value = 10
+	case atom.Div, atom.Main, atom.Section:
+		value = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		value = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		value = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		value = -5
+	}
+
+	hints := attr(node, "class") + " " + attr(node, "id")
+	if positiveHint.MatchString(hints) {
+		value += 25
+	}
+	if negativeHint.MatchString(hints) {
+		value -= 25
+	}
+
+	scores[node] = value
+}
+
+// gather collects the top candidate together with any siblings that scored well
+// enough or look like prose, since articles are often split over several containers
+func gather(top *html.Node, scores map[*html.Node]float64) *html.Node {
+	content := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	threshold := max(10, scores[top]*0.2)
+
+	if top.Parent == nil {
+		appendClone(content, top)
+		return content
+	}
+
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		include := sibling == top
+		if !include {
+			if value, ok := scores[sibling]; ok && value >= threshold {
+				include = true
+			} else if sibling.Type == html.ElementNode && sibling.DataAtom == atom.P {
+				text := strings.TrimSpace(textOf(sibling))
+				include = len(text) > 80 && linkDensity(sibling) < 0.25
+			}
+		}
+
+		if include {
+			appendClone(content, sibling)
+		}
+	}
+
+	return content
+}
+
+func appendClone(parent *html.Node, node *html.Node) {
+	clone := &html.Node{
+		Type:      node.Type,
+		DataAtom:  node.DataAtom,
+		Data:      node.Data,
+		Namespace: node.Namespace,
+		Attr:      append([]html.Attribute(nil), node.Attr...),
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		appendClone(clone, child)
+	}
+	parent.AppendChild(clone)
+}
+
+func linkDensity(node *html.Node) float64 {
+	total := len(textOf(node))
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linked += len(textOf(n))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return float64(linked) / float64(total)
+}
+
+func resolveLinks(node *html.Node, base *url.URL) {
+	if node.Type == html.ElementNode {
+		for i, a := range node.Attr {
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(a.Val))
+			if err != nil {
+				continue
+			}
+			node.Attr[i].Val = base.ResolveReference(ref).String()
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		resolveLinks(child, base)
+	}
+}
+
+func textOf(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(textOf(child))
+	}
+	return text.String()
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package readability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const prose = "Gophers dig long tunnels under the garden, and they rarely come up for air, " +
+	"which is why most gardeners only ever see the mounds of earth they leave behind. "
+
+const articlePage = `<html><head><title>Gophers</title><script>var tracking = 1</script></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter for more stories about the garden, every week.</p></div>
+<div class="post-content">
+	<h1>All about gophers</h1>
+	<p>` + prose + `</p>
+	<p>` + prose + `<a href="/tunnels">More on tunnels</a></p>
+	<p>` + prose + `<img src="img/gopher.png" alt="a gopher"></p>
+</div>
+<div class="comments"><p>Great article, thanks for writing it, I learned a lot about gophers today.</p></div>
+<footer>Copyright, all rights reserved, nothing to see here at all really.</footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/gophers")
+
+	cases := []struct {
+		name    string
+		page    string
+		want    []string
+		notWant []string
+		fails   bool
+	}{
+		{
+			name:    "finds the article among the page furniture",
+			page:    articlePage,
+			want:    []string{"All about gophers", "Gophers dig long tunnels"},
+			notWant: []string{"newsletter", "Great article", "Copyright", "Home", "tracking"},
+		},
+		{
+			name: "resolves links against the page",
+			page: articlePage,
+			want: []string{`href="https://example.com/tunnels"`, `src="https://example.com/posts/img/gopher.png"`},
+		},
+		{
+			name: "articles split over sibling paragraphs are kept together",
+			page: `<html><body><div><p>` + prose + `</p></div><p>` + prose + prose + `</p></body></html>`,
+			want: []string{prose + prose},
+		},
+		{
+			name:  "too little text",
+			page:  `<html><body><div><p>Subscribe to read the rest of this story, it's worth it.</p></div></body></html>`,
+			fails: true,
+		},
+		{
+			name:  "nothing to score",
+			page:  `<html><body><ul><li>one</li><li>two</li></ul></body></html>`,
+			fails: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Extract(strings.NewReader(c.page), base)
+			if c.fails {
+				if err == nil {
+					t.Fatalf("expected extraction to fail, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extracting: %v", err)
+			}
+			for _, want := range c.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in\n%v", want, got)
+				}
+			}
+			for _, notWant := range c.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("didn't expect %q in\n%v", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractBreaksTiesInDocumentOrder(t *testing.T) {
+	// both sections score exactly the same, the first one on the page should win every time
+	page := `<html><body>
+<section><div><p>first ` + prose + `</p><p>` + prose + `</p></div></section>
+<section><div><p>other ` + prose + `</p><p>` + prose + `</p></div></section>
+</body></html>`
+
+	for range 20 {
+		got, err := Extract(strings.NewReader(page), nil)
+		if err != nil {
+			t.Fatalf("extracting: %v", err)
+		}
+		if !strings.Contains(got, "first") || strings.Contains(got, "other") {
+			t.Fatalf("expected only the first section, got\n%v", got)
+		}
+	}
+}
+
+func TestFetchArticle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/posts/gophers":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(articlePage))
+		case "/feed.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	got, err := FetchArticle(context.Background(), server.URL+"/posts/gophers")
+	if err != nil {
+		t.Fatalf("fetching article: %v", err)
+	}
+	if !strings.Contains(got, `href="`+server.URL+`/tunnels"`) {
+		t.Fatalf("expected links resolved against %v, got\n%v", server.URL, got)
+	}
+
+	if _, err := FetchArticle(context.Background(), server.URL+"/feed.json"); err == nil {
+		t.Fatal("expected a non html page to be refused")
+	}
+	if _, err := FetchArticle(context.Background(), server.URL+"/missing"); err == nil {
+		t.Fatal("expected a missing page to fail")
+	}
+}
@@ -0,0 +1,310 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToText renders an html fragment (usually a post description) as plain text
+// wrapped to the given width. Links are replaced by numbered footnotes listed at the end
+func HTMLToText(src string, width int) string {
+	nodes, err := html.ParseFragment(strings.NewReader(src), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return src
+	}
+
+	r := renderer{width: width}
+	for _, node := range nodes {
+		r.walk(node)
+	}
+	r.flush()
+
+	text := strings.TrimRight(r.out.String(), "\n")
+	if len(r.links) > 0 {
+		text += "\n\n"
+		for i, link := range r.links {
+			text += fmt.Sprintf("[%d] %v\n", i+1, link)
+		}
+		text = strings.TrimRight(text, "\n")
+	}
+
+	return text
+}
+
+type renderer struct {
+	width int
+	out   strings.Builder
+
+	// inline text waiting to be wrapped into the current block
+	inline strings.Builder
+	// prefix put in front of every line, grows with blockquotes and lists
+	prefix []string
+	// replaces the last prefix on the first line of a block, used for list bullets
+	marker string
+	// counters for every open list, 0 means unordered
+	lists []int
+	links []string
+	pre   int
+	// set when the next line written should be preceded by an empty one, deferring
+	// it means blocks are never separated twice and nothing trails at the end
+	pendingBlank bool
+	blankPrefix  string
+}
+
+func (r *renderer) walk(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		r.text(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.children(node)
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Noscript, atom.Iframe:
+		return
+
+	case atom.Br:
+		r.flush()
+
+	case atom.Hr:
+		r.block()
+		r.writeLine(strings.Repeat("-", r.available()))
+		r.blank()
+
+	case atom.Img:
+		alt := strings.TrimSpace(attr(node, "alt"))
+		if alt == "" {
+			r.text(" [image] ")
+		} else {
+			r.text(" [image: " + alt + "] ")
+		}
+
+	case atom.A:
+		start := r.inline.Len()
+		r.children(node)
+
+		href := strings.TrimSpace(attr(node, "href"))
+		if href == "" || strings.HasPrefix(href, "#") {
+			return
+		}
+		if start <= r.inline.Len() && strings.TrimSpace(r.inline.String()[start:]) == href {
+			return
+		}
+		r.links = append(r.links, href)
+		r.text(fmt.Sprintf("[%d]", len(r.links)))
+
+	case atom.Code:
+		if r.pre > 0 {
+			r.children(node)
+			return
+		}
+		r.text("`")
+		r.children(node)
+		r.text("`")
+
+	case atom.Pre:
+		r.block()
+		r.pre++
+		r.prefix = append(r.prefix, "    ")
+		r.children(node)
+		r.flush()
+		r.prefix = r.prefix[:len(r.prefix)-1]
+		r.pre--
+		r.blank()
+
+	case atom.Blockquote:
+		r.block()
+		r.prefix = append(r.prefix, "> ")
+		r.children(node)
+		r.block()
+		r.prefix = r.prefix[:len(r.prefix)-1]
+		r.blank()
+
+	case atom.Ul, atom.Ol:
+		if len(r.lists) == 0 {
+			r.block()
+		} else {
+			r.flush()
+		}
+		counter := 0
+		if node.DataAtom == atom.Ol {
+			counter = 1
+		}
+		r.lists = append(r.lists, counter)
+		r.children(node)
+		r.flush()
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.blank()
+		}
+
+	case atom.Li:
+		r.flush()
+		bullet := "* "
+		if len(r.lists) > 0 && r.lists[len(r.lists)-1] > 0 {
+			bullet = fmt.Sprintf("%d. ", r.lists[len(r.lists)-1])
+			r.lists[len(r.lists)-1]++
+		}
+		r.prefix = append(r.prefix, strings.Repeat(" ", utf8.RuneCountInString(bullet)))
+		r.marker = bullet
+		r.children(node)
+		r.flush()
+		r.prefix = r.prefix[:len(r.prefix)-1]
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.block()
+		r.text(strings.Repeat("#", int(node.Data[1]-'0')) + " ")
+		r.children(node)
+		r.block()
+
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Figure, atom.Figcaption, atom.Table, atom.Dl, atom.Dd, atom.Dt:
+		r.block()
+		r.children(node)
+		r.block()
+
+	case atom.Tr:
+		r.flush()
+		r.children(node)
+		r.flush()
+
+	case atom.Td, atom.Th:
+		r.children(node)
+		r.text(" ")
+
+	default:
+		r.children(node)
+	}
+}
+
+func (r *renderer) children(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+func (r *renderer) text(data string) {
+	if r.pre > 0 {
+		r.inline.WriteString(data)
+		return
+	}
+
+	collapsed := strings.Join(strings.Fields(data), " ")
+	if collapsed == "" {
+		if data != "" && r.inline.Len() > 0 {
+			r.inline.WriteString(" ")
+		}
+		return
+	}
+
+	if startsWithSpace(data) && r.inline.Len() > 0 {
+		r.inline.WriteString(" ")
+	}
+	r.inline.WriteString(collapsed)
+	if endsWithSpace(data) {
+		r.inline.WriteString(" ")
+	}
+}
+
+// block ends the current paragraph and separates it from whatever comes next
+func (r *renderer) block() {
+	if r.inline.Len() == 0 {
+		return
+	}
+	r.flush()
+	r.blank()
+}
+
+// flush writes out any pending inline text, wrapping it unless inside a <pre>
+func (r *renderer) flush() {
+	text := r.inline.String()
+	r.inline.Reset()
+
+	if r.pre > 0 {
+		for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+			r.writeLine(strings.TrimRight(line, " \t"))
+		}
+		return
+	}
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return
+	}
+
+	available := r.available()
+	line := ""
+	for _, word := range words {
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > available {
+			r.writeLine(line)
+			line = ""
+		}
+
+		if line == "" {
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+	r.writeLine(line)
+}
+
+func (r *renderer) writeLine(line string) {
+	prefix := strings.Join(r.prefix, "")
+	if r.pendingBlank {
+		r.out.WriteString(r.blankPrefix + "\n")
+		r.pendingBlank = false
+	}
+
+	if r.marker != "" && len(r.prefix) > 0 {
+		prefix = strings.Join(r.prefix[:len(r.prefix)-1], "") + r.marker
+		r.marker = ""
+	}
+
+	r.out.WriteString(strings.TrimRight(prefix+line, " "))
+	r.out.WriteString("\n")
+}
+
+// blank separates whatever is written next from the previous block
+func (r *renderer) blank() {
+	if r.out.Len() > 0 {
+		r.pendingBlank = true
+		r.blankPrefix = strings.TrimRight(strings.Join(r.prefix, ""), " ")
+	}
+}
+
+func (r *renderer) available() int {
+	available := r.width - utf8.RuneCountInString(strings.Join(r.prefix, ""))
+	if available < 20 {
+		return 20
+	}
+	return available
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func startsWithSpace(data string) bool {
+	return strings.TrimLeft(data, " \t\r\n") != data
+}
+
+func endsWithSpace(data string) bool {
+	return strings.TrimRight(data, " \t\r\n") != data
+}
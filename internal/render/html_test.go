@@ -0,0 +1,83 @@
+package render
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	cases := []struct {
+		name  string
+		html  string
+		width int
+		want  string
+	}{
+		{
+			name:  "links become footnotes",
+			html:  `<p>See <a href="https://example.com/a">the docs</a> and <a href="https://example.com/b">this</a>.</p>`,
+			width: 80,
+			want:  "See the docs[1] and this[2].\n\n[1] https://example.com/a\n[2] https://example.com/b",
+		},
+		{
+			name:  "bare and in page links stay inline",
+			html:  `<p><a href="https://example.com/c">https://example.com/c</a> <a href="#top">top</a></p>`,
+			width: 80,
+			want:  "https://example.com/c top",
+		},
+		{
+			name:  "images show their alt text",
+			html:  `<p>A chart <img src="x.png" alt="growth chart"> and <img src="y.png"></p>`,
+			width: 80,
+			want:  "A chart [image: growth chart] and [image]",
+		},
+		{
+			name:  "lists",
+			html:  `<ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul><ol><li>first</li><li>second</li></ol>`,
+			width: 80,
+			want:  "* one\n* two\n  * nested\n\n1. first\n2. second",
+		},
+		{
+			name:  "blockquotes",
+			html:  `<p>before</p><blockquote><p>quoted</p><p>twice</p></blockquote><p>after</p>`,
+			width: 80,
+			want:  "before\n\n> quoted\n>\n> twice\n\nafter",
+		},
+		{
+			name: "escaped markup in code stays text",
+			html: `<p>Use this:</p><pre><code>&lt;div class="x"&gt;
+  a &amp;&amp; b
+&lt;/div&gt;</code></pre><p>and <code>&lt;br&gt;</code> inline</p>`,
+			width: 80,
+			want:  "Use this:\n\n    <div class=\"x\">\n      a && b\n    </div>\n\nand `<br>` inline",
+		},
+		{
+			name:  "pre blocks aren't wrapped",
+			html:  `<pre>a line that is far longer than the width it is rendered at</pre>`,
+			width: 20,
+			want:  "    a line that is far longer than the width it is rendered at",
+		},
+		{
+			name:  "wrapping",
+			html:  `<p>the quick brown fox jumps over the lazy dog again and again</p>`,
+			width: 20,
+			want:  "the quick brown fox\njumps over the lazy\ndog again and again",
+		},
+		{
+			name:  "wrapping counts the quote prefix",
+			html:  `<blockquote>the quick brown fox jumps over the lazy dog</blockquote>`,
+			width: 30,
+			want:  "> the quick brown fox jumps\n> over the lazy dog",
+		},
+		{
+			name:  "scripts are dropped",
+			html:  `<p>kept</p><script>alert(1)</script>`,
+			width: 80,
+			want:  "kept",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := HTMLToText(c.html, c.width); got != c.want {
+				t.Fatalf("expected\n%v\ngot\n%v", c.want, got)
+			}
+		})
+	}
+}
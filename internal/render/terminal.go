@@ -0,0 +1,38 @@
+package render
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const defaultWidth = 80
+
+// TerminalWidth reports the width of the terminal attached to stdout, falling back
+// to $COLUMNS and then 80 columns when output is piped somewhere else
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err == nil && width > 0 {
+		return width
+	}
+
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err == nil && columns > 0 {
+		return columns
+	}
+
+	return defaultWidth
+}
+
+// Indent puts prefix in front of every non empty line of text
+func Indent(text string, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
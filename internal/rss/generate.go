@@ -0,0 +1,200 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Timeline is a feed gator publishes itself, built out of posts it has collected
+type Timeline struct {
+	ID          string
+	Title       string
+	Description string
+	// url of the feed document itself
+	SelfURL string
+	Updated time.Time
+	Entries []TimelineEntry
+}
+
+type TimelineEntry struct {
+	ID        string
+	Title     string
+	Link      string
+	Author    string
+	Summary   string
+	Content   string
+	Published time.Time
+	Updated   time.Time
+	// the feed the entry was originally published in
+	SourceTitle string
+	SourceURL   string
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	SelfLink      atomLink     `xml:"atom:link"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Items         []rssOutItem `xml:"item"`
+}
+
+type rssOutItem struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	GUID        rssGUID   `xml:"guid"`
+	PubDate     string    `xml:"pubDate,omitempty"`
+	Creator     string    `xml:"dc:creator,omitempty"`
+	Description string    `xml:"description"`
+	Source      rssSource `xml:"source"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Link      atomLink    `xml:"link"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+	Source    atomSource  `xml:"source"`
+}
+
+type atomSource struct {
+	Title string   `xml:"title"`
+	Link  atomLink `xml:"link"`
+}
+
+func (t Timeline) WriteRSS(w io.Writer) error {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         t.Title,
+			Link:          t.SelfURL,
+			Description:   t.Description,
+			SelfLink:      atomLink{Href: t.SelfURL, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: t.Updated.Format(time.RFC1123Z),
+		},
+	}
+
+	for _, entry := range t.Entries {
+		item := rssOutItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			GUID:        rssGUID{IsPermaLink: false, Value: entry.ID},
+			Creator:     entry.Author,
+			Description: entry.Content,
+			Source:      rssSource{URL: entry.SourceURL, Title: entry.SourceTitle},
+		}
+		if item.Description == "" {
+			item.Description = entry.Summary
+		}
+		if !entry.Published.IsZero() {
+			item.PubDate = entry.Published.Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	return writeXML(w, doc)
+}
+
+func (t Timeline) WriteAtom(w io.Writer) error {
+	doc := atomDocument{
+		ID:      t.ID,
+		Title:   t.Title,
+		Updated: t.Updated.Format(time.RFC3339),
+		Author:  atomPerson{Name: "gator"},
+		Links:   []atomLink{{Href: t.SelfURL, Rel: "self", Type: "application/atom+xml"}},
+	}
+
+	for _, entry := range t.Entries {
+		item := atomEntry{
+			ID:      entry.ID,
+			Title:   entry.Title,
+			Updated: entry.Updated.Format(time.RFC3339),
+			Link:    atomLink{Href: entry.Link, Rel: "alternate"},
+			Source: atomSource{
+				Title: entry.SourceTitle,
+				Link:  atomLink{Href: entry.SourceURL, Rel: "self"},
+			},
+		}
+		if !entry.Published.IsZero() {
+			item.Published = entry.Published.Format(time.RFC3339)
+		}
+		if entry.Author != "" {
+			item.Author = &atomPerson{Name: entry.Author}
+		}
+		if entry.Summary != "" {
+			item.Summary = &atomText{Type: "html", Value: entry.Summary}
+		}
+		if entry.Content != "" {
+			item.Content = &atomText{Type: "html", Value: entry.Content}
+		}
+		doc.Entries = append(doc.Entries, item)
+	}
+
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("unable to write feed: %v", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -8,11 +8,15 @@ import (
 	"html"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
+		Title string `xml:"title"`
+		// declared before Link so a self referencing <atom:link/> doesn't overwrite the site link
+		AtomLink    string    `xml:"http://www.w3.org/2005/Atom link"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
 		Item        []RSSItem `xml:"item"`
@@ -20,10 +24,46 @@ type RSSFeed struct {
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []string `xml:"category"`
+}
+
+// AuthorName prefers dc:creator since <author> is meant to hold an email address
+func (i RSSItem) AuthorName() string {
+	if i.Creator != "" {
+		return i.Creator
+	}
+	return i.Author
+}
+
+// layouts seen in the wild for pubDate, RFC 822 is what the spec asks for
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"2006-01-02",
+}
+
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date format: %v", value)
 }
 
 func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
@@ -59,9 +99,13 @@ func cleanFeed(feed *RSSFeed) error {
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
 
-	for _, item := range feed.Channel.Item {
-		item.Description = html.UnescapeString(item.Description)
-		item.Title = html.UnescapeString(item.Title)
+	for i := range feed.Channel.Item {
+		// descriptions and content are html and xml already decoded them once, unescaping
+		// again would turn escaped markup like a code sample's &lt;div&gt; into real tags
+		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
+		for j, category := range feed.Channel.Item[i].Categories {
+			feed.Channel.Item[i].Categories[j] = strings.TrimSpace(html.UnescapeString(category))
+		}
 	}
 
 	return nil
@@ -77,4 +121,4 @@ func (f RSSFeed) PrintFeed() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,50 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const escapedFeed = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>Tom &amp; Jerry</title>
+	<item>
+		<title>Cats &amp;amp; dogs</title>
+		<description>&lt;pre&gt;&amp;lt;div&amp;gt;&lt;/pre&gt;</description>
+		<content:encoded><![CDATA[<p>Run &lt;script&gt;alert(1)&lt;/script&gt;</p>]]></content:encoded>
+		<category> Go </category>
+	</item>
+</channel>
+</rss>`
+
+func TestFetchFeedDecodesOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(escapedFeed))
+	}))
+	t.Cleanup(server.Close)
+
+	feed, err := FetchFeed(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("fetching feed: %v", err)
+	}
+	if feed.Channel.Title != "Tom & Jerry" {
+		t.Fatalf("unexpected channel title %q", feed.Channel.Title)
+	}
+
+	item := feed.Channel.Item[0]
+	if item.Title != "Cats & dogs" {
+		t.Fatalf("expected titles to be plain text, got %q", item.Title)
+	}
+	if want := "<pre>&lt;div&gt;</pre>"; item.Description != want {
+		t.Fatalf("expected the description's code to stay escaped %q, got %q", want, item.Description)
+	}
+	if want := "<p>Run &lt;script&gt;alert(1)&lt;/script&gt;</p>"; item.Content != want {
+		t.Fatalf("expected the content's markup to stay escaped %q, got %q", want, item.Content)
+	}
+	if len(item.Categories) != 1 || item.Categories[0] != "Go" {
+		t.Fatalf("unexpected categories %q", item.Categories)
+	}
+}
@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// the parts of a post a rule can look at
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldCategory    = "category"
+	// matches either the name the feed is followed under or its url
+	FieldFeed = "feed"
+)
+
+// what happens to a post a rule matches
+const (
+	ActionHide = "hide"
+	ActionRead = "read"
+	ActionStar = "star"
+	ActionTag  = "tag"
+)
+
+var (
+	Fields  = []string{FieldTitle, FieldDescription, FieldAuthor, FieldCategory, FieldFeed}
+	Actions = []string{ActionHide, ActionRead, ActionStar, ActionTag}
+)
+
+// Post is what rules are matched against, the description may hold html
+type Post struct {
+	Title       string
+	Description string
+	Author      string
+	Categories  []string
+	Feed        string
+	FeedURL     string
+}
+
+type Matcher struct {
+	field   string
+	pattern string
+	re      *regexp.Regexp
+}
+
+// Compile checks a rule's field and pattern. Plain patterns match case insensitive
+// substrings, regexes are used as written so they can opt in with (?i)
+func Compile(field, pattern string, regex bool) (Matcher, error) {
+	if !valid(Fields, field) {
+		return Matcher{}, fmt.Errorf("unknown field %v, expected one of %v", field, strings.Join(Fields, ", "))
+	}
+	if pattern == "" {
+		return Matcher{}, fmt.Errorf("pattern can't be empty")
+	}
+
+	m := Matcher{field: field, pattern: strings.ToLower(pattern)}
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return Matcher{}, fmt.Errorf("invalid regex: %v", err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+func ValidAction(action string) bool {
+	return valid(Actions, action)
+}
+
+func (m Matcher) Match(post Post) bool {
+	switch m.field {
+	case FieldTitle:
+		return m.matchValue(post.Title)
+	case FieldDescription:
+		return m.matchValue(text(post.Description))
+	case FieldAuthor:
+		return m.matchValue(post.Author)
+	case FieldCategory:
+		for _, category := range post.Categories {
+			if m.matchValue(category) {
+				return true
+			}
+		}
+		return false
+	case FieldFeed:
+		return m.matchValue(post.Feed) || m.matchValue(post.FeedURL)
+	}
+	return false
+}
+
+func (m Matcher) matchValue(value string) bool {
+	if value == "" {
+		return false
+	}
+	if m.re != nil {
+		return m.re.MatchString(value)
+	}
+	return strings.Contains(strings.ToLower(value), m.pattern)
+}
+
+// text drops the markup of a description so patterns don't match tags or attributes,
+// whitespace is collapsed so a phrase still matches across inline tags like links
+func text(src string) string {
+	var out strings.Builder
+	tokens := html.NewTokenizer(strings.NewReader(src))
+	for {
+		switch tokens.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(out.String()), " ")
+		case html.TextToken:
+			out.Write(tokens.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			out.WriteByte(' ')
+		}
+	}
+}
+
+func valid(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package rules
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	post := Post{
+		Title:       "Go 1.24 is released",
+		Description: `<p class="sponsored">A <a href="https://example.com/golang">new version</a> of Go</p>`,
+		Author:      "The Go Team",
+		Categories:  []string{"Releases", "Programming Languages"},
+		Feed:        "Go Blog",
+		FeedURL:     "https://go.dev/blog/feed.atom",
+	}
+
+	cases := []struct {
+		name    string
+		field   string
+		pattern string
+		regex   bool
+		want    bool
+	}{
+		{"plain patterns ignore case", FieldTitle, "go 1.24", false, true},
+		{"plain patterns match substrings", FieldTitle, "releas", false, true},
+		{"plain patterns aren't regexes", FieldTitle, "go .*", false, false},
+		{"regexes are used as written", FieldTitle, `^Go \d+\.\d+`, true, true},
+		{"regexes are case sensitive", FieldTitle, `^go`, true, false},
+		{"regexes can opt out of case", FieldTitle, `(?i)^go`, true, true},
+		{"description text", FieldDescription, "new version of go", false, true},
+		{"description tags", FieldDescription, "<p", false, false},
+		{"description attributes", FieldDescription, "sponsored", false, false},
+		{"description link targets", FieldDescription, "golang", false, false},
+		{"author", FieldAuthor, "go team", false, true},
+		{"any category", FieldCategory, "programming", false, true},
+		{"whole category regex", FieldCategory, `^Releases$`, true, true},
+		{"missing category", FieldCategory, "security", false, false},
+		{"feed name", FieldFeed, "go blog", false, true},
+		{"feed url", FieldFeed, "go.dev", false, true},
+		{"feed doesn't look at the title", FieldFeed, "released", false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := Compile(c.field, c.pattern, c.regex)
+			if err != nil {
+				t.Fatalf("compiling rule: %v", err)
+			}
+			if got := m.Match(post); got != c.want {
+				t.Fatalf("expected %v %q to match %v, got %v", c.field, c.pattern, c.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchEmptyValues(t *testing.T) {
+	m, err := Compile(FieldAuthor, ".*", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Match(Post{Title: "no author"}) {
+		t.Fatal("a missing value shouldn't match anything")
+	}
+}
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		name    string
+		field   string
+		pattern string
+		regex   bool
+	}{
+		{"unknown field", "body", "x", false},
+		{"empty pattern", FieldTitle, "", false},
+		{"invalid regex", FieldTitle, "(", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Compile(c.field, c.pattern, c.regex); err == nil {
+				t.Fatal("expected the rule to be refused")
+			}
+		})
+	}
+
+	if _, err := Compile(FieldTitle, "(", false); err != nil {
+		t.Fatalf("plain patterns aren't parsed as regexes: %v", err)
+	}
+	if !ValidAction(ActionHide) || ValidAction("delete") {
+		t.Fatal("unexpected action validation")
+	}
+}
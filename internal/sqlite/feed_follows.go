@@ -0,0 +1,263 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// sqlite has no INSERT inside WITH, so the follow is inserted first and then read
+// back joined with its feed and user
+const createFeedFollow = `-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, custom_name, folder)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7
+)
+`
+
+const getCreatedFeedFollow = `-- name: CreateFeedFollow :one
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.custom_name, feed_follows.folder,
+    feeds.name AS feed_name,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = feed_follows.user_id
+WHERE feed_follows.id = ?1
+`
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	_, err := q.db.ExecContext(ctx, createFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.CustomName,
+		arg.Folder,
+	)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+
+	row := q.db.QueryRowContext(ctx, getCreatedFeedFollow, arg.ID)
+	var i database.CreateFeedFollowRow
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.CustomName,
+		&i.Folder,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+const deleteFeedFollowForUser = `-- name: DeleteFeedFollowForUser :execrows
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = ?1
+AND feed_follows.feed_id = 
+    (SELECT id FROM feeds
+    WHERE url = ?2
+    )
+`
+
+func (q *Queries) DeleteFeedFollowForUser(ctx context.Context, arg database.DeleteFeedFollowForUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowForUser, arg.UserID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const feedFollowsReset = `-- name: FeedFollowsReset :execrows
+DELETE FROM feed_follows
+`
+
+func (q *Queries) FeedFollowsReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, feedFollowsReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
+SELECT
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+feeds.site_url,
+feed_follows.folder
+FROM feed_follows
+INNER JOIN users
+ON users.id = feed_follows.user_id
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = ?1
+ORDER BY feed_follows.folder NULLS FIRST, feed_name
+`
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedFollowsForUserRow
+	for rows.Next() {
+		var i database.GetFeedFollowsForUserRow
+		if err := rows.Scan(
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.SiteUrl,
+			&i.Folder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFollowName = `-- name: SetFeedFollowName :execrows
+UPDATE feed_follows
+SET custom_name = ?1,
+    updated_at = ?2
+WHERE feed_follows.user_id = ?3
+AND feed_follows.feed_id =
+    (SELECT id FROM feeds
+    WHERE url = ?4
+    )
+`
+
+func (q *Queries) SetFeedFollowName(ctx context.Context, arg database.SetFeedFollowNameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowName,
+		arg.CustomName,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Url,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getSubscriptionsForUser = `-- name: GetSubscriptionsForUser :many
+SELECT
+feeds.id,
+feeds.url,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.site_url,
+feeds.last_fetched_at,
+feed_follows.folder,
+feed_follows.created_at
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = ?1
+ORDER BY feed_name
+`
+
+func (q *Queries) GetSubscriptionsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetSubscriptionsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSubscriptionsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetSubscriptionsForUserRow
+	for rows.Next() {
+		var i database.GetSubscriptionsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.FeedName,
+			&i.SiteUrl,
+			&i.LastFetchedAt,
+			&i.Folder,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder = ?1,
+    updated_at = ?2
+WHERE feed_follows.user_id = ?3
+AND feed_follows.feed_id =
+    (SELECT id FROM feeds
+    WHERE url = ?4
+    )
+`
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.Folder,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Url,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedFollowsInvolvingUser = `-- name: DeleteFeedFollowsInvolvingUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = ?1
+OR feed_id IN (
+    SELECT id FROM feeds
+    WHERE feeds.user_id = ?1
+)
+`
+
+// the user's own follows and every follow of a feed they added
+func (q *Queries) DeleteFeedFollowsInvolvingUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsInvolvingUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countOtherFeedFollowers = `-- name: CountOtherFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = ?1
+AND user_id != ?2
+`
+
+func (q *Queries) CountOtherFeedFollowers(ctx context.Context, arg database.CountOtherFeedFollowersParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherFeedFollowers, arg.FeedID, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
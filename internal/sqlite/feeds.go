@@ -0,0 +1,412 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const addFeed = `-- name: AddFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_url) 
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts
+`
+
+func (q *Queries) AddFeed(ctx context.Context, arg database.AddFeedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, addFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+		arg.SiteUrl,
+	)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const feedsReset = `-- name: FeedsReset :execrows
+DELETE FROM feeds
+`
+
+func (q *Queries) FeedsReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, feedsReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedByUrl = `-- name: GetFeedByUrl :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts FROM feeds
+WHERE url = ?1
+`
+
+func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const getFeeds = `-- name: GetFeeds :many
+SELECT feeds.name, feeds.url, users.name as user_name FROM feeds
+INNER JOIN users
+ON users.id = feeds.user_id
+`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedsRow
+	for rows.Next() {
+		var i database.GetFeedsRow
+		if err := rows.Scan(&i.Name, &i.Url, &i.UserName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts FROM feeds
+ORDER BY last_fetched_at ASC
+NULLS FIRST
+LIMIT 1
+`
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const listFeeds = `-- name: ListFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.fetch_full_content, feeds.site_url, feeds.retention_max_age_days, feeds.retention_max_posts, users.name AS user_name FROM feeds
+INNER JOIN users
+ON users.id = feeds.user_id
+ORDER BY feeds.created_at, feeds.id
+LIMIT ?1
+OFFSET ?2
+`
+
+func (q *Queries) ListFeeds(ctx context.Context, arg database.ListFeedsParams) ([]database.ListFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeds, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ListFeedsRow
+	for rows.Next() {
+		var i database.ListFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.SiteUrl,
+			&i.RetentionMaxAgeDays,
+			&i.RetentionMaxPosts,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFetched = `-- name: MarkFeedFetched :one
+UPDATE feeds
+SET last_fetched_at = ?2,
+    updated_at = ?2
+WHERE id = ?1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts
+`
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.SiteUrl,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const setFeedFullContent = `-- name: SetFeedFullContent :execrows
+UPDATE feeds
+SET fetch_full_content = ?2,
+    updated_at = ?3
+WHERE url = ?1
+`
+
+func (q *Queries) SetFeedFullContent(ctx context.Context, arg database.SetFeedFullContentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFullContent, arg.Url, arg.FetchFullContent, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedSiteUrl = `-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET site_url = ?2
+WHERE id = ?1
+`
+
+func (q *Queries) SetFeedSiteUrl(ctx context.Context, arg database.SetFeedSiteUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteUrl, arg.ID, arg.SiteUrl)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, site_url, retention_max_age_days, retention_max_posts FROM feeds
+ORDER BY name, url
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Feed
+	for rows.Next() {
+		var i database.Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.SiteUrl,
+			&i.RetentionMaxAgeDays,
+			&i.RetentionMaxPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :execrows
+UPDATE feeds
+SET retention_max_age_days = ?2,
+    retention_max_posts = ?3,
+    updated_at = ?4
+WHERE url = ?1
+`
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedRetention,
+		arg.Url,
+		arg.RetentionMaxAgeDays,
+		arg.RetentionMaxPosts,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedsForUser = `-- name: DeleteFeedsForUser :execrows
+DELETE FROM feeds
+WHERE user_id = ?1
+`
+
+func (q *Queries) DeleteFeedsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedsForUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = ?1,
+    updated_at = ?2
+WHERE user_id = ?3
+`
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds,
+		arg.ToUserID,
+		arg.UpdatedAt,
+		arg.FromUserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFeedsToFollowers = `-- name: TransferFeedsToFollowers :execrows
+UPDATE feeds
+SET user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        AND feed_follows.user_id != ?1
+        ORDER BY feed_follows.created_at, feed_follows.id
+        LIMIT 1
+    ),
+    updated_at = ?2
+WHERE user_id = ?1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id != ?1
+)
+`
+
+// hands each feed the user added to whoever has followed it the longest, feeds
+// nobody else follows are left alone
+func (q *Queries) TransferFeedsToFollowers(ctx context.Context, arg database.TransferFeedsToFollowersParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeedsToFollowers, arg.UserID, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = ?1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFeed = `-- name: RenameFeed :execrows
+UPDATE feeds
+SET name = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameFeed,
+		arg.ID,
+		arg.Name,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :execrows
+UPDATE feeds
+SET url = ?2,
+    last_fetched_at = NULL,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+// the feed is fetched again straight away from its new url
+func (q *Queries) SetFeedUrl(ctx context.Context, arg database.SetFeedUrlParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedUrl,
+		arg.ID,
+		arg.Url,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,21 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, category)
+VALUES (
+    ?1,
+    ?2
+)
+ON CONFLICT (post_id, category) DO NOTHING
+`
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg database.CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory, arg.PostID, arg.Category)
+	return err
+}
@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+const hidePost = `-- name: HidePost :exec
+INSERT INTO post_hides (user_id, post_id, rule_id, hidden_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4
+)
+ON CONFLICT (user_id, post_id, rule_id) DO NOTHING
+`
+
+func (q *Queries) HidePost(ctx context.Context, arg database.HidePostParams) error {
+	_, err := q.db.ExecContext(ctx, hidePost,
+		arg.UserID,
+		arg.PostID,
+		arg.RuleID,
+		arg.HiddenAt,
+	)
+	return err
+}
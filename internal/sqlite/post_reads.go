@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const markPostUnread = `-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = ?1
+AND post_id = ?2
+`
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostsReadBySeq = `-- name: MarkPostsReadBySeq :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, ?1
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?2
+AND posts.seq IN (SELECT value FROM json_each(?3))
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) MarkPostsReadBySeq(ctx context.Context, arg database.MarkPostsReadBySeqParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsReadBySeq, arg.ReadAt, arg.UserID, jsonList(arg.Seqs))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostsUnreadBySeq = `-- name: MarkPostsUnreadBySeq :execrows
+DELETE FROM post_reads
+WHERE post_reads.user_id = ?1
+AND post_reads.post_id IN (
+    SELECT posts.id FROM posts
+    WHERE posts.seq IN (SELECT value FROM json_each(?2))
+)
+`
+
+func (q *Queries) MarkPostsUnreadBySeq(ctx context.Context, arg database.MarkPostsUnreadBySeqParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsUnreadBySeq, arg.UserID, jsonList(arg.Seqs))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostsReadBefore = `-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, ?1
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = ?2
+AND (?3 IS NULL OR feeds.url = ?3)
+AND (?4 IS NULL OR feed_follows.folder = ?4)
+AND posts.created_at < ?5
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) MarkPostsReadBefore(ctx context.Context, arg database.MarkPostsReadBeforeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsReadBefore,
+		arg.ReadAt,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.Folder,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+const starPost = `-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID, arg.StarredAt)
+	return err
+}
+
+const unstarPost = `-- name: UnstarPost :execrows
+DELETE FROM post_stars
+WHERE user_id = ?1
+AND post_id = ?2
+`
+
+func (q *Queries) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const starPostsBySeq = `-- name: StarPostsBySeq :execrows
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT feed_follows.user_id, posts.id, ?1
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?2
+AND posts.seq IN (SELECT value FROM json_each(?3))
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) StarPostsBySeq(ctx context.Context, arg database.StarPostsBySeqParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, starPostsBySeq, arg.StarredAt, arg.UserID, jsonList(arg.Seqs))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unstarPostsBySeq = `-- name: UnstarPostsBySeq :execrows
+DELETE FROM post_stars
+WHERE post_stars.user_id = ?1
+AND post_stars.post_id IN (
+    SELECT posts.id FROM posts
+    WHERE posts.seq IN (SELECT value FROM json_each(?2))
+)
+`
+
+func (q *Queries) UnstarPostsBySeq(ctx context.Context, arg database.UnstarPostsBySeqParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unstarPostsBySeq, arg.UserID, jsonList(arg.Seqs))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+const tagPost = `-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4
+)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING
+`
+
+func (q *Queries) TagPost(ctx context.Context, arg database.TagPostParams) error {
+	_, err := q.db.ExecContext(ctx, tagPost,
+		arg.UserID,
+		arg.PostID,
+		arg.Tag,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const untagPost = `-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = ?1
+AND post_id = ?2
+AND tag = ?3
+`
+
+func (q *Queries) UntagPost(ctx context.Context, arg database.UntagPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagPost, arg.UserID, arg.PostID, arg.Tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
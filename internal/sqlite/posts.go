@@ -0,0 +1,863 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const browsePostsForUser = `-- name: BrowsePostsForUser :many
+WITH timeline AS (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+    COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    post_reads.read_at,
+    (CASE WHEN ?1
+        THEN COALESCE(posts.published_at, posts.created_at)
+        ELSE posts.created_at
+    END) AS sort_key
+    FROM posts
+    INNER JOIN feed_follows
+    ON feed_follows.feed_id = posts.feed_id
+    INNER JOIN feeds
+    ON feeds.id = posts.feed_id
+    LEFT JOIN post_reads
+    ON post_reads.post_id = posts.id
+    AND post_reads.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = ?2
+    AND NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = posts.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+    AND (?3 IS NULL
+        OR feeds.url = ?3
+        OR feeds.name = ?3
+        OR feed_follows.custom_name = ?3)
+    AND (NOT ?4 OR post_reads.read_at IS NULL)
+)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, seq, feed_name, feed_url, read_at, sort_key FROM timeline
+WHERE (?5 IS NULL OR sort_key >= ?5)
+AND (?6 IS NULL OR sort_key < ?6)
+AND (?7 IS NULL
+    OR (?8 AND (sort_key, id) > (?7, ?9))
+    OR (NOT ?8 AND (sort_key, id) < (?7, ?9)))
+ORDER BY
+    CASE WHEN ?8 THEN sort_key END ASC,
+    CASE WHEN ?8 THEN id END ASC,
+    CASE WHEN NOT ?8 THEN sort_key END DESC,
+    CASE WHEN NOT ?8 THEN id END DESC
+LIMIT ?10
+OFFSET ?11
+`
+
+func (q *Queries) BrowsePostsForUser(ctx context.Context, arg database.BrowsePostsForUserParams) ([]database.BrowsePostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsForUser,
+		arg.ByPublished,
+		arg.UserID,
+		arg.Feed,
+		arg.UnreadOnly,
+		arg.Since,
+		arg.Until,
+		arg.CursorKey,
+		arg.Ascending,
+		arg.CursorID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.BrowsePostsForUserRow
+	for rows.Next() {
+		var i database.BrowsePostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.ReadAt,
+			timeValue{&i.SortKey},
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (
+    id,
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    author,
+    content,
+    seq
+)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8,
+    ?9,
+    ?10,
+    ?11
+)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, seq
+`
+
+const nextPostSeq = `INSERT INTO post_seqs DEFAULT VALUES`
+
+// only the latest seq needs keeping, AUTOINCREMENT remembers it even once deleted
+const trimPostSeqs = `DELETE FROM post_seqs WHERE seq < ?1`
+
+// CreatePost draws the post's seq from post_seqs first, standing in for postgres'
+// BIGSERIAL. A post that fails to insert leaves a gap, just like a sequence would
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	result, err := q.db.ExecContext(ctx, nextPostSeq)
+	if err != nil {
+		return database.Post{}, err
+	}
+	seq, err := result.LastInsertId()
+	if err != nil {
+		return database.Post{}, err
+	}
+	if _, err := q.db.ExecContext(ctx, trimPostSeqs, seq); err != nil {
+		return database.Post{}, err
+	}
+
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Author,
+		arg.Content,
+		seq,
+	)
+	var i database.Post
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const exportPostsForUser = `-- name: ExportPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+post_reads.read_at,
+post_stars.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+AND (?2 IS NULL
+    OR feeds.url = ?2
+    OR feeds.name = ?2
+    OR feed_follows.custom_name = ?2)
+AND (?3 IS NULL OR COALESCE(posts.published_at, posts.created_at) >= ?3)
+AND (?4 IS NULL OR COALESCE(posts.published_at, posts.created_at) < ?4)
+AND (NOT ?5 OR post_stars.starred_at IS NOT NULL)
+AND (?6 IS NULL
+    OR (posts.created_at, posts.id) > (?6, ?7))
+ORDER BY posts.created_at, posts.id
+LIMIT ?8
+`
+
+func (q *Queries) ExportPostsForUser(ctx context.Context, arg database.ExportPostsForUserParams) ([]database.ExportPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, exportPostsForUser,
+		arg.UserID,
+		arg.Feed,
+		arg.Since,
+		arg.Until,
+		arg.StarredOnly,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ExportPostsForUserRow
+	for rows.Next() {
+		var i database.ExportPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+post_reads.read_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE posts.id = ?1
+AND feed_follows.user_id = ?2
+`
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.ID, arg.UserID)
+	var i database.GetPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Seq,
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.ReadAt,
+	)
+	return i, err
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND (?2 IS NULL
+    OR feed_follows.folder = ?2
+    OR substr(feed_follows.folder, 1, length(?2) + 1) = ?2 || '/')
+AND (?3 IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag = ?3
+    ))
+ORDER BY posts.created_at DESC
+LIMIT ?4
+`
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Folder,
+		arg.Tag,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPostsForUserRow
+	for rows.Next() {
+		var i database.GetPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content, arg.UpdatedAt)
+	return err
+}
+
+const countUnreadPostsByFeed = `-- name: CountUnreadPostsByFeed :many
+SELECT feeds.url AS feed_url,
+feed_follows.folder,
+COUNT(posts.id) AS unread,
+MAX(posts.created_at) AS newest
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND post_reads.read_at IS NULL
+GROUP BY feeds.url, feed_follows.folder
+`
+
+func (q *Queries) CountUnreadPostsByFeed(ctx context.Context, userID uuid.UUID) ([]database.CountUnreadPostsByFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, countUnreadPostsByFeed, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.CountUnreadPostsByFeedRow
+	for rows.Next() {
+		var i database.CountUnreadPostsByFeedRow
+		if err := rows.Scan(
+			&i.FeedUrl,
+			&i.Folder,
+			&i.Unread,
+			timeValue{&i.Newest},
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncPostRefs = `-- name: ListSyncPostRefs :many
+SELECT posts.seq,
+posts.created_at,
+feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND (?2 IS NULL OR feeds.url = ?2)
+AND (?3 IS NULL OR feed_follows.folder = ?3)
+AND (NOT ?4 OR post_reads.read_at IS NULL)
+AND (NOT ?5 OR post_reads.read_at IS NOT NULL)
+AND (NOT ?6 OR post_stars.starred_at IS NOT NULL)
+AND (?7 IS NULL OR posts.created_at >= ?7)
+AND (?8 IS NULL OR posts.created_at < ?8)
+AND (?9 IS NULL OR posts.seq > ?9)
+AND (?10 IS NULL OR posts.seq < ?10)
+ORDER BY
+    CASE WHEN ?11 THEN posts.seq END ASC,
+    posts.seq DESC
+LIMIT ?12
+`
+
+func (q *Queries) ListSyncPostRefs(ctx context.Context, arg database.ListSyncPostRefsParams) ([]database.ListSyncPostRefsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSyncPostRefs,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.Folder,
+		arg.UnreadOnly,
+		arg.ReadOnly,
+		arg.StarredOnly,
+		arg.Since,
+		arg.Until,
+		arg.AfterSeq,
+		arg.BeforeSeq,
+		arg.Ascending,
+		arg.ItemLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ListSyncPostRefsRow
+	for rows.Next() {
+		var i database.ListSyncPostRefsRow
+		if err := rows.Scan(&i.Seq, &i.CreatedAt, &i.FeedUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncPosts = `-- name: ListSyncPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+feeds.site_url AS feed_site_url,
+feed_follows.folder,
+post_reads.read_at,
+post_stars.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND (?2 IS NULL OR feeds.url = ?2)
+AND (?3 IS NULL OR feed_follows.folder = ?3)
+AND (?4 IS NULL OR posts.seq IN (SELECT value FROM json_each(?4)))
+AND (NOT ?5 OR post_reads.read_at IS NULL)
+AND (NOT ?6 OR post_reads.read_at IS NOT NULL)
+AND (NOT ?7 OR post_stars.starred_at IS NOT NULL)
+AND (?8 IS NULL OR posts.created_at >= ?8)
+AND (?9 IS NULL OR posts.created_at < ?9)
+AND (?10 IS NULL OR posts.seq > ?10)
+AND (?11 IS NULL OR posts.seq < ?11)
+ORDER BY
+    CASE WHEN ?12 THEN posts.seq END ASC,
+    posts.seq DESC
+LIMIT ?13
+`
+
+func (q *Queries) ListSyncPosts(ctx context.Context, arg database.ListSyncPostsParams) ([]database.ListSyncPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSyncPosts,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.Folder,
+		jsonList(arg.Seqs),
+		arg.UnreadOnly,
+		arg.ReadOnly,
+		arg.StarredOnly,
+		arg.Since,
+		arg.Until,
+		arg.AfterSeq,
+		arg.BeforeSeq,
+		arg.Ascending,
+		arg.ItemLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ListSyncPostsRow
+	for rows.Next() {
+		var i database.ListSyncPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
+			&i.Folder,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+`
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getDigestPostsForUser = `-- name: GetDigestPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.seq,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND post_reads.read_at IS NULL
+AND (?2 IS NULL
+    OR posts.created_at > ?2
+    OR (posts.created_at = ?2
+        AND (?3 IS NULL OR posts.seq > ?3)))
+AND posts.created_at < ?4
+ORDER BY posts.created_at, posts.seq
+LIMIT ?5
+`
+
+// unread posts oldest first, a digest that hits its limit leaves the rest to the next
+// one which starts after the last post sent, by fetch time and then seq
+func (q *Queries) GetDigestPostsForUser(ctx context.Context, arg database.GetDigestPostsForUserParams) ([]database.GetDigestPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPostsForUser,
+		arg.UserID,
+		arg.Since,
+		arg.SinceSeq,
+		arg.Until,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetDigestPostsForUserRow
+	for rows.Next() {
+		var i database.GetDigestPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Seq,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRuleCandidatesForUser = `-- name: GetRuleCandidatesForUser :many
+SELECT posts.id,
+posts.seq,
+posts.title,
+posts.description,
+posts.author,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+(
+    SELECT json_group_array(category) FROM (
+        SELECT post_categories.category FROM post_categories
+        WHERE post_categories.post_id = posts.id
+        ORDER BY post_categories.category
+    )
+) AS categories
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND posts.seq > ?2
+ORDER BY posts.seq
+LIMIT ?3
+`
+
+func (q *Queries) GetRuleCandidatesForUser(ctx context.Context, arg database.GetRuleCandidatesForUserParams) ([]database.GetRuleCandidatesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRuleCandidatesForUser, arg.UserID, arg.AfterSeq, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetRuleCandidatesForUserRow
+	for rows.Next() {
+		var i database.GetRuleCandidatesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Seq,
+			&i.Title,
+			&i.Description,
+			&i.Author,
+			&i.FeedName,
+			&i.FeedUrl,
+			jsonStrings{&i.Categories},
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+SELECT ranked.id, ranked.seq, ranked.url
+FROM (
+    SELECT posts.id, posts.seq, posts.url, posts.created_at,
+    ROW_NUMBER() OVER (ORDER BY posts.created_at DESC, posts.seq DESC) AS position
+    FROM posts
+    WHERE posts.feed_id = ?1
+) AS ranked
+WHERE ranked.seq > ?2
+AND (
+    ranked.created_at < ?3
+    OR ranked.position > ?4
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = ?1
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = ranked.id
+        AND post_reads.user_id = feed_follows.user_id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = ranked.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+)
+ORDER BY ranked.seq
+LIMIT ?5
+`
+
+// posts past a feed's retention that every follower has read or hidden and nobody
+// has starred or tagged, oldest first
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]database.GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.FeedID,
+		arg.AfterSeq,
+		arg.Before,
+		arg.Keep,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPrunablePostsRow
+	for rows.Next() {
+		var i database.GetPrunablePostsRow
+		if err := rows.Scan(&i.ID, &i.Seq, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+WHERE id IN (SELECT value FROM json_each(?1))
+`
+
+func (q *Queries) DeletePosts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts, jsonList(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const postsReset = `-- name: PostsReset :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) PostsReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, postsReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostsForUserFeeds = `-- name: DeletePostsForUserFeeds :execrows
+DELETE FROM posts
+WHERE feed_id IN (
+    SELECT id FROM feeds
+    WHERE user_id = ?1
+)
+`
+
+func (q *Queries) DeletePostsForUserFeeds(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsForUserFeeds, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
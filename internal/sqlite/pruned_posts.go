@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+)
+
+// the WHERE is only there because sqlite can't otherwise tell the ON CONFLICT apart
+// from a join constraint
+const createPrunedPosts = `-- name: CreatePrunedPosts :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT value, ?2, ?3 FROM json_each(?1)
+WHERE true
+ON CONFLICT (url) DO NOTHING
+`
+
+func (q *Queries) CreatePrunedPosts(ctx context.Context, arg database.CreatePrunedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, createPrunedPosts, jsonList(arg.Urls), arg.FeedID, arg.PrunedAt)
+	return err
+}
+
+const postWasPruned = `-- name: PostWasPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE url = ?1
+)
+`
+
+func (q *Queries) PostWasPruned(ctx context.Context, url string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, postWasPruned, url)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const prunedPostsReset = `-- name: PrunedPostsReset :exec
+DELETE FROM pruned_posts
+`
+
+func (q *Queries) PrunedPostsReset(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, prunedPostsReset)
+	return err
+}
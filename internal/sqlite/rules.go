@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createRule = `-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, field, pattern, regex, action, tag)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8,
+    ?9
+)
+RETURNING id, created_at, updated_at, user_id, field, pattern, regex, action, tag
+`
+
+func (q *Queries) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	row := q.db.QueryRowContext(ctx, createRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Field,
+		arg.Pattern,
+		arg.Regex,
+		arg.Action,
+		arg.Tag,
+	)
+	var i database.Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Field,
+		&i.Pattern,
+		&i.Regex,
+		&i.Action,
+		&i.Tag,
+	)
+	return i, err
+}
+
+const deleteRule = `-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = ?1
+AND user_id = ?2
+`
+
+func (q *Queries) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRuleForUser = `-- name: GetRuleForUser :one
+SELECT id, created_at, updated_at, user_id, field, pattern, regex, action, tag FROM rules
+WHERE id = ?1
+AND user_id = ?2
+`
+
+func (q *Queries) GetRuleForUser(ctx context.Context, arg database.GetRuleForUserParams) (database.Rule, error) {
+	row := q.db.QueryRowContext(ctx, getRuleForUser, arg.ID, arg.UserID)
+	var i database.Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Field,
+		&i.Pattern,
+		&i.Regex,
+		&i.Action,
+		&i.Tag,
+	)
+	return i, err
+}
+
+const getRulesForFeed = `-- name: GetRulesForFeed :many
+SELECT rules.id, rules.created_at, rules.updated_at, rules.user_id, rules.field, rules.pattern, rules.regex, rules.action, rules.tag,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = rules.user_id
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = ?1
+ORDER BY rules.created_at
+`
+
+func (q *Queries) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetRulesForFeedRow
+	for rows.Next() {
+		var i database.GetRulesForFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Field,
+			&i.Pattern,
+			&i.Regex,
+			&i.Action,
+			&i.Tag,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRulesForUser = `-- name: GetRulesForUser :many
+SELECT id, created_at, updated_at, user_id, field, pattern, regex, action, tag FROM rules
+WHERE user_id = ?1
+ORDER BY created_at
+`
+
+func (q *Queries) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Rule
+	for rows.Next() {
+		var i database.Rule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Field,
+			&i.Pattern,
+			&i.Regex,
+			&i.Action,
+			&i.Tag,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,122 @@
+// Package sqlite implements database.Store on top of sqlite, for running gator without
+// a postgres server. Every query mirrors the one of the same name in sql/queries,
+// rewritten for sqlite's dialect
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/Andrew-The-Cat/gator/internal/migrate"
+	"github.com/Andrew-The-Cat/gator/sql/schema"
+	_ "modernc.org/sqlite"
+)
+
+type Queries struct {
+	db database.DBTX
+}
+
+var _ database.Store = (*Queries)(nil)
+
+func New(db database.DBTX) *Queries {
+	return &Queries{db: db}
+}
+
+func (q *Queries) WithTx(tx *sql.Tx) database.Store {
+	return &Queries{db: tx}
+}
+
+// Open opens the database file at path, creating it if needed. Times are stored as
+// unix milliseconds so they compare correctly whatever zone they were written in
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_integer_format", "unix_milli")
+	params.Set("_inttotime", "1")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// a single writer at a time is all sqlite allows, queueing here beats busy errors
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// OpenMemory opens a private database with every migration applied, which lives in
+// memory until db is closed. Nothing has to be running for it, so it's the store to
+// hand handlers that are being exercised on their own
+func OpenMemory(ctx context.Context) (*sql.DB, database.Store, error) {
+	db, err := Open(":memory:")
+	if err != nil {
+		return nil, nil, err
+	}
+	// every connection to :memory: gets its own database, so the single connection
+	// Open allows must never be closed while db is in use
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	migrations, err := migrate.Load(schema.SQLiteMigrations)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("error reading the bundled migrations: %v", err)
+	}
+	if _, err := migrate.Up(ctx, db, migrate.SQLite, migrations, 0); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, New(db), nil
+}
+
+// jsonList passes a list of seqs, ids or urls as a json array for json_each, standing in
+// for postgres' arrays. nil stays NULL
+func jsonList[T any](values []T) any {
+	if values == nil {
+		return nil
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// jsonStrings scans a json array built by json_group_array
+type jsonStrings struct {
+	dest *[]string
+}
+
+func (j jsonStrings) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j.dest = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), j.dest)
+	case []byte:
+		return json.Unmarshal(v, j.dest)
+	}
+	return fmt.Errorf("unsupported type %T for a json array", src)
+}
+
+// timeValue scans computed columns holding a time, sqlite only turns columns declared
+// as TIMESTAMP back into times so these arrive as the stored milliseconds
+type timeValue struct {
+	dest *time.Time
+}
+
+func (t timeValue) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t.dest = v
+		return nil
+	case int64:
+		*t.dest = time.UnixMilli(v).UTC()
+		return nil
+	}
+	return fmt.Errorf("unsupported type %T for a time", src)
+}
@@ -0,0 +1,329 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4
+)
+RETURNING id, created_at, updated_at, name, feed_token, api_password, email, last_digest_at, password_hash, last_digest_seq
+`
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+	)
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.FeedToken,
+		&i.ApiPassword,
+		&i.Email,
+		&i.LastDigestAt,
+		&i.PasswordHash,
+		&i.LastDigestSeq,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, name, feed_token, api_password, email, last_digest_at, password_hash, last_digest_seq FROM users
+WHERE name = ?1
+LIMIT 1
+`
+
+func (q *Queries) GetUser(ctx context.Context, name string) (database.User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, name)
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.FeedToken,
+		&i.ApiPassword,
+		&i.Email,
+		&i.LastDigestAt,
+		&i.PasswordHash,
+		&i.LastDigestSeq,
+	)
+	return i, err
+}
+
+const getUsers = `-- name: GetUsers :many
+SELECT id, created_at, updated_at, name, feed_token, api_password, email, last_digest_at, password_hash, last_digest_seq FROM users
+`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]database.User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.User
+	for rows.Next() {
+		var i database.User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.FeedToken,
+			&i.ApiPassword,
+			&i.Email,
+			&i.LastDigestAt,
+			&i.PasswordHash,
+			&i.LastDigestSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const usersReset = `-- name: UsersReset :execrows
+DELETE FROM users
+`
+
+func (q *Queries) UsersReset(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, usersReset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserFeedToken = `-- name: SetUserFeedToken :exec
+UPDATE users
+SET feed_token = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) SetUserFeedToken(ctx context.Context, arg database.SetUserFeedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, setUserFeedToken, arg.ID, arg.FeedToken, arg.UpdatedAt)
+	return err
+}
+
+const setUserApiPassword = `-- name: SetUserApiPassword :exec
+UPDATE users
+SET api_password = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) SetUserApiPassword(ctx context.Context, arg database.SetUserApiPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserApiPassword, arg.ID, arg.ApiPassword, arg.UpdatedAt)
+	return err
+}
+
+const setUserEmail = `-- name: SetUserEmail :exec
+UPDATE users
+SET email = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) SetUserEmail(ctx context.Context, arg database.SetUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setUserEmail, arg.ID, arg.Email, arg.UpdatedAt)
+	return err
+}
+
+const setUserLastDigest = `-- name: SetUserLastDigest :exec
+UPDATE users
+SET last_digest_at = ?2,
+    last_digest_seq = ?3
+WHERE id = ?1
+`
+
+// where the next digest starts, the seq is only set when a digest was cut short and
+// the next one starts part way through the posts fetched at last_digest_at
+func (q *Queries) SetUserLastDigest(ctx context.Context, arg database.SetUserLastDigestParams) error {
+	_, err := q.db.ExecContext(ctx, setUserLastDigest, arg.ID, arg.LastDigestAt, arg.LastDigestSeq)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = ?1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameUser = `-- name: RenameUser :execrows
+UPDATE users
+SET name = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) RenameUser(ctx context.Context, arg database.RenameUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameUser,
+		arg.ID,
+		arg.Name,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserStats = `-- name: GetUserStats :one
+SELECT
+    (
+        SELECT COUNT(*) FROM feed_follows
+        WHERE feed_follows.user_id = ?1
+    ) AS follows,
+    (
+        SELECT COUNT(*) FROM posts
+        INNER JOIN feed_follows
+        ON feed_follows.feed_id = posts.feed_id
+        WHERE feed_follows.user_id = ?1
+        AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.post_id = posts.id
+            AND post_reads.user_id = feed_follows.user_id
+        )
+        AND NOT EXISTS (
+            SELECT 1 FROM post_hides
+            WHERE post_hides.post_id = posts.id
+            AND post_hides.user_id = feed_follows.user_id
+        )
+    ) AS unread,
+    (
+        SELECT COUNT(*) FROM feeds
+        WHERE feeds.user_id = ?1
+    ) AS feeds
+`
+
+func (q *Queries) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStats, userID)
+	var i database.GetUserStatsRow
+	err := row.Scan(
+		&i.Follows,
+		&i.Unread,
+		&i.Feeds,
+	)
+	return i, err
+}
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = ?2,
+    updated_at = ?3
+WHERE id = ?1
+`
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword,
+		arg.ID,
+		arg.PasswordHash,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const createSession = `-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, created_at, expires_at, user_id)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4
+)
+`
+
+func (q *Queries) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	_, err := q.db.ExecContext(ctx, createSession,
+		arg.TokenHash,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+		arg.UserID,
+	)
+	return err
+}
+
+const getSession = `-- name: GetSession :one
+SELECT token_hash, created_at, expires_at, user_id FROM sessions
+WHERE token_hash = ?1
+AND expires_at > ?2
+`
+
+func (q *Queries) GetSession(ctx context.Context, arg database.GetSessionParams) (database.Session, error) {
+	row := q.db.QueryRowContext(ctx, getSession, arg.TokenHash, arg.Now)
+	var i database.Session
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const deleteSession = `-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = ?1
+`
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteSession, tokenHash)
+	return err
+}
+
+const deleteSessionsForUser = `-- name: DeleteSessionsForUser :execrows
+DELETE FROM sessions
+WHERE user_id = ?1
+`
+
+// DeleteSessionsForUser logs a user out everywhere, used when their password changes
+func (q *Queries) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSessionsForUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= ?1
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredSessions, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,322 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const claimWebhookRetry = `-- name: ClaimWebhookRetry :execrows
+UPDATE webhook_retries
+SET attempt = attempt + 1,
+retry_at = ?1
+WHERE id = ?2
+AND attempt = ?3
+`
+
+// takes the next attempt of a delivery, nothing is claimed when someone else already
+// made it. retry_at moves to when the attempt after it is due should this one never finish
+func (q *Queries) ClaimWebhookRetry(ctx context.Context, arg database.ClaimWebhookRetryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimWebhookRetry, arg.RetryAt, arg.ID, arg.Attempt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, keyword)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8
+)
+RETURNING id, created_at, updated_at, user_id, url, secret, feed_id, keyword
+`
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Url,
+		arg.Secret,
+		arg.FeedID,
+		arg.Keyword,
+	)
+	var i database.Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.FeedID,
+		&i.Keyword,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, attempt, post_count, status_code, error, succeeded)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8
+)
+`
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) error {
+	_, err := q.db.ExecContext(ctx, createWebhookDelivery,
+		arg.ID,
+		arg.CreatedAt,
+		arg.WebhookID,
+		arg.Attempt,
+		arg.PostCount,
+		arg.StatusCode,
+		arg.Error,
+		arg.Succeeded,
+	)
+	return err
+}
+
+const createWebhookRetry = `-- name: CreateWebhookRetry :exec
+INSERT INTO webhook_retries (id, created_at, webhook_id, payload, attempt, retry_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6
+)
+`
+
+// a delivery waiting for its next attempt, kept in the database so agg stopping doesn't
+// lose it
+func (q *Queries) CreateWebhookRetry(ctx context.Context, arg database.CreateWebhookRetryParams) error {
+	_, err := q.db.ExecContext(ctx, createWebhookRetry,
+		arg.ID,
+		arg.CreatedAt,
+		arg.WebhookID,
+		arg.Payload,
+		arg.Attempt,
+		arg.RetryAt,
+	)
+	return err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = ?1
+AND user_id = ?2
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg database.DeleteWebhookParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhook, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebhookRetry = `-- name: DeleteWebhookRetry :exec
+DELETE FROM webhook_retries
+WHERE id = ?1
+`
+
+func (q *Queries) DeleteWebhookRetry(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhookRetry, id)
+	return err
+}
+
+const getDueWebhookRetries = `-- name: GetDueWebhookRetries :many
+SELECT webhook_retries.id, webhook_retries.created_at, webhook_retries.webhook_id, webhook_retries.payload, webhook_retries.attempt, webhook_retries.retry_at,
+webhooks.url,
+webhooks.secret
+FROM webhook_retries
+INNER JOIN webhooks
+ON webhooks.id = webhook_retries.webhook_id
+WHERE webhook_retries.retry_at <= ?1
+ORDER BY webhook_retries.retry_at
+`
+
+func (q *Queries) GetDueWebhookRetries(ctx context.Context, retryAt time.Time) ([]database.GetDueWebhookRetriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDueWebhookRetries, retryAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetDueWebhookRetriesRow
+	for rows.Next() {
+		var i database.GetDueWebhookRetriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.WebhookID,
+			&i.Payload,
+			&i.Attempt,
+			&i.RetryAt,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT webhook_deliveries.id, webhook_deliveries.created_at, webhook_deliveries.webhook_id, webhook_deliveries.attempt, webhook_deliveries.post_count, webhook_deliveries.status_code, webhook_deliveries.error, webhook_deliveries.succeeded FROM webhook_deliveries
+INNER JOIN webhooks
+ON webhooks.id = webhook_deliveries.webhook_id
+WHERE webhooks.user_id = ?1
+AND (?2 IS NULL OR webhooks.id = ?2)
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT ?3
+`
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg database.GetWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveries, arg.UserID, arg.WebhookID, arg.DeliveryLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.WebhookDelivery
+	for rows.Next() {
+		var i database.WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.WebhookID,
+			&i.Attempt,
+			&i.PostCount,
+			&i.StatusCode,
+			&i.Error,
+			&i.Succeeded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooksForFeed = `-- name: GetWebhooksForFeed :many
+SELECT webhooks.id, webhooks.created_at, webhooks.updated_at, webhooks.user_id, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.keyword,
+users.name AS user_name,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM webhooks
+INNER JOIN feed_follows
+ON feed_follows.user_id = webhooks.user_id
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = webhooks.user_id
+WHERE feed_follows.feed_id = ?1
+AND (webhooks.feed_id IS NULL OR webhooks.feed_id = ?1)
+`
+
+func (q *Queries) GetWebhooksForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetWebhooksForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooksForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetWebhooksForFeedRow
+	for rows.Next() {
+		var i database.GetWebhooksForFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.Keyword,
+			&i.UserName,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooksForUser = `-- name: GetWebhooksForUser :many
+SELECT webhooks.id, webhooks.created_at, webhooks.updated_at, webhooks.user_id, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.keyword,
+feeds.url AS feed_url
+FROM webhooks
+LEFT JOIN feeds
+ON feeds.id = webhooks.feed_id
+WHERE webhooks.user_id = ?1
+ORDER BY webhooks.created_at
+`
+
+func (q *Queries) GetWebhooksForUser(ctx context.Context, userID uuid.UUID) ([]database.GetWebhooksForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooksForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetWebhooksForUserRow
+	for rows.Next() {
+		var i database.GetWebhooksForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.Keyword,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
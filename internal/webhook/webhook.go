@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Andrew-The-Cat/gator/internal/export"
+)
+
+const (
+	EventPostsCreated = "posts.created"
+
+	EventHeader     = "X-Gator-Event"
+	DeliveryHeader  = "X-Gator-Delivery"
+	SignatureHeader = "X-Gator-Signature"
+)
+
+// Payload is the body of every delivery, its field names are part of the webhook
+// format and shouldn't change
+type Payload struct {
+	Event string `json:"event"`
+	// the same across every retry of a delivery, receivers can use it to drop duplicates
+	DeliveryID string        `json:"delivery_id"`
+	WebhookID  string        `json:"webhook_id"`
+	User       string        `json:"user"`
+	Feed       Feed          `json:"feed"`
+	Posts      []export.Post `json:"posts"`
+	SentAt     time.Time     `json:"sent_at"`
+}
+
+type Feed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Sign returns the value of the signature header, an hmac-sha256 of the body keyed with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send posts body to url once, the body is only signed when there's a secret. It
+// returns the response status even when the delivery failed because of it
+func Send(ctx context.Context, url, secret, event, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("unable to form the http request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error when making the request: %v", err)
+	}
+	defer res.Body.Close()
+
+	// drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status: %v", res.Status)
+	}
+	return res.StatusCode, nil
+}
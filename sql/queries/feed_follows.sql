@@ -1,12 +1,14 @@
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, custom_name, folder)
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
+        $5,
+        $6,
+        $7
     )
     RETURNING *
 )
@@ -19,24 +21,76 @@ ON feeds.id = inserted_feed_follow.feed_id
 INNER JOIN users
 ON users.id = inserted_feed_follow.user_id;
 
--- name: FeedFollowsReset :exec
+-- name: FeedFollowsReset :execrows
 DELETE FROM feed_follows *;
 
 -- name: GetFeedFollowsForUser :many
 SELECT
-feeds.name AS feed_name,
-feeds.url AS feed_url
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+feeds.site_url,
+feed_follows.folder
 FROM feed_follows
 INNER JOIN users
 ON users.id = feed_follows.user_id
 INNER JOIN feeds
 ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1;
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.folder NULLS FIRST, feed_name;
 
--- name: DeleteFeedFollowForUser :exec
+-- name: DeleteFeedFollowForUser :execrows
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1
 AND feed_follows.feed_id = 
     (SELECT id FROM feeds
     WHERE url = $2
-    );
\ No newline at end of file
+    );
+
+-- name: SetFeedFollowName :execrows
+UPDATE feed_follows
+SET custom_name = $1,
+    updated_at = $2
+WHERE feed_follows.user_id = $3
+AND feed_follows.feed_id =
+    (SELECT id FROM feeds
+    WHERE url = $4
+    );
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder = $1,
+    updated_at = $2
+WHERE feed_follows.user_id = $3
+AND feed_follows.feed_id =
+    (SELECT id FROM feeds
+    WHERE url = $4
+    );
+
+-- name: GetSubscriptionsForUser :many
+SELECT
+feeds.id,
+feeds.url,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.site_url,
+feeds.last_fetched_at,
+feed_follows.folder,
+feed_follows.created_at
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_name;
+
+-- name: DeleteFeedFollowsInvolvingUser :execrows
+-- the user's own follows and every follow of a feed they added
+DELETE FROM feed_follows
+WHERE user_id = $1
+OR feed_id IN (
+    SELECT id FROM feeds
+    WHERE feeds.user_id = $1
+);
+
+-- name: CountOtherFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1
+AND user_id != $2;
@@ -1,12 +1,13 @@
 -- name: AddFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id) 
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_url) 
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
 RETURNING *;
 
@@ -15,11 +16,19 @@ SELECT feeds.name, feeds.url, users.name as user_name FROM feeds
 INNER JOIN users
 ON users.id = feeds.user_id;
 
+-- name: ListFeeds :many
+SELECT feeds.*, users.name AS user_name FROM feeds
+INNER JOIN users
+ON users.id = feeds.user_id
+ORDER BY feeds.created_at, feeds.id
+LIMIT $1
+OFFSET $2;
+
 -- name: GetFeedByUrl :one
 SELECT * FROM feeds
 WHERE url = $1;
 
--- name: FeedsReset :exec
+-- name: FeedsReset :execrows
 DELETE FROM feeds *;
 
 -- name: MarkFeedFetched :one
@@ -33,4 +42,73 @@ RETURNING *;
 SELECT * FROM feeds
 ORDER BY last_fetched_at ASC
 NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: SetFeedFullContent :execrows
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = $3
+WHERE url = $1;
+
+-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET site_url = $2
+WHERE id = $1;
+
+-- name: GetAllFeeds :many
+SELECT * FROM feeds
+ORDER BY name, url;
+
+-- name: SetFeedRetention :execrows
+UPDATE feeds
+SET retention_max_age_days = $2,
+    retention_max_posts = $3,
+    updated_at = $4
+WHERE url = $1;
+
+-- name: DeleteFeedsForUser :execrows
+DELETE FROM feeds
+WHERE user_id = $1;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = sqlc.arg(to_user_id),
+    updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: TransferFeedsToFollowers :execrows
+-- hands each feed the user added to whoever has followed it the longest, feeds
+-- nobody else follows are left alone
+UPDATE feeds
+SET user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        AND feed_follows.user_id != $1
+        ORDER BY feed_follows.created_at, feed_follows.id
+        LIMIT 1
+    ),
+    updated_at = $2
+WHERE user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id != $1
+);
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: RenameFeed :execrows
+UPDATE feeds
+SET name = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: SetFeedUrl :execrows
+-- the feed is fetched again straight away from its new url
+UPDATE feeds
+SET url = $2,
+    last_fetched_at = NULL,
+    updated_at = $3
+WHERE id = $1;
@@ -0,0 +1,7 @@
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, category)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT (post_id, category) DO NOTHING;
@@ -0,0 +1,45 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+
+-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = $1
+AND post_id = $2;
+
+-- name: MarkPostsReadBySeq :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.seq = ANY(sqlc.arg(seqs)::bigint[])
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostsUnreadBySeq :execrows
+DELETE FROM post_reads
+USING posts
+WHERE post_reads.post_id = posts.id
+AND post_reads.user_id = sqlc.arg(user_id)
+AND posts.seq = ANY(sqlc.arg(seqs)::bigint[]);
+
+-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url))
+AND (sqlc.narg(folder)::text IS NULL OR feed_follows.folder = sqlc.narg(folder))
+AND posts.created_at < sqlc.arg(before)::timestamp
+ON CONFLICT (user_id, post_id) DO NOTHING;
@@ -0,0 +1,30 @@
+-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPost :execrows
+DELETE FROM post_stars
+WHERE user_id = $1
+AND post_id = $2;
+
+-- name: StarPostsBySeq :execrows
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(starred_at)::timestamp
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.seq = ANY(sqlc.arg(seqs)::bigint[])
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPostsBySeq :execrows
+DELETE FROM post_stars
+USING posts
+WHERE post_stars.post_id = posts.id
+AND post_stars.user_id = sqlc.arg(user_id)
+AND posts.seq = ANY(sqlc.arg(seqs)::bigint[]);
@@ -0,0 +1,15 @@
+-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = $1
+AND post_id = $2
+AND tag = $3;
@@ -7,7 +7,9 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    author,
+    content
 )
 VALUES (
     $1,
@@ -17,14 +19,351 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
 RETURNING *;
 
 -- name: GetPostsForUser :many
-SELECT posts.* FROM posts
+SELECT posts.*,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url
+FROM posts
 INNER JOIN feed_follows
 ON feed_follows.feed_id = posts.feed_id
-WHERE user_id = $1
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+-- subfolders are matched on their prefix rather than with LIKE, which would treat _ and %
+-- in folder names as wildcards and ignores case on sqlite but not on postgres
+AND (sqlc.narg(folder)::text IS NULL
+    OR feed_follows.folder = sqlc.narg(folder)
+    OR substr(feed_follows.folder, 1, length(sqlc.narg(folder)) + 1) = sqlc.narg(folder) || '/')
+AND (sqlc.narg(tag)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag = sqlc.narg(tag)
+    ))
 ORDER BY posts.created_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT sqlc.arg(post_limit);
+
+-- name: BrowsePostsForUser :many
+WITH timeline AS (
+    SELECT posts.*,
+    COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    post_reads.read_at,
+    (CASE WHEN sqlc.arg(by_published)::boolean
+        THEN COALESCE(posts.published_at, posts.created_at)
+        ELSE posts.created_at
+    END)::timestamp AS sort_key
+    FROM posts
+    INNER JOIN feed_follows
+    ON feed_follows.feed_id = posts.feed_id
+    INNER JOIN feeds
+    ON feeds.id = posts.feed_id
+    LEFT JOIN post_reads
+    ON post_reads.post_id = posts.id
+    AND post_reads.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = posts.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+    AND (sqlc.narg(feed)::text IS NULL
+        OR feeds.url = sqlc.narg(feed)
+        OR feeds.name = sqlc.narg(feed)
+        OR feed_follows.custom_name = sqlc.narg(feed))
+    AND (NOT sqlc.arg(unread_only)::boolean OR post_reads.read_at IS NULL)
+)
+SELECT * FROM timeline
+WHERE (sqlc.narg(since)::timestamp IS NULL OR sort_key >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR sort_key < sqlc.narg(until))
+AND (sqlc.narg(cursor_key)::timestamp IS NULL
+    OR (sqlc.arg(ascending)::boolean AND (sort_key, id) > (sqlc.narg(cursor_key), sqlc.narg(cursor_id)::uuid))
+    OR (NOT sqlc.arg(ascending) AND (sort_key, id) < (sqlc.narg(cursor_key), sqlc.narg(cursor_id)::uuid)))
+ORDER BY
+    CASE WHEN sqlc.arg(ascending) THEN sort_key END ASC,
+    CASE WHEN sqlc.arg(ascending) THEN id END ASC,
+    CASE WHEN NOT sqlc.arg(ascending) THEN sort_key END DESC,
+    CASE WHEN NOT sqlc.arg(ascending) THEN id END DESC
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: GetPostForUser :one
+SELECT posts.*,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+post_reads.read_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE posts.id = $1
+AND feed_follows.user_id = $2;
+
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: ExportPostsForUser :many
+SELECT posts.*,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+post_reads.read_at,
+post_stars.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed)::text IS NULL
+    OR feeds.url = sqlc.narg(feed)
+    OR feeds.name = sqlc.narg(feed)
+    OR feed_follows.custom_name = sqlc.narg(feed))
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until))
+AND (NOT sqlc.arg(starred_only)::boolean OR post_stars.starred_at IS NOT NULL)
+AND (sqlc.narg(after_created_at)::timestamp IS NULL
+    OR (posts.created_at, posts.id) > (sqlc.narg(after_created_at), sqlc.narg(after_id)::uuid))
+ORDER BY posts.created_at, posts.id
+LIMIT sqlc.arg(batch_size);
+
+
+-- name: ListSyncPosts :many
+SELECT posts.*,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+feeds.site_url AS feed_site_url,
+feed_follows.folder,
+post_reads.read_at,
+post_stars.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url))
+AND (sqlc.narg(folder)::text IS NULL OR feed_follows.folder = sqlc.narg(folder))
+AND (sqlc.narg(seqs)::bigint[] IS NULL OR posts.seq = ANY(sqlc.narg(seqs)::bigint[]))
+AND (NOT sqlc.arg(unread_only)::boolean OR post_reads.read_at IS NULL)
+AND (NOT sqlc.arg(read_only)::boolean OR post_reads.read_at IS NOT NULL)
+AND (NOT sqlc.arg(starred_only)::boolean OR post_stars.starred_at IS NOT NULL)
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.created_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.created_at < sqlc.narg(until))
+AND (sqlc.narg(after_seq)::bigint IS NULL OR posts.seq > sqlc.narg(after_seq))
+AND (sqlc.narg(before_seq)::bigint IS NULL OR posts.seq < sqlc.narg(before_seq))
+ORDER BY
+    CASE WHEN sqlc.arg(ascending)::boolean THEN posts.seq END ASC,
+    posts.seq DESC
+LIMIT sqlc.arg(item_limit);
+
+-- name: ListSyncPostRefs :many
+SELECT posts.seq,
+posts.created_at,
+feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url))
+AND (sqlc.narg(folder)::text IS NULL OR feed_follows.folder = sqlc.narg(folder))
+AND (NOT sqlc.arg(unread_only)::boolean OR post_reads.read_at IS NULL)
+AND (NOT sqlc.arg(read_only)::boolean OR post_reads.read_at IS NOT NULL)
+AND (NOT sqlc.arg(starred_only)::boolean OR post_stars.starred_at IS NOT NULL)
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.created_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.created_at < sqlc.narg(until))
+AND (sqlc.narg(after_seq)::bigint IS NULL OR posts.seq > sqlc.narg(after_seq))
+AND (sqlc.narg(before_seq)::bigint IS NULL OR posts.seq < sqlc.narg(before_seq))
+ORDER BY
+    CASE WHEN sqlc.arg(ascending)::boolean THEN posts.seq END ASC,
+    posts.seq DESC
+LIMIT sqlc.arg(item_limit);
+
+-- name: CountUnreadPostsByFeed :many
+SELECT feeds.url AS feed_url,
+feed_follows.folder,
+COUNT(posts.id) AS unread,
+MAX(posts.created_at)::timestamp AS newest
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND post_reads.read_at IS NULL
+GROUP BY feeds.url, feed_follows.folder;
+
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+);
+
+
+-- name: GetDigestPostsForUser :many
+-- unread posts oldest first, a digest that hits its limit leaves the rest to the next
+-- one which starts after the last post sent, by fetch time and then seq
+SELECT posts.*,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+AND post_reads.read_at IS NULL
+AND (sqlc.narg(since)::timestamp IS NULL
+    OR posts.created_at > sqlc.narg(since)
+    OR (posts.created_at = sqlc.narg(since)
+        AND (sqlc.narg(since_seq)::bigint IS NULL OR posts.seq > sqlc.narg(since_seq))))
+AND posts.created_at < sqlc.arg(until)::timestamp
+ORDER BY posts.created_at, posts.seq
+LIMIT sqlc.arg(post_limit);
+
+-- name: GetRuleCandidatesForUser :many
+SELECT posts.id,
+posts.seq,
+posts.title,
+posts.description,
+posts.author,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name,
+feeds.url AS feed_url,
+ARRAY(
+    SELECT post_categories.category FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    ORDER BY post_categories.category
+)::text[] AS categories
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.seq > sqlc.arg(after_seq)
+ORDER BY posts.seq
+LIMIT sqlc.arg(batch_size);
+
+-- name: GetPrunablePosts :many
+-- posts past a feed's retention that every follower has read or hidden and nobody
+-- has starred or tagged, oldest first
+SELECT ranked.id, ranked.seq, ranked.url
+FROM (
+    SELECT posts.id, posts.seq, posts.url, posts.created_at,
+    ROW_NUMBER() OVER (ORDER BY posts.created_at DESC, posts.seq DESC) AS position
+    FROM posts
+    WHERE posts.feed_id = sqlc.arg(feed_id)
+) AS ranked
+WHERE ranked.seq > sqlc.arg(after_seq)
+AND (
+    ranked.created_at < sqlc.narg(before)
+    OR ranked.position > sqlc.narg(keep)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = ranked.id
+        AND post_reads.user_id = feed_follows.user_id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = ranked.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+)
+ORDER BY ranked.seq
+LIMIT sqlc.arg(batch_size);
+
+-- name: DeletePosts :execrows
+DELETE FROM posts
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: PostsReset :execrows
+DELETE FROM posts;
+
+-- name: DeletePostsForUserFeeds :execrows
+DELETE FROM posts
+WHERE feed_id IN (
+    SELECT id FROM feeds
+    WHERE user_id = $1
+);
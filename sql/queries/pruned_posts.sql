@@ -0,0 +1,13 @@
+-- name: CreatePrunedPosts :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT unnest(sqlc.arg(urls)::text[]), sqlc.arg(feed_id), sqlc.arg(pruned_at)
+ON CONFLICT (url) DO NOTHING;
+
+-- name: PostWasPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE url = $1
+);
+
+-- name: PrunedPostsReset :exec
+DELETE FROM pruned_posts;
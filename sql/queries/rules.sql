@@ -0,0 +1,40 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, field, pattern, regex, action, tag)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT * FROM rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetRuleForUser :one
+SELECT * FROM rules
+WHERE id = $1
+AND user_id = $2;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1
+AND user_id = $2;
+
+-- name: GetRulesForFeed :many
+SELECT rules.*,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = rules.user_id
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.created_at;
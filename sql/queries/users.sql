@@ -13,8 +13,104 @@ SELECT * FROM users
 WHERE name = $1
 LIMIT 1;
 
--- name: UsersReset :exec
+-- name: UsersReset :execrows
 DELETE FROM users *;
 
 -- name: GetUsers :many
-SELECT * FROM users;
\ No newline at end of file
+SELECT * FROM users;
+
+-- name: SetUserFeedToken :exec
+UPDATE users
+SET feed_token = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: SetUserApiPassword :exec
+UPDATE users
+SET api_password = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: SetUserEmail :exec
+UPDATE users
+SET email = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: SetUserLastDigest :exec
+-- where the next digest starts, the seq is only set when a digest was cut short and
+-- the next one starts part way through the posts fetched at last_digest_at
+UPDATE users
+SET last_digest_at = $2,
+    last_digest_seq = $3
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: RenameUser :execrows
+UPDATE users
+SET name = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: GetUserStats :one
+SELECT
+    (
+        SELECT COUNT(*) FROM feed_follows
+        WHERE feed_follows.user_id = $1
+    ) AS follows,
+    (
+        SELECT COUNT(*) FROM posts
+        INNER JOIN feed_follows
+        ON feed_follows.feed_id = posts.feed_id
+        WHERE feed_follows.user_id = $1
+        AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.post_id = posts.id
+            AND post_reads.user_id = feed_follows.user_id
+        )
+        AND NOT EXISTS (
+            SELECT 1 FROM post_hides
+            WHERE post_hides.post_id = posts.id
+            AND post_hides.user_id = feed_follows.user_id
+        )
+    ) AS unread,
+    (
+        SELECT COUNT(*) FROM feeds
+        WHERE feeds.user_id = $1
+    ) AS feeds;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, created_at, expires_at, user_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetSession :one
+SELECT token_hash, created_at, expires_at, user_id FROM sessions
+WHERE token_hash = $1
+AND expires_at > $2;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteSessionsForUser :execrows
+-- DeleteSessionsForUser logs a user out everywhere, used when their password changes
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= $1;
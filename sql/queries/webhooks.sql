@@ -0,0 +1,99 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, keyword)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT webhooks.*,
+feeds.url AS feed_url
+FROM webhooks
+LEFT JOIN feeds
+ON feeds.id = webhooks.feed_id
+WHERE webhooks.user_id = $1
+ORDER BY webhooks.created_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1
+AND user_id = $2;
+
+-- name: GetWebhooksForFeed :many
+SELECT webhooks.*,
+users.name AS user_name,
+COALESCE(feed_follows.custom_name, feeds.name) AS feed_name
+FROM webhooks
+INNER JOIN feed_follows
+ON feed_follows.user_id = webhooks.user_id
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = webhooks.user_id
+WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+AND (webhooks.feed_id IS NULL OR webhooks.feed_id = sqlc.arg(feed_id));
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, attempt, post_count, status_code, error, succeeded)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+);
+
+-- name: GetWebhookDeliveries :many
+SELECT webhook_deliveries.* FROM webhook_deliveries
+INNER JOIN webhooks
+ON webhooks.id = webhook_deliveries.webhook_id
+WHERE webhooks.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(webhook_id)::uuid IS NULL OR webhooks.id = sqlc.narg(webhook_id))
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT sqlc.arg(delivery_limit);
+
+-- name: CreateWebhookRetry :exec
+-- a delivery waiting for its next attempt, kept in the database so agg stopping doesn't
+-- lose it
+INSERT INTO webhook_retries (id, created_at, webhook_id, payload, attempt, retry_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: GetDueWebhookRetries :many
+SELECT webhook_retries.*,
+webhooks.url,
+webhooks.secret
+FROM webhook_retries
+INNER JOIN webhooks
+ON webhooks.id = webhook_retries.webhook_id
+WHERE webhook_retries.retry_at <= $1
+ORDER BY webhook_retries.retry_at;
+
+-- name: ClaimWebhookRetry :execrows
+-- takes the next attempt of a delivery, nothing is claimed when someone else already
+-- made it. retry_at moves to when the attempt after it is due should this one never finish
+UPDATE webhook_retries
+SET attempt = attempt + 1,
+retry_at = sqlc.arg(retry_at)
+WHERE id = sqlc.arg(id)
+AND attempt = sqlc.arg(attempt);
+
+-- name: DeleteWebhookRetry :exec
+DELETE FROM webhook_retries
+WHERE id = $1;
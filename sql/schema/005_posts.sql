@@ -11,4 +11,4 @@ CREATE TABLE posts (
 );
 
 -- +goose Down
-DELETE TABLE posts;
\ No newline at end of file
+DROP TABLE posts;
\ No newline at end of file
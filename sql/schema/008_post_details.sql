@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT,
+ADD COLUMN content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN author,
+DROP COLUMN content;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE post_tags (
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id uuid NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id, tag)
+);
+
+-- +goose Down
+DROP TABLE post_tags;
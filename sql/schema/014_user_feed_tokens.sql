@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN feed_token TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN feed_token;
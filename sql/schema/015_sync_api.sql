@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN seq BIGSERIAL UNIQUE;
+
+ALTER TABLE users
+ADD COLUMN api_password TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN api_password;
+
+ALTER TABLE posts
+DROP COLUMN seq;
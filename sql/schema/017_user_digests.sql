@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email TEXT;
+
+ALTER TABLE users
+ADD COLUMN last_digest_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN last_digest_at;
+
+ALTER TABLE users
+DROP COLUMN email;
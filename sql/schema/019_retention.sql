@@ -0,0 +1,23 @@
+-- +goose Up
+-- NULL follows the retention set in the config, 0 keeps posts forever
+ALTER TABLE feeds
+ADD COLUMN retention_max_age_days INTEGER;
+
+ALTER TABLE feeds
+ADD COLUMN retention_max_posts INTEGER;
+
+-- pruned posts are remembered so fetching the feed again doesn't bring them back
+CREATE TABLE pruned_posts (
+    url TEXT PRIMARY KEY,
+    feed_id uuid NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    pruned_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+
+ALTER TABLE feeds
+DROP COLUMN retention_max_posts;
+
+ALTER TABLE feeds
+DROP COLUMN retention_max_age_days;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE webhook_retries (
+    id uuid PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    webhook_id uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    payload TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    retry_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_retries;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN last_digest_seq BIGINT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN last_digest_seq;
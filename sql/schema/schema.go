@@ -0,0 +1,16 @@
+// Package schema ships the goose migrations next to it inside the gator binary
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var Migrations embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteMigrations mirrors Migrations version for version, written for sqlite
+var SQLiteMigrations, _ = fs.Sub(sqliteMigrations, "sqlite")
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD COLUMN custom_name TEXT;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN custom_name;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT;
+
+ALTER TABLE posts
+ADD COLUMN content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;
+
+ALTER TABLE posts
+DROP COLUMN author;
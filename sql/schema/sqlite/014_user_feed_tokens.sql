@@ -0,0 +1,12 @@
+-- +goose Up
+-- sqlite can't add a UNIQUE column, so the constraint is an index instead
+ALTER TABLE users
+ADD COLUMN feed_token TEXT;
+
+CREATE UNIQUE INDEX users_feed_token_key ON users(feed_token);
+
+-- +goose Down
+DROP INDEX users_feed_token_key;
+
+ALTER TABLE users
+DROP COLUMN feed_token;
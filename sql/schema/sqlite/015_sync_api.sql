@@ -0,0 +1,31 @@
+-- +goose Up
+-- sqlite has no sequences, post_seqs hands out seq values instead and AUTOINCREMENT
+-- makes sure a value is never given out twice
+ALTER TABLE posts
+ADD COLUMN seq INTEGER;
+
+UPDATE posts SET seq = rowid;
+
+CREATE UNIQUE INDEX posts_seq_key ON posts(seq);
+
+CREATE TABLE post_seqs (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT
+);
+
+INSERT INTO post_seqs (seq)
+SELECT MAX(seq) FROM posts
+HAVING MAX(seq) IS NOT NULL;
+
+ALTER TABLE users
+ADD COLUMN api_password TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN api_password;
+
+DROP TABLE post_seqs;
+
+DROP INDEX posts_seq_key;
+
+ALTER TABLE posts
+DROP COLUMN seq;
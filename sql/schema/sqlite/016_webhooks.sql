@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id uuid PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT,
+    feed_id uuid REFERENCES feeds(id) ON DELETE CASCADE,
+    keyword TEXT
+);
+
+CREATE TABLE webhook_deliveries (
+    id uuid PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    webhook_id uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    post_count INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    succeeded BOOLEAN NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
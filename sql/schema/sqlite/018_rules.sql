@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE rules (
+    id uuid PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    field TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    regex BOOLEAN NOT NULL,
+    action TEXT NOT NULL,
+    tag TEXT
+);
+
+-- posts stay hidden only as long as a rule hiding them exists
+CREATE TABLE post_hides (
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id uuid NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    rule_id uuid NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    hidden_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id, rule_id)
+);
+
+CREATE TABLE post_categories (
+    post_id uuid NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    PRIMARY KEY (post_id, category)
+);
+
+-- +goose Down
+DROP TABLE post_categories;
+DROP TABLE post_hides;
+DROP TABLE rules;